@@ -10,6 +10,7 @@ import (
 
 	"github.com/grokify/goauth"
 	"github.com/grokify/goauth/google"
+	googleoauth "golang.org/x/oauth2/google"
 )
 
 var (
@@ -26,6 +27,10 @@ var ErrNoCredentials = errors.New("credentials required: use --credentials or --
 // ErrMultipleCredentials is returned when both credential methods are provided.
 var ErrMultipleCredentials = errors.New("cannot use both --credentials and --goauth-credentials-file")
 
+// ErrDelegationRequiresCredentials is returned when impersonating a user without a
+// service account key.
+var ErrDelegationRequiresCredentials = errors.New("domain-wide delegation requires a service account key: use --credentials")
+
 // SetCredentials sets the credential values (called from root command).
 func SetCredentials(creds, goauthFile, goauthAcct string) {
 	mu.Lock()
@@ -75,3 +80,26 @@ func NewHTTPClient(ctx context.Context, scopes []string) (*http.Client, error) {
 
 	return google.NewClientSvcAccountFromFile(ctx, creds, scopes...)
 }
+
+// NewHTTPClientForUser creates an HTTP client that acts as `subject` using domain-wide
+// delegation. It requires a service account key set with --credentials whose client ID
+// is authorized for the scopes in the Google Workspace admin console.
+func NewHTTPClientForUser(ctx context.Context, subject string, scopes []string) (*http.Client, error) {
+	creds, _, _ := GetCredentials()
+	if creds == "" {
+		creds = os.Getenv("GOOGLE_CREDENTIALS_FILE")
+	}
+	if creds == "" {
+		return nil, ErrDelegationRequiresCredentials
+	}
+	data, err := os.ReadFile(creds)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := googleoauth.JWTConfigFromJSON(data, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	conf.Subject = subject
+	return conf.Client(ctx), nil
+}
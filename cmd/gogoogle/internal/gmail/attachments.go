@@ -0,0 +1,97 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grokify/gogoogle/cmd/gogoogle/internal/config"
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+)
+
+var (
+	// attachments command flags
+	attachmentsQuery         string
+	attachmentsOutputDir     string
+	attachmentsMIMETypes     []string
+	attachmentsFilenameGlobs []string
+	attachmentsExcludeInline bool
+	attachmentsMaxMessages   int
+)
+
+var attachmentsCmd = &cobra.Command{
+	Use:   "attachments",
+	Short: "Download attachments from messages matching a query",
+	Long: `Download attachments from all messages matching a Gmail search query.
+
+Attachments, including inline images and parts nested in multipart messages,
+are written to the output directory with sanitized filenames. Existing files
+are not overwritten; a numbered suffix is added instead.
+
+Example:
+  gogoogle gmail attachments \
+    --query="from:billing@example.com has:attachment newer_than:1y" \
+    --output-dir=./invoices \
+    --filename="*.pdf"`,
+	RunE: runAttachments,
+}
+
+func init() {
+	attachmentsCmd.Flags().StringVarP(&attachmentsQuery, "query", "q", "",
+		"Gmail search query (required)")
+	attachmentsCmd.Flags().StringVarP(&attachmentsOutputDir, "output-dir", "o", "",
+		"Directory to write attachments to (required)")
+	attachmentsCmd.Flags().StringSliceVar(&attachmentsMIMETypes, "mime-type", nil,
+		"MIME types to include, e.g. application/pdf or image/*")
+	attachmentsCmd.Flags().StringSliceVar(&attachmentsFilenameGlobs, "filename", nil,
+		"Filename glob patterns to include, e.g. *.pdf")
+	attachmentsCmd.Flags().BoolVar(&attachmentsExcludeInline, "exclude-inline", false,
+		"Skip inline parts such as embedded images")
+	attachmentsCmd.Flags().IntVar(&attachmentsMaxMessages, "max-messages", 0,
+		"Maximum number of messages to process (0 for no limit)")
+
+	_ = attachmentsCmd.MarkFlagRequired("query")
+	_ = attachmentsCmd.MarkFlagRequired("output-dir")
+}
+
+func runAttachments(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	expr, err := gmailutil.ParseQuery(attachmentsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to parse query: %w", err)
+	}
+
+	httpClient, err := config.NewHTTPClient(ctx, []string{gmailutil.GmailReadonlyScope})
+	if err != nil {
+		return fmt.Errorf("failed to create authenticated client: %w", err)
+	}
+
+	svc, err := gmailutil.NewGmailService(ctx, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create Gmail service: %w", err)
+	}
+
+	saved, err := svc.MessagesAPI.SaveAttachments(ctx,
+		gmailutil.MessagesListOpts{
+			Query:    gmailutil.MessagesListQueryOpts{Expr: expr},
+			MaxTotal: attachmentsMaxMessages,
+		},
+		attachmentsOutputDir,
+		gmailutil.AttachmentFilter{
+			MIMETypes:     attachmentsMIMETypes,
+			FilenameGlobs: attachmentsFilenameGlobs,
+			ExcludeInline: attachmentsExcludeInline,
+		})
+	for _, s := range saved {
+		fmt.Fprintf(os.Stdout, "%s\t%d\t%s\n", s.MessageID, s.Size, s.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save attachments: %w", err)
+	}
+
+	fmt.Fprintf(os.Stdout, "Saved %d attachment(s) to %s\n", len(saved), attachmentsOutputDir)
+	return nil
+}
@@ -0,0 +1,268 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grokify/gogoogle/cmd/gogoogle/internal/config"
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+	"github.com/grokify/mogo/mime/multipartutil"
+	"github.com/grokify/mogo/net/http/httputilmore"
+	"github.com/grokify/mogo/net/mailutil"
+)
+
+var (
+	// drafts command flags
+	draftsQuery     string
+	draftsMaxDrafts int
+	draftsTo        []string
+	draftsCc        []string
+	draftsBcc       []string
+	draftsSubject   string
+	draftsBody      string
+	draftsShowHTML  bool
+)
+
+var draftsCmd = &cobra.Command{
+	Use:   "drafts",
+	Short: "Manage Gmail drafts",
+	Long: `Create, list, show, send and delete Gmail drafts.
+
+Drafts let messages, such as mail merge output, be reviewed in Gmail before
+they are sent. The send-markdown and merge commands also accept --draft to
+create drafts instead of sending.`,
+}
+
+var draftsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List drafts",
+	Long: `List drafts with their ID, recipients and subject.
+
+Example:
+  gogoogle gmail drafts list --query="to:customer@example.com"`,
+	Args: cobra.NoArgs,
+	RunE: runDraftsList,
+}
+
+var draftsShowCmd = &cobra.Command{
+	Use:   "show DRAFT_ID",
+	Short: "Show a draft",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDraftsShow,
+}
+
+var draftsCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a draft",
+	Long: `Create a plain text draft.
+
+The body can be specified as inline text or as a file reference using @filename.txt.
+
+Example:
+  gogoogle gmail drafts create \
+    --to="user@example.com" \
+    --subject="Hello" \
+    --body=@body.txt`,
+	Args: cobra.NoArgs,
+	RunE: runDraftsCreate,
+}
+
+var draftsSendCmd = &cobra.Command{
+	Use:   "send DRAFT_ID...",
+	Short: "Send drafts",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runDraftsSend,
+}
+
+var draftsDeleteCmd = &cobra.Command{
+	Use:   "delete DRAFT_ID...",
+	Short: "Permanently delete drafts",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runDraftsDelete,
+}
+
+func init() {
+	draftsListCmd.Flags().StringVarP(&draftsQuery, "query", "q", "",
+		"Gmail search query")
+	draftsListCmd.Flags().IntVar(&draftsMaxDrafts, "max-drafts", 0,
+		"Maximum number of drafts to list (0 for no limit)")
+
+	draftsShowCmd.Flags().BoolVar(&draftsShowHTML, "html", false,
+		"Show the HTML body instead of the text body")
+
+	draftsCreateCmd.Flags().StringSliceVar(&draftsTo, "to", nil,
+		"Recipient email addresses (required)")
+	draftsCreateCmd.Flags().StringSliceVar(&draftsCc, "cc", nil,
+		"CC email addresses")
+	draftsCreateCmd.Flags().StringSliceVar(&draftsBcc, "bcc", nil,
+		"BCC email addresses")
+	draftsCreateCmd.Flags().StringVarP(&draftsSubject, "subject", "s", "",
+		"Email subject (required)")
+	draftsCreateCmd.Flags().StringVarP(&draftsBody, "body", "b", "",
+		"Body text or @filename to read from file (required)")
+	_ = draftsCreateCmd.MarkFlagRequired("to")
+	_ = draftsCreateCmd.MarkFlagRequired("subject")
+	_ = draftsCreateCmd.MarkFlagRequired("body")
+
+	draftsCmd.AddCommand(draftsListCmd)
+	draftsCmd.AddCommand(draftsShowCmd)
+	draftsCmd.AddCommand(draftsCreateCmd)
+	draftsCmd.AddCommand(draftsSendCmd)
+	draftsCmd.AddCommand(draftsDeleteCmd)
+}
+
+func newDraftsService(ctx context.Context) (*gmailutil.GmailService, error) {
+	httpClient, err := config.NewHTTPClient(ctx, []string{gmailutil.GmailComposeScope})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authenticated client: %w", err)
+	}
+	svc, err := gmailutil.NewGmailService(ctx, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gmail service: %w", err)
+	}
+	return svc, nil
+}
+
+func runDraftsList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	expr, err := gmailutil.ParseQuery(draftsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to parse query: %w", err)
+	}
+	svc, err := newDraftsService(ctx)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for d, err := range svc.DraftsAPI.ListAll(ctx, gmailutil.MessagesListOpts{
+		Query:    gmailutil.MessagesListQueryOpts{Expr: expr},
+		MaxTotal: draftsMaxDrafts,
+	}) {
+		if err != nil {
+			return fmt.Errorf("failed to list drafts: %w", err)
+		}
+		full, err := svc.DraftsAPI.Get(ctx, "", d.Id, &gmailutil.GetMessageOpts{Format: gmailutil.MessageFormatMetadata})
+		if err != nil {
+			return fmt.Errorf("failed to get draft %s: %w", d.Id, err)
+		}
+		pm, err := gmailutil.ParseMessage(full.Message)
+		if err != nil {
+			return fmt.Errorf("failed to parse draft %s: %w", d.Id, err)
+		}
+		fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", d.Id, strings.Join(pm.To.Strings(true, false, false), ","), pm.Subject)
+		count++
+	}
+	fmt.Fprintf(os.Stdout, "%d draft(s)\n", count)
+	return nil
+}
+
+func runDraftsShow(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	svc, err := newDraftsService(ctx)
+	if err != nil {
+		return err
+	}
+
+	d, err := svc.DraftsAPI.Get(ctx, "", args[0], &gmailutil.GetMessageOpts{Format: gmailutil.MessageFormatFull})
+	if err != nil {
+		return fmt.Errorf("failed to get draft: %w", err)
+	}
+	pm, err := gmailutil.ParseMessage(d.Message)
+	if err != nil {
+		return fmt.Errorf("failed to parse draft: %w", err)
+	}
+
+	fmt.Fprintf(os.Stdout, "Draft: %s\n", d.Id)
+	fmt.Fprintf(os.Stdout, "To: %s\n", pm.To.String(false, false, false))
+	if len(pm.Cc) > 0 {
+		fmt.Fprintf(os.Stdout, "Cc: %s\n", pm.Cc.String(false, false, false))
+	}
+	if len(pm.Bcc) > 0 {
+		fmt.Fprintf(os.Stdout, "Bcc: %s\n", pm.Bcc.String(false, false, false))
+	}
+	fmt.Fprintf(os.Stdout, "Subject: %s\n", pm.Subject)
+	for _, att := range pm.Attachments {
+		fmt.Fprintf(os.Stdout, "Attachment: %s (%s, %d bytes)\n", att.Filename, att.MIMEType, att.Size)
+	}
+	body := pm.BodyText
+	if draftsShowHTML {
+		body = pm.BodyHTML
+	}
+	fmt.Fprintf(os.Stdout, "\n%s\n", body)
+	return nil
+}
+
+func runDraftsCreate(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	bodyText := draftsBody
+	if strings.HasPrefix(draftsBody, "@") {
+		filename := strings.TrimPrefix(draftsBody, "@")
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read body file %q: %w", filename, err)
+		}
+		bodyText = string(data)
+	}
+
+	partsSet := multipartutil.NewPartsSet(httputilmore.ContentTypeMultipartMixed)
+	if err := partsSet.AddMailBody([]byte(bodyText), nil); err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+	msg := mailutil.MessageWriter{
+		To:           parseAddressList(draftsTo),
+		Cc:           parseAddressList(draftsCc),
+		Bcc:          parseAddressList(draftsBcc),
+		Subject:      draftsSubject,
+		BodyPartsSet: partsSet,
+	}
+
+	svc, err := newDraftsService(ctx)
+	if err != nil {
+		return err
+	}
+	d, err := svc.DraftsAPI.Create(ctx, "", msg)
+	if err != nil {
+		return fmt.Errorf("failed to create draft: %w", err)
+	}
+
+	fmt.Fprintf(os.Stdout, "Draft created (ID: %s)\n", d.Id)
+	return nil
+}
+
+func runDraftsSend(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	svc, err := newDraftsService(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range args {
+		msg, err := svc.DraftsAPI.Send(ctx, "", id)
+		if err != nil {
+			return fmt.Errorf("failed to send draft %s: %w", id, err)
+		}
+		fmt.Fprintf(os.Stdout, "Draft %s sent (ID: %s)\n", id, msg.Id)
+	}
+	return nil
+}
+
+func runDraftsDelete(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	svc, err := newDraftsService(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range args {
+		if err := svc.DraftsAPI.Delete(ctx, "", id); err != nil {
+			return fmt.Errorf("failed to delete draft %s: %w", id, err)
+		}
+		fmt.Fprintf(os.Stdout, "Draft %s deleted\n", id)
+	}
+	return nil
+}
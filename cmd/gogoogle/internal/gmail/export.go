@@ -0,0 +1,112 @@
+package gmail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grokify/gogoogle/cmd/gogoogle/internal/config"
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+)
+
+var (
+	// export command flags
+	exportQuery       string
+	exportLabels      []string
+	exportOutputDir   string
+	exportFormat      string
+	exportZip         bool
+	exportMaxMessages int
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export messages to an mbox file or EML archive",
+	Long: `Export messages matching a Gmail search query and/or labels in their
+original RFC 822 form.
+
+The output directory contains an index.json of message IDs, dates and
+subjects, and either a messages.mbox file (mboxrd) or one .eml file per
+message. With --zip, the .eml files are written to messages.zip.
+
+Exports are resumable: messages already in index.json are skipped, so running
+the same export again only adds new messages.
+
+Example:
+  gogoogle gmail export \
+    --label="Compliance/Contracts" \
+    --query="newer_than:1y" \
+    --output-dir=./export \
+    --format=eml --zip`,
+	RunE: runExport,
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportQuery, "query", "q", "",
+		"Gmail search query")
+	exportCmd.Flags().StringSliceVar(&exportLabels, "label", nil,
+		"Label names to export (messages must have all labels)")
+	exportCmd.Flags().StringVarP(&exportOutputDir, "output-dir", "o", "",
+		"Directory to write the export to (required)")
+	exportCmd.Flags().StringVar(&exportFormat, "format", gmailutil.ExportFormatMbox,
+		"Export format: mbox or eml")
+	exportCmd.Flags().BoolVar(&exportZip, "zip", false,
+		"Write .eml files to messages.zip (eml format only)")
+	exportCmd.Flags().IntVar(&exportMaxMessages, "max-messages", 0,
+		"Maximum number of messages to export (0 for no limit)")
+
+	_ = exportCmd.MarkFlagRequired("output-dir")
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if exportQuery == "" && len(exportLabels) == 0 {
+		return errors.New("--query or --label is required")
+	}
+	expr, err := gmailutil.ParseQuery(exportQuery)
+	if err != nil {
+		return fmt.Errorf("failed to parse query: %w", err)
+	}
+
+	httpClient, err := config.NewHTTPClient(ctx, []string{gmailutil.GmailReadonlyScope})
+	if err != nil {
+		return fmt.Errorf("failed to create authenticated client: %w", err)
+	}
+
+	svc, err := gmailutil.NewGmailService(ctx, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create Gmail service: %w", err)
+	}
+
+	labelIDs, err := svc.LabelsAPI.LabelIDs(ctx, gmailutil.UserIDMe, exportLabels)
+	if err != nil {
+		return fmt.Errorf("failed to resolve labels: %w", err)
+	}
+
+	res, err := svc.MessagesAPI.Export(ctx, gmailutil.ExportOpts{
+		Query:    gmailutil.MessagesListQueryOpts{Expr: expr},
+		LabelIDs: labelIDs,
+		MaxTotal: exportMaxMessages,
+		Dir:      exportOutputDir,
+		Format:   exportFormat,
+		Zip:      exportZip,
+		Progress: func(p gmailutil.ExportProgress) {
+			fmt.Fprintf(os.Stderr, "matched %d, skipped %d, exported %d, failed %d\n",
+				p.Matched, p.Skipped, p.Exported, p.Failed)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export messages: %w", err)
+	}
+
+	for id, err := range res.Errors {
+		fmt.Fprintf(os.Stderr, "message %s: %v\n", id, err)
+	}
+	fmt.Fprintf(os.Stdout, "Exported %d message(s), skipped %d already exported, %d failed; index: %s\n",
+		res.Exported, res.Skipped, res.Failed, res.IndexPath)
+	return nil
+}
@@ -0,0 +1,159 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grokify/gogoogle/cmd/gogoogle/internal/config"
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+)
+
+var (
+	// filters command flags
+	filtersFile   string
+	filtersDryRun bool
+	filtersPrune  bool
+)
+
+var filtersCmd = &cobra.Command{
+	Use:   "filters",
+	Short: "Manage Gmail filters declaratively",
+	Long: `List Gmail filters and apply filters and forwarding addresses declared
+in a YAML or JSON file, so mail rules can be kept in version control.`,
+}
+
+var filtersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List filters",
+	Args:  cobra.NoArgs,
+	RunE:  runFiltersList,
+}
+
+var filtersApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply filters from a YAML or JSON file",
+	Long: `Compare the filters and forwarding addresses in a YAML or JSON file with
+the mailbox and create what is missing.
+
+Gmail filters cannot be edited, so a changed filter is created anew. With
+--prune, filters not in the file are deleted; otherwise they are listed as
+unmanaged. Labels used by new filters are created. Use --dry-run to show the
+changes without applying them.
+
+Example rules.yaml:
+  forwardingAddresses:
+    - archive@example.com
+  filters:
+    - name: newsletters
+      criteria:
+        from: news@example.com
+      action:
+        addLabels: [Newsletters]
+        archive: true
+
+Example:
+  gogoogle gmail filters apply --file=rules.yaml --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runFiltersApply,
+}
+
+func init() {
+	filtersApplyCmd.Flags().StringVarP(&filtersFile, "file", "f", "",
+		"YAML or JSON filters file (required)")
+	filtersApplyCmd.Flags().BoolVar(&filtersDryRun, "dry-run", false,
+		"Show changes without applying them")
+	filtersApplyCmd.Flags().BoolVar(&filtersPrune, "prune", false,
+		"Delete filters which are not in the file")
+	_ = filtersApplyCmd.MarkFlagRequired("file")
+
+	filtersCmd.AddCommand(filtersListCmd)
+	filtersCmd.AddCommand(filtersApplyCmd)
+}
+
+func newFiltersService(ctx context.Context, scopes []string) (*gmailutil.GmailService, error) {
+	httpClient, err := config.NewHTTPClient(ctx, scopes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authenticated client: %w", err)
+	}
+	svc, err := gmailutil.NewGmailService(ctx, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gmail service: %w", err)
+	}
+	return svc, nil
+}
+
+func runFiltersList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	svc, err := newFiltersService(ctx, []string{gmailutil.GmailReadonlyScope})
+	if err != nil {
+		return err
+	}
+	states, err := svc.SettingsAPI.FilterStates(ctx, gmailutil.UserIDMe)
+	if err != nil {
+		return fmt.Errorf("failed to list filters: %w", err)
+	}
+	for _, fs := range states {
+		fmt.Fprintf(os.Stdout, "%s\t%s\n", fs.ID, fs)
+	}
+	return nil
+}
+
+func runFiltersApply(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	cfg, err := gmailutil.ReadFiltersConfigFile(filtersFile)
+	if err != nil {
+		return fmt.Errorf("failed to read filters file: %w", err)
+	}
+
+	scopes := []string{gmailutil.GmailSettingsBasicScope, gmailutil.GmailLabelsScope}
+	if len(cfg.ForwardingAddresses) > 0 {
+		scopes = append(scopes, gmailutil.GmailSettingsSharingScope)
+	}
+	if filtersDryRun {
+		scopes = []string{gmailutil.GmailReadonlyScope}
+	}
+	svc, err := newFiltersService(ctx, scopes)
+	if err != nil {
+		return err
+	}
+
+	diff, err := svc.SettingsAPI.DiffFilters(ctx, gmailutil.UserIDMe, cfg, filtersPrune)
+	if err != nil {
+		return fmt.Errorf("failed to compare filters: %w", err)
+	}
+	for _, email := range diff.CreateForwardingAddresses {
+		fmt.Fprintf(os.Stdout, "+ forwarding address %s\n", email)
+	}
+	for _, name := range diff.CreateLabels {
+		fmt.Fprintf(os.Stdout, "+ label %s\n", name)
+	}
+	for _, fs := range diff.Create {
+		fmt.Fprintf(os.Stdout, "+ filter %s\n", fs)
+	}
+	for _, fs := range diff.Delete {
+		fmt.Fprintf(os.Stdout, "- filter %s (%s)\n", fs, fs.ID)
+	}
+	for _, fs := range diff.Unmanaged {
+		fmt.Fprintf(os.Stdout, "? unmanaged filter %s (%s)\n", fs, fs.ID)
+	}
+
+	if diff.IsEmpty() {
+		fmt.Fprintf(os.Stdout, "No changes; %d filter(s) up to date\n", len(diff.Unchanged))
+		return nil
+	} else if filtersDryRun {
+		fmt.Fprintf(os.Stdout, "Dry run: %d filter(s) to create, %d to delete, %d unchanged\n",
+			len(diff.Create), len(diff.Delete), len(diff.Unchanged))
+		return nil
+	}
+
+	if err := svc.SettingsAPI.ApplyFilters(ctx, gmailutil.UserIDMe, diff); err != nil {
+		return fmt.Errorf("failed to apply filters: %w", err)
+	}
+	fmt.Fprintf(os.Stdout, "Created %d filter(s), deleted %d, %d unchanged\n",
+		len(diff.Create), len(diff.Delete), len(diff.Unchanged))
+	return nil
+}
@@ -13,6 +13,15 @@ var Cmd = &cobra.Command{
 }
 
 func init() {
+	Cmd.AddCommand(attachmentsCmd)
+	Cmd.AddCommand(draftsCmd)
+	Cmd.AddCommand(exportCmd)
+	Cmd.AddCommand(filtersCmd)
 	Cmd.AddCommand(mergeCmd)
+	Cmd.AddCommand(outboxCmd)
+	Cmd.AddCommand(purgeCmd)
 	Cmd.AddCommand(sendMarkdownCmd)
+	Cmd.AddCommand(signatureCmd)
+	Cmd.AddCommand(statsCmd)
+	Cmd.AddCommand(vacationCmd)
 }
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -16,6 +19,7 @@ import (
 var (
 	// merge command flags
 	mergeSheetID         string
+	mergeRecipients      string
 	mergeSheetIndex      uint
 	mergeSheetHeaderRows uint32
 	mergeSubjectTemplate string
@@ -23,20 +27,35 @@ var (
 	mergeTextTemplate    string
 	mergeInlineFiles     []string
 	mergeAttachmentFiles []string
+	mergeInlineTmpls     []string
+	mergeAttachmentTmpls []string
+	mergeFilesDir        string
 	mergeGoauthFile      string
 	mergeGoauthAccount   string
+	mergeDraft           bool
+	mergeSendAt          string
+	mergeOutboxDir       string
+	mergeDryRun          bool
+	mergePreviewDir      string
+	mergeJournal         string
+	mergeWriteStatus     bool
+	mergeStatusColumn    string
+	mergeSentAtColumn    string
+	mergeStopOnError     bool
 )
 
 var mergeCmd = &cobra.Command{
 	Use:   "merge",
 	Short: "Send templated emails via mail merge",
-	Long: `Send templated emails using data from a Google Sheet.
+	Long: `Send templated emails using data from a Google Sheet or a local file.
 
-The Google Sheet should contain columns for recipients (TO, CC, BCC) and any
-template variables used in the subject and body templates.
+The recipients should contain columns for recipients (TO, CC, BCC) and any
+template variables used in the subject and body templates. Use --sheet-id for a
+Google Sheet or --recipients for a CSV, TSV, XLSX, JSON or NDJSON file, such as
+a CRM export. JSON files hold an array of objects or one object per line.
 
 Template files use Mustache syntax. The template variables are populated from
-the column headers in the Google Sheet.
+the column headers, or JSON object keys, of the recipients.
 
 Example:
   gogoogle gmail merge \
@@ -44,39 +63,180 @@ Example:
     --goauth-credentials-account=myaccount \
     --sheet-id=1abc123xyz \
     --subject-template=subject.mustache \
-    --html-template=body.mustache`,
+    --html-template=body.mustache
+
+With --recipients, --dry-run does not need credentials, so templates can be
+tested offline:
+  gogoogle gmail merge --recipients=contacts.csv \
+    --subject-template=subject.mustache --html-template=body.mustache \
+    --dry-run --preview-dir=preview
+
+Each recipient can also get their own files. Rows may list files in INLINE and
+ATTACHMENTS columns, separated by semicolons, and --inline-template and
+--attachment-template give filename templates such as invoices/{{ID}}.pdf.
+Files are local paths, relative to --files-dir, or Google Drive files as
+drive:<file ID> or Drive URLs. A missing file fails the merge before any email
+is sent.
+
+Use "gogoogle gmail merge lint" to check every row for problems first. Use
+--dry-run to list the messages without sending, and --preview-dir to also
+write each message as an .eml file with an index.html page to review.
+
+Each row is sent separately. Failed rows are reported and the merge continues
+unless --stop-on-error is set. With --journal, results are recorded in a local
+file and a rerun skips rows already sent. With --write-status, the status and
+sent time are written to the STATUS and SENT_AT columns of the sheet, which are
+added if missing; rows with status SENT are skipped.
+
+Use --send-at to stage the campaign in the outbox and send it at a set time
+with "gogoogle gmail outbox run".`,
 	RunE: runMerge,
 }
 
 func init() {
-	mergeCmd.Flags().StringVarP(&mergeGoauthFile, "goauth-credentials-file", "c", "",
+	mergeCmd.PersistentFlags().StringVarP(&mergeGoauthFile, "goauth-credentials-file", "c", "",
 		"Path to goauth CredentialsSet JSON file (env: GOAUTH_CREDENTIALS_FILE)")
-	mergeCmd.Flags().StringVarP(&mergeGoauthAccount, "goauth-credentials-account", "k", "",
+	mergeCmd.PersistentFlags().StringVarP(&mergeGoauthAccount, "goauth-credentials-account", "k", "",
 		"Account key within goauth CredentialsSet file (env: GOAUTH_CREDENTIALS_ACCOUNT)")
-	mergeCmd.Flags().StringVarP(&mergeSheetID, "sheet-id", "s", "",
-		"Google Sheet ID with recipients (required)")
-	mergeCmd.Flags().UintVarP(&mergeSheetIndex, "sheet-index", "x", 0,
+	mergeCmd.PersistentFlags().StringVarP(&mergeSheetID, "sheet-id", "s", "",
+		"Google Sheet ID with recipients")
+	mergeCmd.PersistentFlags().StringVar(&mergeRecipients, "recipients", "",
+		"Recipients file instead of a sheet: .csv, .tsv, .xlsx, .json, .ndjson or .jsonl")
+	mergeCmd.PersistentFlags().UintVarP(&mergeSheetIndex, "sheet-index", "x", 0,
 		"Sheet index within the spreadsheet")
-	mergeCmd.Flags().Uint32VarP(&mergeSheetHeaderRows, "sheet-header-rows", "r", 1,
+	mergeCmd.PersistentFlags().Uint32VarP(&mergeSheetHeaderRows, "sheet-header-rows", "r", 1,
 		"Number of header rows in the sheet")
-	mergeCmd.Flags().StringVarP(&mergeSubjectTemplate, "subject-template", "j", "",
+	mergeCmd.PersistentFlags().StringVarP(&mergeSubjectTemplate, "subject-template", "j", "",
 		"Subject template file (required)")
-	mergeCmd.Flags().StringVar(&mergeHTMLTemplate, "html-template", "",
+	mergeCmd.PersistentFlags().StringVar(&mergeHTMLTemplate, "html-template", "",
 		"HTML body template file")
-	mergeCmd.Flags().StringVarP(&mergeTextTemplate, "text-template", "t", "",
+	mergeCmd.PersistentFlags().StringVarP(&mergeTextTemplate, "text-template", "t", "",
 		"Text body template file")
-	mergeCmd.Flags().StringSliceVarP(&mergeInlineFiles, "inline", "i", nil,
+	mergeCmd.PersistentFlags().StringSliceVarP(&mergeInlineFiles, "inline", "i", nil,
 		"Inline attachment files")
-	mergeCmd.Flags().StringSliceVarP(&mergeAttachmentFiles, "attachment", "a", nil,
+	mergeCmd.PersistentFlags().StringSliceVarP(&mergeAttachmentFiles, "attachment", "a", nil,
 		"Attachment files")
+	mergeCmd.PersistentFlags().StringArrayVar(&mergeInlineTmpls, "inline-template", nil,
+		"Per-recipient inline image filename template, e.g. certs/{{ID}}.png")
+	mergeCmd.PersistentFlags().StringArrayVar(&mergeAttachmentTmpls, "attachment-template", nil,
+		"Per-recipient attachment filename template, e.g. invoices/{{ID}}.pdf")
+	mergeCmd.PersistentFlags().StringVar(&mergeFilesDir, "files-dir", "",
+		"Directory for relative per-recipient files (default: the --recipients file directory)")
+	mergeCmd.Flags().BoolVar(&mergeDraft, "draft", false,
+		"Create drafts for review instead of sending")
+	mergeCmd.Flags().StringVar(&mergeSendAt, "send-at", "",
+		"Queue in the outbox to send at this time (RFC 3339 or \"YYYY-MM-DD HH:MM\")")
+	mergeCmd.Flags().StringVar(&mergeOutboxDir, "outbox-dir", "",
+		"Outbox directory for --send-at (default: <user config dir>/gogoogle/outbox)")
+	mergeCmd.Flags().StringVar(&mergeJournal, "journal", "",
+		"Journal file recording each row; rerunning with it skips rows already sent")
+	mergeCmd.Flags().BoolVar(&mergeWriteStatus, "write-status", false,
+		"Write status and sent time to the sheet")
+	mergeCmd.PersistentFlags().StringVar(&mergeStatusColumn, "status-column", mailmerge.ColumnStatus,
+		"Sheet column for the send status; rows with SENT are skipped")
+	mergeCmd.PersistentFlags().StringVar(&mergeSentAtColumn, "sent-at-column", mailmerge.ColumnSentAt,
+		"Sheet column for the sent time")
+	mergeCmd.Flags().BoolVar(&mergeStopOnError, "stop-on-error", false,
+		"Stop at the first row which fails instead of continuing")
+	mergeCmd.Flags().BoolVar(&mergeDryRun, "dry-run", false,
+		"Render messages without sending them")
+	mergeCmd.Flags().StringVar(&mergePreviewDir, "preview-dir", "",
+		"With --dry-run, write .eml files and an index.html to this directory")
+
+	_ = mergeCmd.MarkPersistentFlagRequired("subject-template")
 
-	_ = mergeCmd.MarkFlagRequired("sheet-id")
-	_ = mergeCmd.MarkFlagRequired("subject-template")
+	mergeCmd.AddCommand(mergeLintCmd)
 }
 
 func runMerge(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	if mergePreviewDir != "" && !mergeDryRun {
+		return fmt.Errorf("--preview-dir requires --dry-run")
+	} else if mergeWriteStatus && mergeRecipients != "" {
+		return fmt.Errorf("--write-status requires --sheet-id; use --journal with --recipients")
+	}
+
+	var sendAt time.Time
+	if mergeSendAt != "" {
+		if mergeDraft {
+			return fmt.Errorf("--draft cannot be used with --send-at")
+		}
+		t, err := parseSendAt(mergeSendAt)
+		if err != nil {
+			return err
+		}
+		sendAt = t
+	}
+
+	mm, err := newMergeMailMerge(ctx, mergeDryRun)
+	if err != nil {
+		return err
+	}
+	defer removeMergeDriveFiles(mm)
+
+	if mergeDryRun {
+		return runMergeDryRun(mm)
+	}
+	mm.GmailService.DraftOnly = mergeDraft
+
+	if !sendAt.IsZero() {
+		ob, err := enqueueOutbox(mergeOutboxDir)
+		if err != nil {
+			return err
+		}
+		cnt, err := mm.Enqueue(ctx, ob, "", sendAt)
+		if err != nil {
+			return fmt.Errorf("failed to queue mail merge: %w", err)
+		}
+		fmt.Fprintf(os.Stdout, "Queued %d email message(s) for %s\n", cnt, sendAt.Local().Format(time.DateTime))
+		return nil
+	}
+
+	sendOpts := mailmerge.SendOpts{
+		StatusColumn: mergeStatusColumn,
+		StopOnError:  mergeStopOnError,
+		OnResult: func(r mailmerge.RowResult) {
+			row := mergeRowNumber(r.Row)
+			switch r.Status {
+			case mailmerge.RowStatusFailed:
+				fmt.Fprintf(os.Stderr, "row %d (%s): failed: %s\n", row, r.To, r.Error)
+			case mailmerge.RowStatusSkipped:
+				fmt.Fprintf(os.Stderr, "row %d (%s): already sent, skipped\n", row, r.To)
+			}
+		},
+	}
+	if mergeJournal != "" {
+		sendOpts.Journal = mailmerge.NewJournal(mergeJournal)
+	}
+	if mergeWriteStatus {
+		sw, err := mm.NewSheetStatusWriter(mergeStatusColumn, mergeSentAtColumn)
+		if err != nil {
+			return fmt.Errorf("failed to write status to sheet: %w", err)
+		}
+		sendOpts.StatusWriter = sw
+	}
+
+	res, err := mm.SendRows(ctx, sendOpts)
+	if err != nil {
+		return fmt.Errorf("failed to send mail merge: %w", err)
+	}
+
+	noun := "email message(s) sent"
+	if mergeDraft {
+		noun = "draft(s) created"
+	}
+	fmt.Fprintf(os.Stdout, "%d %s, %d skipped, %d failed\n", res.Sent, noun, res.Skipped, res.Failed)
+	if res.Failed > 0 {
+		return fmt.Errorf("%d row(s) failed; rerun with the same --journal to retry them", res.Failed)
+	}
+	return nil
+}
+
+// newMergeMailMerge creates the mail merge from the merge flags. If `offline` is set and
+// recipients are read from a file, no credentials are loaded, so messages can be
+// rendered but not sent.
+func newMergeMailMerge(ctx context.Context, offline bool) (*mailmerge.MailMerge, error) {
 	// Apply environment variable defaults.
 	if mergeGoauthFile == "" {
 		mergeGoauthFile = os.Getenv("GOAUTH_CREDENTIALS_FILE")
@@ -84,21 +244,29 @@ func runMerge(cmd *cobra.Command, args []string) error {
 	if mergeGoauthAccount == "" {
 		mergeGoauthAccount = os.Getenv("GOAUTH_CREDENTIALS_ACCOUNT")
 	}
-
-	if mergeGoauthFile == "" || mergeGoauthAccount == "" {
-		return fmt.Errorf("goauth credentials required: use --goauth-credentials-file and --goauth-credentials-account")
+	switch {
+	case mergeSheetID == "" && mergeRecipients == "":
+		return nil, fmt.Errorf("recipients required: use --sheet-id or --recipients")
+	case mergeSheetID != "" && mergeRecipients != "":
+		return nil, fmt.Errorf("--sheet-id cannot be used with --recipients")
 	}
 
+	// Recipients from a local file are rendered offline without credentials.
 	var googleClient *http.Client
-	creds, err := goauth.NewCredentialsFromSetFile(mergeGoauthFile, mergeGoauthAccount, true)
-	if err != nil {
-		return fmt.Errorf("failed to load credentials: %w", err)
-	}
-	tok, err := creds.NewOrExistingValidToken(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get token: %w", err)
+	if !offline || mergeRecipients == "" {
+		if mergeGoauthFile == "" || mergeGoauthAccount == "" {
+			return nil, fmt.Errorf("goauth credentials required: use --goauth-credentials-file and --goauth-credentials-account")
+		}
+		creds, err := goauth.NewCredentialsFromSetFile(mergeGoauthFile, mergeGoauthAccount, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credentials: %w", err)
+		}
+		tok, err := creds.NewOrExistingValidToken(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+		googleClient = authutil.NewClientTokenOAuth2(tok)
 	}
-	googleClient = authutil.NewClientTokenOAuth2(tok)
 
 	opts := mailmerge.MailMergeOpts{
 		GoauthCredsFile:                 mergeGoauthFile,
@@ -106,24 +274,64 @@ func runMerge(cmd *cobra.Command, args []string) error {
 		RecipientsGoogleSheetID:         mergeSheetID,
 		RecipientsGoogleSheetIndex:      mergeSheetIndex,
 		RecipientsGoogleSheetHeaderRows: mergeSheetHeaderRows,
+		RecipientsFilename:              mergeRecipients,
 		SubjectTemplateTextFilename:     mergeSubjectTemplate,
 		BodyTemplateHTMLFilename:        mergeHTMLTemplate,
 		BodyTemplateTextFilename:        mergeTextTemplate,
 		InlineFilenames:                 mergeInlineFiles,
 		AttachmentsFilenames:            mergeAttachmentFiles,
+		InlineFileTemplates:             mergeInlineTmpls,
+		AttachmentFileTemplates:         mergeAttachmentTmpls,
+		FilesDir:                        mergeFilesDir,
 		GoogleClient:                    googleClient,
 	}
 
 	mm, err := mailmerge.NewMailMerge(ctx, &opts)
 	if err != nil {
-		return fmt.Errorf("failed to create mail merge: %w", err)
+		return nil, fmt.Errorf("failed to create mail merge: %w", err)
 	}
+	return mm, nil
+}
 
-	cnt, err := mm.Send(ctx, "")
-	if err != nil {
-		return fmt.Errorf("failed to send mail merge: %w", err)
+// removeMergeDriveFiles removes Drive files downloaded for per-recipient files.
+func removeMergeDriveFiles(mm *mailmerge.MailMerge) {
+	if mm.DriveCacheDir != "" {
+		_ = os.RemoveAll(mm.DriveCacheDir)
 	}
+}
 
-	fmt.Fprintf(os.Stdout, "Successfully sent %d email message(s)\n", cnt)
+// mergeRowNumber returns the 1-based row number of table row `i` as shown in the
+// recipients sheet or file. JSON recipients are numbered by record.
+func mergeRowNumber(i int) int {
+	if mergeRecipients == "" {
+		return int(mergeSheetHeaderRows) + i + 1
+	}
+	switch strings.ToLower(filepath.Ext(mergeRecipients)) {
+	case ".json", ".ndjson", ".jsonl":
+		return i + 1
+	default:
+		return i + 2
+	}
+}
+
+func runMergeDryRun(mm *mailmerge.MailMerge) error {
+	if mergePreviewDir == "" {
+		msgs, err := mm.Messages()
+		if err != nil {
+			return fmt.Errorf("failed to render mail merge: %w", err)
+		}
+		for i, msg := range msgs {
+			fmt.Fprintf(os.Stdout, "%d\t%s\t%s\n", i+1,
+				strings.Join(msg.To.Strings(true, false, false), ","), msg.Subject)
+		}
+		fmt.Fprintf(os.Stdout, "Dry run: %d email message(s) not sent\n", len(msgs))
+		return nil
+	}
+	entries, err := mm.Preview(mergePreviewDir)
+	if err != nil {
+		return fmt.Errorf("failed to write preview: %w", err)
+	}
+	fmt.Fprintf(os.Stdout, "Dry run: %d email message(s) not sent; preview written to %s\n",
+		len(entries), filepath.Join(mergePreviewDir, mailmerge.PreviewIndexFilename))
 	return nil
 }
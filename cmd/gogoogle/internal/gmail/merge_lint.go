@@ -0,0 +1,98 @@
+package gmail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grokify/gogoogle/gmailutil/v1/mailmerge"
+)
+
+var (
+	// merge lint command flags
+	mergeLintJSON                 bool
+	mergeLintMaxMessageSize       int
+	mergeLintMaxRecipientsMessage int
+	mergeLintMaxRecipientsDay     int
+)
+
+var mergeLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check a mail merge without sending",
+	Long: `Check every row of a mail merge without sending and list all issues.
+
+Errors include invalid addresses, rows without recipients, template variables
+without a matching column, missing files and messages over the size or
+recipient limits. Warnings include duplicate recipients, unused columns, empty
+subjects and merges over the daily sending limit. The command fails if any
+error is found.
+
+The limits default to those of a free Gmail account; use
+--max-recipients-per-day=2000 for Google Workspace.
+
+Example:
+  gogoogle gmail merge lint --recipients=contacts.csv \
+    --subject-template=subject.mustache --html-template=body.mustache`,
+	Args: cobra.NoArgs,
+	RunE: runMergeLint,
+}
+
+func init() {
+	mergeLintCmd.Flags().BoolVar(&mergeLintJSON, "json", false,
+		"Print the report as JSON")
+	mergeLintCmd.Flags().IntVar(&mergeLintMaxMessageSize, "max-message-size", mailmerge.DefaultMaxMessageSize,
+		"Maximum encoded message size in bytes")
+	mergeLintCmd.Flags().IntVar(&mergeLintMaxRecipientsMessage, "max-recipients-per-message", mailmerge.DefaultMaxRecipientsPerMessage,
+		"Maximum recipients per message")
+	mergeLintCmd.Flags().IntVar(&mergeLintMaxRecipientsDay, "max-recipients-per-day", mailmerge.DefaultMaxRecipientsPerDay,
+		"Daily sending limit for all recipients of the merge")
+}
+
+func runMergeLint(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	mm, err := newMergeMailMerge(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer removeMergeDriveFiles(mm)
+
+	rpt, err := mm.Validate(ctx, &mailmerge.ValidateOpts{
+		MaxMessageSize:          mergeLintMaxMessageSize,
+		MaxRecipientsPerMessage: mergeLintMaxRecipientsMessage,
+		MaxRecipientsPerDay:     mergeLintMaxRecipientsDay,
+		StatusColumn:            mergeStatusColumn,
+		SentAtColumn:            mergeSentAtColumn,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check mail merge: %w", err)
+	}
+
+	if mergeLintJSON {
+		out, err := json.MarshalIndent(rpt, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(out))
+	} else {
+		for _, is := range rpt.Issues {
+			loc := "merge"
+			if is.Row != mailmerge.IssueRowAll {
+				loc = fmt.Sprintf("row %d", mergeRowNumber(is.Row))
+			}
+			if is.Column != "" {
+				loc += " " + is.Column
+			}
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%s\n", loc, is.Severity, is.Code, is.Message)
+		}
+		fmt.Fprintf(os.Stdout, "%d row(s), %d recipient(s): %d error(s), %d warning(s)\n", rpt.Rows, rpt.Recipients,
+			rpt.Count(mailmerge.SeverityError), rpt.Count(mailmerge.SeverityWarning))
+	}
+	if rpt.HasErrors() {
+		return fmt.Errorf("mail merge has %d error(s)", rpt.Count(mailmerge.SeverityError))
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grokify/gogoogle/cmd/gogoogle/internal/config"
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+)
+
+var (
+	// outbox command flags
+	outboxDir         string
+	outboxOnce        bool
+	outboxInterval    time.Duration
+	outboxMaxAttempts int
+)
+
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "Queue messages to send later",
+	Long: `Manage the local outbox of messages queued to be sent later.
+
+Queue messages with --send-at on send-markdown or merge, then send them with
+"outbox run". Messages are stored as JSON files in the outbox directory, so the
+queue survives restarts. Sends which fail with a 429 or 5xx response are
+retried with exponential backoff.`,
+}
+
+var outboxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queued and sent messages",
+	Args:  cobra.NoArgs,
+	RunE:  runOutboxList,
+}
+
+var outboxRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Send queued messages when they are due",
+	Long: `Send queued messages when they are due.
+
+Without --once, the outbox is checked every --interval until interrupted.
+Run one "outbox run" per outbox directory.
+
+Example:
+  gogoogle gmail send-markdown --to=team@example.com --subject="Standup" \
+    --body=@standup.md --send-at="2026-10-19 09:00"
+  gogoogle gmail outbox run`,
+	Args: cobra.NoArgs,
+	RunE: runOutboxRun,
+}
+
+var outboxRemoveCmd = &cobra.Command{
+	Use:   "remove ID...",
+	Short: "Remove messages from the outbox",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runOutboxRemove,
+}
+
+func init() {
+	outboxCmd.PersistentFlags().StringVar(&outboxDir, "dir", "",
+		"Outbox directory (default: <user config dir>/gogoogle/outbox)")
+
+	outboxRunCmd.Flags().BoolVar(&outboxOnce, "once", false,
+		"Send due messages and exit")
+	outboxRunCmd.Flags().DurationVar(&outboxInterval, "interval", gmailutil.DefaultOutboxPollInterval,
+		"How often to check for due messages")
+	outboxRunCmd.Flags().IntVar(&outboxMaxAttempts, "max-attempts", gmailutil.DefaultOutboxMaxAttempts,
+		"Attempts before a message is marked failed")
+
+	outboxCmd.AddCommand(outboxListCmd)
+	outboxCmd.AddCommand(outboxRunCmd)
+	outboxCmd.AddCommand(outboxRemoveCmd)
+}
+
+// newOutboxStore returns the store for the outbox directory `dir`, or the default
+// directory if `dir` is empty.
+func newOutboxStore(dir string) (*gmailutil.FileOutboxStore, error) {
+	if dir = strings.TrimSpace(dir); dir == "" {
+		cfgDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find outbox directory: %w", err)
+		}
+		dir = filepath.Join(cfgDir, "gogoogle", "outbox")
+	}
+	return gmailutil.NewFileOutboxStore(dir), nil
+}
+
+// parseSendAt parses a `--send-at` time as RFC 3339, or as a local date with an optional
+// time such as "2026-10-19 09:00".
+func parseSendAt(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid send time %q: use RFC 3339 or YYYY-MM-DD [HH:MM]", s)
+	}
+	return t, nil
+}
+
+// enqueueOutbox returns an outbox for queueing messages without a Gmail service.
+func enqueueOutbox(dir string) (*gmailutil.Outbox, error) {
+	store, err := newOutboxStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	return gmailutil.NewOutbox(nil, store), nil
+}
+
+func runOutboxList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	store, err := newOutboxStore(outboxDir)
+	if err != nil {
+		return err
+	}
+	items, err := store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list outbox: %w", err)
+	}
+	for _, item := range items {
+		detail := item.MessageID
+		if item.Status != gmailutil.OutboxStatusSent {
+			detail = item.LastError
+		}
+		fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", item.ID, item.Status,
+			item.SendAt.Local().Format(time.DateTime), item.Attempts, item.To, item.Subject, detail)
+	}
+	fmt.Fprintf(os.Stdout, "%d message(s)\n", len(items))
+	return nil
+}
+
+func runOutboxRun(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store, err := newOutboxStore(outboxDir)
+	if err != nil {
+		return err
+	}
+	httpClient, err := config.NewHTTPClient(ctx, []string{gmailutil.GmailSendScope})
+	if err != nil {
+		return fmt.Errorf("failed to create authenticated client: %w", err)
+	}
+	svc, err := gmailutil.NewGmailService(ctx, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create Gmail service: %w", err)
+	}
+
+	ob := gmailutil.NewOutbox(svc, store)
+	ob.MaxAttempts = outboxMaxAttempts
+	ob.PollInterval = outboxInterval
+	ob.OnSend = func(item gmailutil.OutboxItem, err error) {
+		switch item.Status {
+		case gmailutil.OutboxStatusSent:
+			fmt.Fprintf(os.Stderr, "sent %s (message ID: %s)\n", item.ID, item.MessageID)
+		case gmailutil.OutboxStatusFailed:
+			fmt.Fprintf(os.Stderr, "failed %s after %d attempt(s): %v\n", item.ID, item.Attempts, err)
+		default:
+			fmt.Fprintf(os.Stderr, "retrying %s at %s: %v\n", item.ID,
+				item.NextAttemptAt.Local().Format(time.DateTime), err)
+		}
+	}
+
+	if !outboxOnce {
+		fmt.Fprintf(os.Stderr, "Sending from outbox %s every %s (Ctrl+C to stop)\n", store.Dir, outboxInterval)
+		return ob.Run(ctx)
+	}
+	res, err := ob.SendDue(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to send outbox: %w", err)
+	}
+	fmt.Fprintf(os.Stdout, "Sent %d message(s), %d to retry, %d failed, %d pending\n",
+		res.Sent, res.Retry, res.Failed, res.Pending)
+	return nil
+}
+
+func runOutboxRemove(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	store, err := newOutboxStore(outboxDir)
+	if err != nil {
+		return err
+	}
+	for _, id := range args {
+		if err := store.Delete(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", id, err)
+		}
+		fmt.Fprintf(os.Stdout, "Removed %s\n", id)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grokify/gogoogle/cmd/gogoogle/internal/config"
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+)
+
+var (
+	// purge command flags
+	purgeQuery       string
+	purgeDryRun      bool
+	purgePermanent   bool
+	purgeMaxMessages int
+	purgePreview     int
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Trash or delete messages matching a query",
+	Long: `Move all messages matching a Gmail search query to the trash.
+
+Use --dry-run to see how many messages match, and a preview of the first
+matches, without modifying anything. Use --permanent to delete messages
+instead of moving them to the trash; this cannot be undone and requires the
+full https://mail.google.com/ scope.
+
+Example:
+  gogoogle gmail purge \
+    --query="from:newsletter@example.com older_than:1y" \
+    --dry-run`,
+	RunE: runPurge,
+}
+
+func init() {
+	purgeCmd.Flags().StringVarP(&purgeQuery, "query", "q", "",
+		"Gmail search query (required)")
+	purgeCmd.Flags().BoolVar(&purgeDryRun, "dry-run", false,
+		"List matching messages without modifying them")
+	purgeCmd.Flags().BoolVar(&purgePermanent, "permanent", false,
+		"Permanently delete messages instead of moving them to the trash")
+	purgeCmd.Flags().IntVar(&purgeMaxMessages, "max-messages", 0,
+		"Maximum number of messages to process (0 for no limit)")
+	purgeCmd.Flags().IntVar(&purgePreview, "preview", 10,
+		"Number of matching messages to show with --dry-run")
+
+	_ = purgeCmd.MarkFlagRequired("query")
+}
+
+func runPurge(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	expr, err := gmailutil.ParseQuery(purgeQuery)
+	if err != nil {
+		return fmt.Errorf("failed to parse query: %w", err)
+	}
+
+	action := gmailutil.BulkActionTrash
+	scope := gmailutil.GmailModifyScope
+	if purgePermanent {
+		action = gmailutil.BulkActionDelete
+		scope = gmailutil.MailGoogleComScope
+	}
+	if purgeDryRun {
+		scope = gmailutil.GmailReadonlyScope
+	}
+
+	httpClient, err := config.NewHTTPClient(ctx, []string{scope})
+	if err != nil {
+		return fmt.Errorf("failed to create authenticated client: %w", err)
+	}
+
+	svc, err := gmailutil.NewGmailService(ctx, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create Gmail service: %w", err)
+	}
+
+	opts := gmailutil.BulkActionOpts{
+		Query:    gmailutil.MessagesListQueryOpts{Expr: expr},
+		MaxTotal: purgeMaxMessages,
+		Action:   action,
+		DryRun:   purgeDryRun,
+		Progress: func(p gmailutil.BulkProgress) {
+			fmt.Fprintf(os.Stderr, "%s: matched %d, processed %d\n", p.Stage, p.Matched, p.Processed)
+		},
+	}
+	if purgeDryRun {
+		opts.PreviewCount = purgePreview
+	}
+
+	res, err := svc.MessagesAPI.BulkAction(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to %s messages: %w", action, err)
+	}
+
+	if res.DryRun {
+		for _, pm := range res.Preview {
+			from := ""
+			if pm.From != nil {
+				from = pm.From.Address
+			}
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%s\n", pm.ID, pm.Date.Format("2006-01-02"), from, pm.Subject)
+		}
+		fmt.Fprintf(os.Stdout, "Dry run: %d message(s) match %q and would be %s\n",
+			res.Matched, res.Query, purgeVerb(action))
+		return nil
+	}
+
+	fmt.Fprintf(os.Stdout, "%d of %d message(s) matching %q %s\n",
+		res.Processed, res.Matched, res.Query, purgeVerb(action))
+	return nil
+}
+
+func purgeVerb(action string) string {
+	if action == gmailutil.BulkActionDelete {
+		return "permanently deleted"
+	}
+	return "moved to trash"
+}
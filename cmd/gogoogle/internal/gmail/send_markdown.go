@@ -5,24 +5,29 @@ import (
 	"fmt"
 	"net/mail"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/grokify/gogoogle/cmd/gogoogle/internal/config"
 	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
-	"github.com/grokify/mogo/mime/multipartutil"
 	"github.com/grokify/mogo/net/mailutil"
 )
 
 var (
 	// send-markdown command flags
-	sendFrom    string
-	sendTo      []string
-	sendCc      []string
-	sendBcc     []string
-	sendSubject string
-	sendBody    string
+	sendFrom      string
+	sendTo        []string
+	sendCc        []string
+	sendBcc       []string
+	sendSubject   string
+	sendBody      string
+	sendDraft     bool
+	sendAttach    []string
+	sendSendAt    string
+	sendOutboxDir string
 )
 
 var sendMarkdownCmd = &cobra.Command{
@@ -31,7 +36,15 @@ var sendMarkdownCmd = &cobra.Command{
 	Long: `Send an email with a markdown-formatted body.
 
 The body can be specified as inline text or as a file reference using @filename.md.
-The markdown is converted to both plain text and HTML for maximum compatibility.
+The markdown (CommonMark with GitHub extensions such as tables and task lists)
+is rendered as plain text and as HTML with inline styles for email clients.
+
+Images which reference local files, such as ![logo](./logo.png), are sent as
+inline parts. Paths are relative to the body file, or to the current directory
+for inline text. Use --attach to add attachments.
+
+With --send-at, the message is queued in the outbox and sent by
+"gogoogle gmail outbox run".
 
 Example:
   gogoogle gmail send-markdown \
@@ -45,7 +58,8 @@ Example:
   gogoogle gmail send-markdown \
     --to="user@example.com" \
     --subject="Newsletter" \
-    --body=@newsletter.md`,
+    --body=@newsletter.md \
+    --attach=report.pdf`,
 	RunE: runSendMarkdown,
 }
 
@@ -62,6 +76,14 @@ func init() {
 		"Email subject (required)")
 	sendMarkdownCmd.Flags().StringVarP(&sendBody, "body", "b", "",
 		"Markdown body text or @filename.md to read from file (required)")
+	sendMarkdownCmd.Flags().BoolVar(&sendDraft, "draft", false,
+		"Create a draft instead of sending")
+	sendMarkdownCmd.Flags().StringSliceVarP(&sendAttach, "attach", "a", nil,
+		"Files to attach (repeatable)")
+	sendMarkdownCmd.Flags().StringVar(&sendSendAt, "send-at", "",
+		"Queue in the outbox to send at this time (RFC 3339 or \"YYYY-MM-DD HH:MM\")")
+	sendMarkdownCmd.Flags().StringVar(&sendOutboxDir, "outbox-dir", "",
+		"Outbox directory for --send-at (default: <user config dir>/gogoogle/outbox)")
 
 	_ = sendMarkdownCmd.MarkFlagRequired("to")
 	_ = sendMarkdownCmd.MarkFlagRequired("subject")
@@ -71,20 +93,21 @@ func init() {
 func runSendMarkdown(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	scopes := []string{gmailutil.GmailSendScope}
-
-	httpClient, err := config.NewHTTPClient(ctx, scopes)
-	if err != nil {
-		return fmt.Errorf("failed to create authenticated client: %w", err)
-	}
-
-	svc, err := gmailutil.NewGmailService(ctx, httpClient)
-	if err != nil {
-		return fmt.Errorf("failed to create Gmail service: %w", err)
+	var sendAt time.Time
+	if sendSendAt != "" {
+		if sendDraft {
+			return fmt.Errorf("--draft cannot be used with --send-at")
+		}
+		t, err := parseSendAt(sendSendAt)
+		if err != nil {
+			return err
+		}
+		sendAt = t
 	}
 
 	// Parse body - handle @filename syntax.
 	bodyText := sendBody
+	baseDir := ""
 	if strings.HasPrefix(sendBody, "@") {
 		filename := strings.TrimPrefix(sendBody, "@")
 		data, err := os.ReadFile(filename)
@@ -92,6 +115,13 @@ func runSendMarkdown(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to read body file %q: %w", filename, err)
 		}
 		bodyText = string(data)
+		baseDir = filepath.Dir(filename)
+	}
+
+	// Render body with local images inline and attachments.
+	body, err := gmailutil.NewMarkdownPartsSetFiles(bodyText, baseDir, sendAttach)
+	if err != nil {
+		return fmt.Errorf("failed to build message body: %w", err)
 	}
 
 	// Parse recipient addresses.
@@ -99,25 +129,56 @@ func runSendMarkdown(cmd *cobra.Command, args []string) error {
 	ccAddrs := parseAddressList(sendCc)
 	bccAddrs := parseAddressList(sendBcc)
 
-	// Create simple HTML from markdown (basic conversion).
-	htmlBody := markdownToHTML(bodyText)
-
 	// Build message.
 	msg := mailutil.MessageWriter{
-		To:      toAddrs,
-		Cc:      ccAddrs,
-		Bcc:     bccAddrs,
-		Subject: sendSubject,
-		BodyPartsSet: multipartutil.NewPartsSetAlternative(
-			[]byte(bodyText), []byte(htmlBody)),
+		To:           toAddrs,
+		Cc:           ccAddrs,
+		Bcc:          bccAddrs,
+		Subject:      sendSubject,
+		BodyPartsSet: body,
 	}
 
+	// Queue the message to send later.
+	if !sendAt.IsZero() {
+		ob, err := enqueueOutbox(sendOutboxDir)
+		if err != nil {
+			return err
+		}
+		item, err := ob.Enqueue(ctx, sendFrom, msg, sendAt)
+		if err != nil {
+			return fmt.Errorf("failed to queue email: %w", err)
+		}
+		fmt.Fprintf(os.Stdout, "Email queued for %s (outbox ID: %s)\n",
+			item.SendAt.Local().Format(time.DateTime), item.ID)
+		return nil
+	}
+
+	scopes := []string{gmailutil.GmailSendScope}
+	if sendDraft {
+		scopes = []string{gmailutil.GmailComposeScope}
+	}
+
+	httpClient, err := config.NewHTTPClient(ctx, scopes)
+	if err != nil {
+		return fmt.Errorf("failed to create authenticated client: %w", err)
+	}
+
+	svc, err := gmailutil.NewGmailService(ctx, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create Gmail service: %w", err)
+	}
+	svc.DraftOnly = sendDraft
+
 	// Send the message.
 	result, err := svc.Send(ctx, sendFrom, msg)
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
 
+	if sendDraft {
+		fmt.Fprintf(os.Stdout, "Draft created (message ID: %s)\n", result.Id)
+		return nil
+	}
 	fmt.Fprintf(os.Stdout, "Email sent successfully (ID: %s)\n", result.Id)
 	return nil
 }
@@ -134,80 +195,3 @@ func parseAddressList(addrs []string) mailutil.Addresses {
 	}
 	return result
 }
-
-// markdownToHTML performs a basic markdown to HTML conversion.
-// For full markdown support, consider using a proper markdown library.
-func markdownToHTML(md string) string {
-	lines := strings.Split(md, "\n")
-	var html strings.Builder
-	html.WriteString("<html><body>\n")
-
-	inParagraph := false
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		// Handle headers.
-		if strings.HasPrefix(trimmed, "# ") {
-			if inParagraph {
-				html.WriteString("</p>\n")
-				inParagraph = false
-			}
-			html.WriteString("<h1>")
-			html.WriteString(escapeHTML(strings.TrimPrefix(trimmed, "# ")))
-			html.WriteString("</h1>\n")
-			continue
-		}
-		if strings.HasPrefix(trimmed, "## ") {
-			if inParagraph {
-				html.WriteString("</p>\n")
-				inParagraph = false
-			}
-			html.WriteString("<h2>")
-			html.WriteString(escapeHTML(strings.TrimPrefix(trimmed, "## ")))
-			html.WriteString("</h2>\n")
-			continue
-		}
-		if strings.HasPrefix(trimmed, "### ") {
-			if inParagraph {
-				html.WriteString("</p>\n")
-				inParagraph = false
-			}
-			html.WriteString("<h3>")
-			html.WriteString(escapeHTML(strings.TrimPrefix(trimmed, "### ")))
-			html.WriteString("</h3>\n")
-			continue
-		}
-
-		// Handle empty lines.
-		if trimmed == "" {
-			if inParagraph {
-				html.WriteString("</p>\n")
-				inParagraph = false
-			}
-			continue
-		}
-
-		// Regular text - wrap in paragraph.
-		if !inParagraph {
-			html.WriteString("<p>")
-			inParagraph = true
-		} else {
-			html.WriteString("<br>")
-		}
-		html.WriteString(escapeHTML(trimmed))
-	}
-
-	if inParagraph {
-		html.WriteString("</p>\n")
-	}
-	html.WriteString("</body></html>")
-	return html.String()
-}
-
-func escapeHTML(s string) string {
-	s = strings.ReplaceAll(s, "&", "&amp;")
-	s = strings.ReplaceAll(s, "<", "&lt;")
-	s = strings.ReplaceAll(s, ">", "&gt;")
-	s = strings.ReplaceAll(s, "\"", "&quot;")
-	return s
-}
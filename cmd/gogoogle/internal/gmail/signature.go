@@ -0,0 +1,100 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+)
+
+var (
+	// signature command flags
+	signatureUsers     []string
+	signatureUsersFile string
+	signatureSendAs    string
+	signatureBody      string
+)
+
+var signatureCmd = &cobra.Command{
+	Use:   "signature",
+	Short: "Set or clear signatures",
+	Long: `Set or clear the signature for the authenticated user or, with --users or
+--users-file, for each listed user using domain-wide delegation.
+
+Domain-wide delegation requires a service account key set with --credentials
+whose client ID is authorized for the gmail.settings.basic scope.`,
+}
+
+var signatureSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Set the signature",
+	Long: `Set the signature of the primary address, or of the --send-as alias, from
+markdown converted to HTML.
+
+The body can be specified as inline text or as a file reference using @filename.md.
+
+Example:
+  gogoogle gmail signature set \
+    --credentials=service-account.json \
+    --users-file=oncall.txt \
+    --send-as=oncall@example.com \
+    --body=@signature.md`,
+	Args: cobra.NoArgs,
+	RunE: runSignatureSet,
+}
+
+var signatureClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear the signature",
+	Args:  cobra.NoArgs,
+	RunE:  runSignatureClear,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{signatureSetCmd, signatureClearCmd} {
+		c.Flags().StringSliceVar(&signatureUsers, "users", nil,
+			"Users to update with domain-wide delegation")
+		c.Flags().StringVar(&signatureUsersFile, "users-file", "",
+			"File with one user email per line")
+		c.Flags().StringVar(&signatureSendAs, "send-as", "",
+			"Send-as alias to update (default primary address)")
+	}
+	signatureSetCmd.Flags().StringVarP(&signatureBody, "body", "b", "",
+		"Markdown signature or @filename.md to read from file (required)")
+	_ = signatureSetCmd.MarkFlagRequired("body")
+
+	signatureCmd.AddCommand(signatureSetCmd)
+	signatureCmd.AddCommand(signatureClearCmd)
+}
+
+func runSignatureSet(cmd *cobra.Command, args []string) error {
+	body, err := readBodyArg(signatureBody)
+	if err != nil {
+		return err
+	}
+	return updateSignatures(gmailutil.MarkdownToHTML(body), "set")
+}
+
+func runSignatureClear(cmd *cobra.Command, args []string) error {
+	return updateSignatures("", "cleared")
+}
+
+func updateSignatures(signatureHTML, verb string) error {
+	ctx := context.Background()
+	users, err := readUsers(signatureUsers, signatureUsersFile)
+	if err != nil {
+		return err
+	}
+	return forEachUser(ctx, users, []string{gmailutil.GmailSettingsBasicScope},
+		func(svc *gmailutil.GmailService, userID string) error {
+			sa, err := svc.SettingsAPI.UpdateSignature(ctx, userID, signatureSendAs, signatureHTML)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "%s: signature %s for %s\n", userID, verb, sa.SendAsEmail)
+			return nil
+		})
+}
@@ -0,0 +1,149 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grokify/gogoogle/cmd/gogoogle/internal/config"
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+)
+
+var (
+	// stats command flags
+	statsQuery       string
+	statsLabels      []string
+	statsBy          []string
+	statsTop         int
+	statsOutput      string
+	statsMaxMessages int
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Report message counts and sizes by sender, domain, label and week",
+	Long: `Scan messages matching a Gmail search query and report message counts
+and total sizes by sender, sender domain, label and ISO week.
+
+Only message metadata is retrieved. Without --output, the report is written
+to stdout as Markdown. The output format is chosen by the file extension:
+.csv, .xlsx or .md. XLSX files have a sheet per dimension; with more than one
+dimension, CSV output is written to one file per dimension, e.g.
+stats-sender.csv.
+
+Example:
+  gogoogle gmail stats \
+    --query="category:promotions older_than:6m" \
+    --by=sender,domain \
+    --top=25 \
+    --output=stats.xlsx`,
+	RunE: runStats,
+}
+
+func init() {
+	statsCmd.Flags().StringVarP(&statsQuery, "query", "q", "",
+		"Gmail search query (required)")
+	statsCmd.Flags().StringSliceVar(&statsLabels, "label", nil,
+		"Label names to restrict to (messages must have all labels)")
+	statsCmd.Flags().StringSliceVar(&statsBy, "by", gmailutil.StatsDimensions,
+		"Dimensions to report: sender, domain, label, week")
+	statsCmd.Flags().IntVar(&statsTop, "top", 20,
+		"Maximum rows per dimension, sorted by message count (0 for all; weeks are not limited)")
+	statsCmd.Flags().StringVarP(&statsOutput, "output", "o", "",
+		"Output file (.csv, .xlsx or .md); Markdown to stdout if empty")
+	statsCmd.Flags().IntVar(&statsMaxMessages, "max-messages", 0,
+		"Maximum number of messages to scan (0 for no limit)")
+
+	_ = statsCmd.MarkFlagRequired("query")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	for _, dim := range statsBy {
+		if !slices.Contains(gmailutil.StatsDimensions, dim) {
+			return fmt.Errorf("unsupported --by dimension %q", dim)
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(statsOutput))
+	if statsOutput != "" && ext != ".csv" && ext != ".xlsx" && ext != ".md" {
+		return fmt.Errorf("unsupported output format %q: use .csv, .xlsx or .md", ext)
+	}
+
+	expr, err := gmailutil.ParseQuery(statsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to parse query: %w", err)
+	}
+
+	httpClient, err := config.NewHTTPClient(ctx, []string{gmailutil.GmailReadonlyScope})
+	if err != nil {
+		return fmt.Errorf("failed to create authenticated client: %w", err)
+	}
+
+	svc, err := gmailutil.NewGmailService(ctx, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create Gmail service: %w", err)
+	}
+
+	labelIDs, err := svc.LabelsAPI.LabelIDs(ctx, gmailutil.UserIDMe, statsLabels)
+	if err != nil {
+		return fmt.Errorf("failed to resolve labels: %w", err)
+	}
+
+	ms, err := svc.MessagesAPI.Stats(ctx, gmailutil.StatsOpts{
+		Query:    gmailutil.MessagesListQueryOpts{Expr: expr},
+		LabelIDs: labelIDs,
+		MaxTotal: statsMaxMessages,
+		Progress: func(scanned int) {
+			fmt.Fprintf(os.Stderr, "scanned %d message(s)\n", scanned)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan messages: %w", err)
+	}
+	if len(ms.Errors) > 0 {
+		fmt.Fprintf(os.Stderr, "%d message(s) could not be retrieved\n", len(ms.Errors))
+	}
+
+	ts, err := ms.TableSet(statsBy, statsTop)
+	if err != nil {
+		return err
+	}
+
+	switch ext {
+	case ".xlsx":
+		if err := ts.WriteXLSX(statsOutput); err != nil {
+			return fmt.Errorf("failed to write XLSX: %w", err)
+		}
+	case ".csv":
+		for _, name := range ts.Order {
+			path := statsOutput
+			if len(ts.Order) > 1 {
+				path = strings.TrimSuffix(statsOutput, filepath.Ext(statsOutput)) + "-" + strings.ToLower(name) + ".csv"
+			}
+			if err := ts.TableMap[name].WriteCSV(path); err != nil {
+				return fmt.Errorf("failed to write CSV: %w", err)
+			}
+		}
+	default:
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "# Mailbox Stats\n\n%d message(s), %d bytes matching `%s`\n", ms.Messages, ms.Bytes, statsQuery)
+		for _, name := range ts.Order {
+			fmt.Fprintf(&sb, "\n## %s\n\n%s\n", name, ts.TableMap[name].Markdown("\n", true))
+		}
+		if statsOutput == "" {
+			fmt.Fprint(os.Stdout, sb.String())
+			return nil
+		} else if err := os.WriteFile(statsOutput, []byte(sb.String()), 0o644); err != nil {
+			return fmt.Errorf("failed to write Markdown: %w", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "Wrote stats for %d message(s) to %s\n", ms.Messages, statsOutput)
+	return nil
+}
@@ -0,0 +1,92 @@
+package gmail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grokify/gogoogle/cmd/gogoogle/internal/config"
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+)
+
+// readUsers returns the users from `users` and, if set, `usersFile`, which has one
+// email address per line with `#` comments.
+func readUsers(users []string, usersFile string) ([]string, error) {
+	var out []string
+	for _, u := range users {
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, u)
+		}
+	}
+	if usersFile == "" {
+		return out, nil
+	}
+	f, err := os.Open(usersFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file %q: %w", usersFile, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			out = append(out, line)
+		}
+	}
+	return out, scanner.Err()
+}
+
+// forEachUser calls `fn` with a Gmail service acting as each user, using domain-wide
+// delegation. If `users` is empty, `fn` is called once for the authenticated user.
+// Failures are reported to stderr and do not stop the remaining users.
+func forEachUser(ctx context.Context, users []string, scopes []string, fn func(svc *gmailutil.GmailService, userID string) error) error {
+	if len(users) == 0 {
+		httpClient, err := config.NewHTTPClient(ctx, scopes)
+		if err != nil {
+			return fmt.Errorf("failed to create authenticated client: %w", err)
+		}
+		svc, err := gmailutil.NewGmailService(ctx, httpClient)
+		if err != nil {
+			return fmt.Errorf("failed to create Gmail service: %w", err)
+		}
+		return fn(svc, gmailutil.UserIDMe)
+	}
+
+	failed := 0
+	for _, user := range users {
+		err := func() error {
+			httpClient, err := config.NewHTTPClientForUser(ctx, user, scopes)
+			if err != nil {
+				return fmt.Errorf("failed to create delegated client: %w", err)
+			}
+			svc, err := gmailutil.NewGmailService(ctx, httpClient)
+			if err != nil {
+				return fmt.Errorf("failed to create Gmail service: %w", err)
+			}
+			return fn(svc, user)
+		}()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", user, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d user(s) failed", failed, len(users))
+	}
+	return nil
+}
+
+// readBodyArg returns `s`, or the contents of the file if `s` is `@filename`.
+func readBodyArg(s string) (string, error) {
+	if !strings.HasPrefix(s, "@") {
+		return s, nil
+	}
+	filename := strings.TrimPrefix(s, "@")
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read body file %q: %w", filename, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,151 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+)
+
+var (
+	// vacation command flags
+	vacationUsers        []string
+	vacationUsersFile    string
+	vacationSubject      string
+	vacationBody         string
+	vacationStart        string
+	vacationEnd          string
+	vacationContactsOnly bool
+	vacationDomainOnly   bool
+)
+
+var vacationCmd = &cobra.Command{
+	Use:   "vacation",
+	Short: "Set or clear vacation auto-replies",
+	Long: `Set or clear the vacation auto-reply for the authenticated user or, with
+--users or --users-file, for each listed user using domain-wide delegation.
+
+Domain-wide delegation requires a service account key set with --credentials
+whose client ID is authorized for the gmail.settings.basic scope.`,
+}
+
+var vacationSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Enable a vacation auto-reply",
+	Long: `Enable a vacation auto-reply with a markdown body.
+
+The body can be specified as inline text or as a file reference using @filename.md.
+The markdown is rendered as the plain text and HTML replies.
+
+Example:
+  gogoogle gmail vacation set \
+    --credentials=service-account.json \
+    --users=alice@example.com,bob@example.com \
+    --subject="Out of office" \
+    --body=@away.md \
+    --start=2026-12-20 --end=2027-01-04 \
+    --domain-only`,
+	Args: cobra.NoArgs,
+	RunE: runVacationSet,
+}
+
+var vacationClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Disable the vacation auto-reply",
+	Args:  cobra.NoArgs,
+	RunE:  runVacationClear,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{vacationSetCmd, vacationClearCmd} {
+		c.Flags().StringSliceVar(&vacationUsers, "users", nil,
+			"Users to update with domain-wide delegation")
+		c.Flags().StringVar(&vacationUsersFile, "users-file", "",
+			"File with one user email per line")
+	}
+	vacationSetCmd.Flags().StringVarP(&vacationSubject, "subject", "s", "",
+		"Auto-reply subject")
+	vacationSetCmd.Flags().StringVarP(&vacationBody, "body", "b", "",
+		"Markdown body text or @filename.md to read from file (required)")
+	vacationSetCmd.Flags().StringVar(&vacationStart, "start", "",
+		"Start time as YYYY-MM-DD or RFC 3339")
+	vacationSetCmd.Flags().StringVar(&vacationEnd, "end", "",
+		"End time as YYYY-MM-DD or RFC 3339")
+	vacationSetCmd.Flags().BoolVar(&vacationContactsOnly, "contacts-only", false,
+		"Only reply to senders in the user's contacts")
+	vacationSetCmd.Flags().BoolVar(&vacationDomainOnly, "domain-only", false,
+		"Only reply to senders in the user's domain")
+	_ = vacationSetCmd.MarkFlagRequired("body")
+
+	vacationCmd.AddCommand(vacationSetCmd)
+	vacationCmd.AddCommand(vacationClearCmd)
+}
+
+func runVacationSet(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	body, err := readBodyArg(vacationBody)
+	if err != nil {
+		return err
+	}
+	opts := gmailutil.VacationOpts{
+		Subject:      vacationSubject,
+		Markdown:     body,
+		ContactsOnly: vacationContactsOnly,
+		DomainOnly:   vacationDomainOnly}
+	if opts.Start, err = parseVacationTime(vacationStart); err != nil {
+		return fmt.Errorf("invalid --start: %w", err)
+	} else if opts.End, err = parseVacationTime(vacationEnd); err != nil {
+		return fmt.Errorf("invalid --end: %w", err)
+	}
+	// validate before contacting any user
+	if _, err := opts.VacationSettings(); err != nil {
+		return err
+	}
+
+	users, err := readUsers(vacationUsers, vacationUsersFile)
+	if err != nil {
+		return err
+	}
+	return forEachUser(ctx, users, []string{gmailutil.GmailSettingsBasicScope},
+		func(svc *gmailutil.GmailService, userID string) error {
+			if _, err := svc.SettingsAPI.SetVacation(ctx, userID, opts); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "%s: vacation auto-reply enabled\n", userID)
+			return nil
+		})
+}
+
+func runVacationClear(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	users, err := readUsers(vacationUsers, vacationUsersFile)
+	if err != nil {
+		return err
+	}
+	return forEachUser(ctx, users, []string{gmailutil.GmailSettingsBasicScope},
+		func(svc *gmailutil.GmailService, userID string) error {
+			if _, err := svc.SettingsAPI.ClearVacation(ctx, userID); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "%s: vacation auto-reply disabled\n", userID)
+			return nil
+		})
+}
+
+// parseVacationTime parses a date in local time or an RFC 3339 timestamp. An empty
+// string returns the zero time.
+func parseVacationTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	} else if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -8,6 +8,18 @@ const (
 	MailGoogleComScope = gmail.MailGoogleComScope // "https://mail.google.com/"
 	GmailReadonlyScope = gmail.GmailReadonlyScope // "https://www.googleapis.com/auth/gmail.readonly"
 	GmailSendScope     = gmail.GmailSendScope     // "https://www.googleapis.com/auth/gmail.send"
+	GmailModifyScope   = gmail.GmailModifyScope   // "https://www.googleapis.com/auth/gmail.modify"
+	GmailComposeScope  = gmail.GmailComposeScope  // "https://www.googleapis.com/auth/gmail.compose"
+
+	GmailLabelsScope          = gmail.GmailLabelsScope          // "https://www.googleapis.com/auth/gmail.labels"
+	GmailSettingsBasicScope   = gmail.GmailSettingsBasicScope   // "https://www.googleapis.com/auth/gmail.settings.basic"
+	GmailSettingsSharingScope = gmail.GmailSettingsSharingScope // "https://www.googleapis.com/auth/gmail.settings.sharing"
 
 	UserIDMe = "me"
+
+	// Message formats for `users.messages.get`.
+	MessageFormatMinimal  = "minimal"
+	MessageFormatMetadata = "metadata"
+	MessageFormatFull     = "full"
+	MessageFormatRaw      = "raw"
 )
@@ -0,0 +1,143 @@
+package gmailutil
+
+import (
+	"context"
+	"iter"
+	"strings"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	"github.com/grokify/mogo/net/mailutil"
+	gmail "google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// DraftsAPI creates, lists, updates, sends and deletes drafts. Drafts are built from
+// the same `mailutil.MessageWriter` used by `GmailService.Send()`. To switch an existing
+// sender to drafts, set `GmailService.DraftOnly`.
+type DraftsAPI struct {
+	GmailService *GmailService
+}
+
+func (dapi *DraftsAPI) validate() error {
+	if dapi.GmailService == nil || dapi.GmailService.UsersService == nil {
+		return ErrGmailServiceCannotBeNil
+	}
+	return nil
+}
+
+// Create creates a draft from `msg`.
+func (dapi *DraftsAPI) Create(ctx context.Context, userID string, msg mailutil.MessageWriter, opts ...googleapi.CallOption) (*gmail.Draft, error) {
+	return dapi.CreateInThread(ctx, userID, "", msg, opts...)
+}
+
+// CreateInThread creates a draft from `msg` in the existing thread `threadID`, or in a
+// new thread if `threadID` is empty.
+func (dapi *DraftsAPI) CreateInThread(ctx context.Context, userID, threadID string, msg mailutil.MessageWriter, opts ...googleapi.CallOption) (*gmail.Draft, error) {
+	if err := dapi.validate(); err != nil {
+		return nil, err
+	}
+	gmsg, err := newRawMessage(threadID, msg)
+	if err != nil {
+		return nil, err
+	}
+	return dapi.createMessage(ctx, userID, gmsg, opts...)
+}
+
+// createMessage creates a draft from an encoded message, as returned by `newRawMessage()`.
+func (dapi *DraftsAPI) createMessage(ctx context.Context, userID string, gmsg *gmail.Message, opts ...googleapi.CallOption) (*gmail.Draft, error) {
+	if err := dapi.validate(); err != nil {
+		return nil, err
+	}
+	return dapi.GmailService.UsersService.Drafts.Create(labelUserID(userID), &gmail.Draft{Message: gmsg}).
+		Context(ctx).Do(opts...)
+}
+
+// List returns a single page of drafts. `opts.Query`, `opts.IncludeSpamTrash`,
+// `opts.MaxResults` and `opts.PageToken` are used; `opts.LabelIDs` is not supported
+// by `users.drafts.list`.
+func (dapi *DraftsAPI) List(ctx context.Context, opts MessagesListOpts) (*gmail.ListDraftsResponse, error) {
+	if err := dapi.validate(); err != nil {
+		return nil, err
+	}
+	opts.Inflate()
+	call := dapi.GmailService.UsersService.Drafts.List(opts.UserID)
+	call.IncludeSpamTrash(opts.IncludeSpamTrash)
+	if opts.MaxResults > 0 {
+		call.MaxResults(int64(opts.MaxResults))
+	}
+	if len(opts.PageToken) > 0 {
+		call.PageToken(opts.PageToken)
+	}
+	if q := opts.Query.Encode(); len(q) > 0 {
+		call.Q(q)
+	}
+	if len(opts.Fields) > 0 {
+		call.Fields(opts.Fields...)
+	}
+	resp, err := call.Context(ctx).Do(dapi.GmailService.APICallOptions...)
+	if err != nil {
+		return resp, errorsutil.Wrap(err, "func DraftsAPI.List() call to Drafts.List().Do()")
+	}
+	return resp, nil
+}
+
+// ListAll returns an iterator over all drafts matching `opts`, following `NextPageToken`
+// until the result set is exhausted, `opts.MaxTotal` drafts have been yielded, or `ctx`
+// is cancelled. Drafts are yielded as returned by `users.drafts.list` which includes
+// only the draft `Id` and the message `Id` and `ThreadId`.
+func (dapi *DraftsAPI) ListAll(ctx context.Context, opts MessagesListOpts) iter.Seq2[*gmail.Draft, error] {
+	return listAll(ctx, opts, func(opts MessagesListOpts) ([]*gmail.Draft, string, error) {
+		resp, err := dapi.List(ctx, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return resp.Drafts, resp.NextPageToken, nil
+	})
+}
+
+// Get retrieves a draft using the message format in `opts`, which can be nil. Use
+// `ParseMessage()` on `Draft.Message` to decode its headers and bodies.
+func (dapi *DraftsAPI) Get(ctx context.Context, userID, draftID string, opts *GetMessageOpts) (*gmail.Draft, error) {
+	if err := dapi.validate(); err != nil {
+		return nil, err
+	}
+	call := dapi.GmailService.UsersService.Drafts.Get(labelUserID(userID), strings.TrimSpace(draftID))
+	if opts != nil {
+		if format := strings.TrimSpace(opts.Format); format != "" {
+			call.Format(format)
+		}
+	}
+	return call.Context(ctx).Do(dapi.GmailService.APICallOptions...)
+}
+
+// Update replaces the message of draft `draftID` with `msg`, keeping `threadID`.
+func (dapi *DraftsAPI) Update(ctx context.Context, userID, draftID, threadID string, msg mailutil.MessageWriter) (*gmail.Draft, error) {
+	if err := dapi.validate(); err != nil {
+		return nil, err
+	}
+	gmsg, err := newRawMessage(threadID, msg)
+	if err != nil {
+		return nil, err
+	}
+	draftID = strings.TrimSpace(draftID)
+	return dapi.GmailService.UsersService.Drafts.Update(labelUserID(userID), draftID, &gmail.Draft{Id: draftID, Message: gmsg}).
+		Context(ctx).Do(dapi.GmailService.APICallOptions...)
+}
+
+// Send sends an existing draft and returns the sent message. The draft is deleted.
+func (dapi *DraftsAPI) Send(ctx context.Context, userID, draftID string) (*gmail.Message, error) {
+	if err := dapi.validate(); err != nil {
+		return nil, err
+	}
+	return dapi.GmailService.UsersService.Drafts.Send(labelUserID(userID), &gmail.Draft{Id: strings.TrimSpace(draftID)}).
+		Context(ctx).Do(dapi.GmailService.APICallOptions...)
+}
+
+// Delete permanently deletes a draft.
+func (dapi *DraftsAPI) Delete(ctx context.Context, userID, draftID string) error {
+	if err := dapi.validate(); err != nil {
+		return err
+	}
+	return dapi.GmailService.UsersService.Drafts.Delete(labelUserID(userID), strings.TrimSpace(draftID)).
+		Context(ctx).Do(dapi.GmailService.APICallOptions...)
+}
@@ -0,0 +1,176 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grokify/mogo/net/mailutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+// newTestDraftsService returns a service backed by an in-memory draft store which
+// records the calls made.
+func newTestDraftsService(t *testing.T, calls *[]string) (*GmailService, map[string]*gmail.Draft) {
+	t.Helper()
+	drafts := map[string]*gmail.Draft{}
+	decode := func(w http.ResponseWriter, r *http.Request) *gmail.Draft {
+		d := &gmail.Draft{}
+		if err := json.NewDecoder(r.Body).Decode(d); err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return nil
+		}
+		return d
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/drafts", func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "create")
+		if d := decode(w, r); d != nil {
+			d.Id = "d1"
+			d.Message.Id = "m1"
+			drafts[d.Id] = d
+			_ = json.NewEncoder(w).Encode(d)
+		}
+	})
+	mux.HandleFunc("PUT /gmail/v1/users/me/drafts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "update")
+		if d := decode(w, r); d != nil {
+			drafts[r.PathValue("id")] = d
+			_ = json.NewEncoder(w).Encode(d)
+		}
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/drafts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "get")
+		if d, ok := drafts[r.PathValue("id")]; ok {
+			_ = json.NewEncoder(w).Encode(d)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/drafts", func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "list:"+r.URL.Query().Get("q"))
+		resp := gmail.ListDraftsResponse{}
+		for _, d := range drafts {
+			resp.Drafts = append(resp.Drafts, &gmail.Draft{Id: d.Id})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/drafts/send", func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "send")
+		if d := decode(w, r); d != nil {
+			delete(drafts, d.Id)
+			_ = json.NewEncoder(w).Encode(&gmail.Message{Id: "m1"})
+		}
+	})
+	mux.HandleFunc("DELETE /gmail/v1/users/me/drafts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "delete")
+		delete(drafts, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/messages/send", func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "messages.send")
+		_ = json.NewEncoder(w).Encode(&gmail.Message{Id: "m2"})
+	})
+	return newTestGmailService(t, mux), drafts
+}
+
+func draftSubject(t *testing.T, d *gmail.Draft) string {
+	t.Helper()
+	raw, err := base64.URLEncoding.DecodeString(d.Message.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, err := ParseMessageRaw(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pm.Subject
+}
+
+func TestDraftsAPI(t *testing.T) {
+	var calls []string
+	gs, drafts := newTestDraftsService(t, &calls)
+	ctx := context.Background()
+
+	msg := mailutil.MessageWriter{
+		To:      mailutil.Addresses{{Address: "alice@example.com"}},
+		Subject: "Draft one"}
+	d, err := gs.DraftsAPI.CreateInThread(ctx, "", "thread-1", msg)
+	if err != nil {
+		t.Fatalf("DraftsAPI.Create() error: [%v]", err)
+	}
+	if got := draftSubject(t, drafts[d.Id]); got != "Draft one" || drafts[d.Id].Message.ThreadId != "thread-1" {
+		t.Errorf("DraftsAPI.Create() mismatch: got subject [%s] thread [%s]", got, drafts[d.Id].Message.ThreadId)
+	}
+
+	msg.Subject = "Draft two"
+	if _, err := gs.DraftsAPI.Update(ctx, "", d.Id, "thread-1", msg); err != nil {
+		t.Fatalf("DraftsAPI.Update() error: [%v]", err)
+	}
+	got, err := gs.DraftsAPI.Get(ctx, "", d.Id, &GetMessageOpts{Format: MessageFormatRaw})
+	if err != nil {
+		t.Fatalf("DraftsAPI.Get() error: [%v]", err)
+	}
+	if subject := draftSubject(t, got); subject != "Draft two" {
+		t.Errorf("DraftsAPI.Update() subject mismatch: want [Draft two] got [%s]", subject)
+	}
+
+	count := 0
+	for _, err := range gs.DraftsAPI.ListAll(ctx, MessagesListOpts{Query: MessagesListQueryOpts{To: "alice@example.com"}}) {
+		if err != nil {
+			t.Fatalf("DraftsAPI.ListAll() error: [%v]", err)
+		}
+		count++
+	}
+	if count != 1 {
+		t.Errorf("DraftsAPI.ListAll() count mismatch: want [1] got [%d]", count)
+	}
+
+	if sent, err := gs.DraftsAPI.Send(ctx, "", d.Id); err != nil || sent.Id != "m1" {
+		t.Fatalf("DraftsAPI.Send() error: [%v]", err)
+	}
+	if err := gs.DraftsAPI.Delete(ctx, "", "d2"); err != nil {
+		t.Fatalf("DraftsAPI.Delete() error: [%v]", err)
+	}
+
+	want := "create,update,get,list:to:alice@example.com,send,delete"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("DraftsAPI calls mismatch: want [%s] got [%s]", want, got)
+	}
+}
+
+func TestDraftOnly(t *testing.T) {
+	var calls []string
+	gs, drafts := newTestDraftsService(t, &calls)
+	gs.DraftOnly = true
+
+	msg, err := gs.SendSimple(context.Background(), UserIDMe, SendSimpleOpts{
+		To:       "alice@example.com",
+		Subject:  "Review me",
+		BodyText: "Hello"})
+	if err != nil {
+		t.Fatalf("SendSimple() with DraftOnly error: [%v]", err)
+	}
+	if msg.Id != "m1" || len(drafts) != 1 {
+		t.Errorf("SendSimple() with DraftOnly mismatch: got message [%s] drafts [%d]", msg.Id, len(drafts))
+	}
+	if got := strings.Join(calls, ","); got != "create" {
+		t.Errorf("SendSimple() with DraftOnly calls mismatch: want [create] got [%s]", got)
+	}
+
+	rawMsg := newTestOutboxMessage("Review raw")
+	raw, err := rawMsg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gs.SendRaw(context.Background(), "", "", raw); err != nil {
+		t.Fatalf("SendRaw() with DraftOnly error: [%v]", err)
+	}
+	if got := strings.Join(calls, ","); got != "create,create" {
+		t.Errorf("SendRaw() with DraftOnly calls mismatch: want [create,create] got [%s]", got)
+	}
+}
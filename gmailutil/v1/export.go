@@ -0,0 +1,375 @@
+package gmailutil
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+const (
+	ExportFormatMbox = "mbox" // mboxrd file, `ExportMboxFilename`
+	ExportFormatEML  = "eml"  // one `<id>.eml` file per message, optionally in `ExportZipFilename`
+
+	ExportIndexFilename = "index.json"
+	ExportMboxFilename  = "messages.mbox"
+	ExportZipFilename   = "messages.zip"
+
+	// ExportChunkSize is the number of messages retrieved and written between index saves.
+	ExportChunkSize = 100
+)
+
+// ExportOpts configures `MessagesAPI.Export()`.
+type ExportOpts struct {
+	UserID           string
+	Query            MessagesListQueryOpts
+	LabelIDs         []string
+	IncludeSpamTrash bool
+	MaxTotal         int    // maximum number of matching messages, 0 for no limit
+	Dir              string // output directory for the index and messages, created if needed
+	Format           string // `ExportFormatMbox` (default) or `ExportFormatEML`
+	Zip              bool   // with `ExportFormatEML`, write the `.eml` files to `ExportZipFilename`
+	Concurrency      int    // workers retrieving messages, defaults to `DefaultInflateConcurrency`
+	RequestsPerSec   float64
+	Progress         func(ExportProgress) // optional callback invoked after each chunk
+}
+
+func (opts *ExportOpts) inflate() error {
+	if opts.UserID = strings.TrimSpace(opts.UserID); opts.UserID == "" {
+		opts.UserID = UserIDMe
+	}
+	if opts.Dir = strings.TrimSpace(opts.Dir); opts.Dir == "" {
+		return errors.New("export dir cannot be empty")
+	}
+	switch opts.Format = strings.ToLower(strings.TrimSpace(opts.Format)); opts.Format {
+	case "":
+		opts.Format = ExportFormatMbox
+	case ExportFormatMbox, ExportFormatEML:
+	default:
+		return fmt.Errorf("export format not supported (%s)", opts.Format)
+	}
+	if opts.Zip && opts.Format != ExportFormatEML {
+		return errors.New("export zip requires eml format")
+	}
+	return nil
+}
+
+// ExportProgress reports export progress.
+type ExportProgress struct {
+	Matched  int // messages matching the query
+	Skipped  int // messages already in the index
+	Exported int // messages written in this run
+	Failed   int // messages which could not be retrieved in this run
+}
+
+// ExportIndex lists the exported messages. It is stored as `ExportIndexFilename` in the
+// export directory and used to skip messages on later runs.
+type ExportIndex struct {
+	Format    string             `json:"format"`
+	Query     string             `json:"query,omitempty"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+	Messages  []ExportIndexEntry `json:"messages"`
+}
+
+// ExportIndexEntry describes one exported message. For mbox exports, `Offset` and `Size`
+// locate the message, including its `From ` line, in the mbox file. For EML exports,
+// `File` is the name of the `.eml` file in the directory or zip file.
+type ExportIndexEntry struct {
+	ID       string    `json:"id"`
+	ThreadID string    `json:"threadId"`
+	Date     time.Time `json:"date"`
+	From     string    `json:"from,omitempty"`
+	Subject  string    `json:"subject"`
+	LabelIDs []string  `json:"labelIds,omitempty"`
+	File     string    `json:"file"`
+	Offset   int64     `json:"offset,omitempty"`
+	Size     int64     `json:"size"`
+}
+
+// ExportResult summarizes an export.
+type ExportResult struct {
+	ExportProgress
+	IndexPath string
+	Errors    map[string]error // retrieval errors by message ID; these are retried on the next run
+}
+
+// ReadExportIndex reads an export index, returning an empty index if the file does not exist.
+func ReadExportIndex(path string) (*ExportIndex, error) {
+	idx := &ExportIndex{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return idx, json.Unmarshal(data, idx)
+}
+
+// Export writes the messages matching `opts` in `raw` format to `opts.Dir` as an mboxrd
+// file or as `.eml` files, with an `index.json` of message IDs, dates and subjects.
+// Messages are exported oldest first. Exports are resumable: messages already in the
+// index are skipped, so re-running an export, including after an interruption, only
+// adds new messages.
+func (mapi *MessagesAPI) Export(ctx context.Context, opts ExportOpts) (*ExportResult, error) {
+	if mapi.GmailService == nil {
+		return nil, ErrGmailServiceCannotBeNil
+	}
+	if err := opts.inflate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	res := &ExportResult{
+		IndexPath: filepath.Join(opts.Dir, ExportIndexFilename),
+		Errors:    map[string]error{}}
+	idx, err := ReadExportIndex(res.IndexPath)
+	if err != nil {
+		return nil, errorsutil.Wrapf(err, "read export index (%s)", res.IndexPath)
+	}
+	if idx.Format != "" && idx.Format != exportIndexFormat(opts) {
+		return nil, fmt.Errorf("export dir (%s) has format (%s), not (%s)", opts.Dir, idx.Format, exportIndexFormat(opts))
+	}
+	idx.Format = exportIndexFormat(opts)
+	idx.Query = opts.Query.Encode()
+	done := map[string]bool{}
+	for _, e := range idx.Messages {
+		done[e.ID] = true
+	}
+
+	var todo []*gmail.Message
+	for msg, err := range mapi.ListAll(ctx, MessagesListOpts{
+		UserID:           opts.UserID,
+		Query:            opts.Query,
+		LabelIDs:         opts.LabelIDs,
+		IncludeSpamTrash: opts.IncludeSpamTrash,
+		MaxTotal:         opts.MaxTotal}) {
+		if err != nil {
+			return res, err
+		}
+		res.Matched++
+		if done[msg.Id] {
+			res.Skipped++
+		} else {
+			done[msg.Id] = true
+			todo = append(todo, msg)
+		}
+	}
+	slices.Reverse(todo)
+	if opts.Progress != nil {
+		opts.Progress(res.ExportProgress)
+	}
+	if len(todo) == 0 {
+		return res, nil
+	}
+
+	out, err := newExportOutput(opts, idx)
+	if err != nil {
+		return res, err
+	}
+	for chunk := range slices.Chunk(todo, ExportChunkSize) {
+		if err := ctx.Err(); err != nil {
+			return res, errors.Join(err, out.close(idx, res.IndexPath))
+		}
+		ir := mapi.InflateMessagesWithOpts(ctx, chunk, InflateOpts{
+			UserID:         opts.UserID,
+			Format:         MessageFormatRaw,
+			Concurrency:    opts.Concurrency,
+			RequestsPerSec: opts.RequestsPerSec})
+		for id, err := range ir.Errors {
+			res.Errors[id] = err
+		}
+		for _, msg := range ir.Messages {
+			if msg == nil {
+				continue
+			}
+			entry, err := out.write(msg)
+			if err != nil {
+				return res, errors.Join(errorsutil.Wrapf(err, "message id (%s)", msg.Id), out.close(idx, res.IndexPath))
+			}
+			idx.Messages = append(idx.Messages, entry)
+			res.Exported++
+		}
+		res.Failed = len(res.Errors)
+		if err := out.checkpoint(idx, res.IndexPath); err != nil {
+			return res, errors.Join(err, out.close(idx, res.IndexPath))
+		}
+		if opts.Progress != nil {
+			opts.Progress(res.ExportProgress)
+		}
+	}
+	return res, out.close(idx, res.IndexPath)
+}
+
+func exportIndexFormat(opts ExportOpts) string {
+	if opts.Zip {
+		return ExportFormatEML + "+zip"
+	}
+	return opts.Format
+}
+
+func writeExportIndex(idx *ExportIndex, path string) error {
+	idx.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// exportOutput writes messages for one export format.
+type exportOutput struct {
+	opts   ExportOpts
+	mbox   *os.File
+	mw     *MboxWriter
+	offset int64
+	zipTmp *os.File
+	zw     *zip.Writer
+}
+
+func newExportOutput(opts ExportOpts, idx *ExportIndex) (*exportOutput, error) {
+	out := &exportOutput{opts: opts}
+	switch {
+	case opts.Format == ExportFormatMbox:
+		f, err := os.OpenFile(filepath.Join(opts.Dir, ExportMboxFilename), os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		// discard messages written after the last index save
+		for _, e := range idx.Messages {
+			out.offset = max(out.offset, e.Offset+e.Size)
+		}
+		if err := f.Truncate(out.offset); err != nil {
+			return nil, errors.Join(err, f.Close())
+		} else if _, err := f.Seek(out.offset, io.SeekStart); err != nil {
+			return nil, errors.Join(err, f.Close())
+		}
+		out.mbox, out.mw = f, NewMboxWriter(f)
+	case opts.Zip:
+		// zip files cannot be appended to, so existing entries are copied to a new file
+		// which replaces the old one when the export completes
+		zipPath := filepath.Join(opts.Dir, ExportZipFilename)
+		tmp, err := os.CreateTemp(opts.Dir, "."+ExportZipFilename+".*")
+		if err != nil {
+			return nil, err
+		}
+		out.zipTmp, out.zw = tmp, zip.NewWriter(tmp)
+		indexed := map[string]bool{}
+		for _, e := range idx.Messages {
+			indexed[e.File] = true
+		}
+		if zr, err := zip.OpenReader(zipPath); err == nil {
+			for _, f := range zr.File {
+				if !indexed[f.Name] {
+					continue
+				} else if err := out.zw.Copy(f); err != nil {
+					return nil, errors.Join(err, zr.Close(), out.abortZip())
+				}
+			}
+			if err := zr.Close(); err != nil {
+				return nil, errors.Join(err, out.abortZip())
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, errors.Join(err, out.abortZip())
+		}
+	}
+	return out, nil
+}
+
+func (out *exportOutput) write(msg *gmail.Message) (ExportIndexEntry, error) {
+	raw, err := decodeBase64URL(msg.Raw)
+	if err != nil {
+		return ExportIndexEntry{}, err
+	}
+	entry := ExportIndexEntry{
+		ID:       msg.Id,
+		ThreadID: msg.ThreadId,
+		LabelIDs: msg.LabelIds}
+	if msg.InternalDate > 0 {
+		entry.Date = time.UnixMilli(msg.InternalDate).UTC()
+	}
+	from := ""
+	if pm, err := ParseMessageRaw(raw); err == nil {
+		entry.Subject = pm.Subject
+		if pm.From != nil {
+			from = pm.From.Address
+			entry.From = pm.From.String()
+		}
+		if entry.Date.IsZero() {
+			entry.Date = pm.Date.UTC()
+		}
+	}
+
+	switch {
+	case out.mw != nil:
+		entry.File = ExportMboxFilename
+		entry.Offset = out.offset
+		if entry.Size, err = out.mw.WriteMessage(from, entry.Date, raw); err != nil {
+			return entry, err
+		}
+		out.offset += entry.Size
+	case out.zw != nil:
+		entry.File = msg.Id + ".eml"
+		entry.Size = int64(len(raw))
+		w, err := out.zw.CreateHeader(&zip.FileHeader{Name: entry.File, Method: zip.Deflate, Modified: entry.Date})
+		if err != nil {
+			return entry, err
+		} else if _, err := w.Write(raw); err != nil {
+			return entry, err
+		}
+	default:
+		entry.File = msg.Id + ".eml"
+		entry.Size = int64(len(raw))
+		if err := writeFileAtomic(filepath.Join(out.opts.Dir, entry.File), raw); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// checkpoint saves the index after a chunk. Zip exports are only indexed on close, when
+// the new zip file is complete.
+func (out *exportOutput) checkpoint(idx *ExportIndex, indexPath string) error {
+	if out.zw != nil {
+		return nil
+	}
+	if out.mbox != nil {
+		if err := out.mbox.Sync(); err != nil {
+			return err
+		}
+	}
+	return writeExportIndex(idx, indexPath)
+}
+
+func (out *exportOutput) close(idx *ExportIndex, indexPath string) error {
+	switch {
+	case out.mbox != nil:
+		err := out.checkpoint(idx, indexPath)
+		return errors.Join(err, out.mbox.Close())
+	case out.zw != nil:
+		if err := out.zw.Close(); err != nil {
+			return errors.Join(err, out.abortZip())
+		} else if err := out.zipTmp.Close(); err != nil {
+			return errors.Join(err, os.Remove(out.zipTmp.Name()))
+		} else if err := os.Rename(out.zipTmp.Name(), filepath.Join(out.opts.Dir, ExportZipFilename)); err != nil {
+			return errors.Join(err, os.Remove(out.zipTmp.Name()))
+		}
+		return writeExportIndex(idx, indexPath)
+	default:
+		return writeExportIndex(idx, indexPath)
+	}
+}
+
+func (out *exportOutput) abortZip() error {
+	return errors.Join(out.zipTmp.Close(), os.Remove(out.zipTmp.Name()))
+}
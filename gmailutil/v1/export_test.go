@@ -0,0 +1,159 @@
+package gmailutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+func testExportRaw(id string) string {
+	return fmt.Sprintf("From: Alice <alice@example.com>\r\nTo: bob@example.com\r\nSubject: Message %s\r\n"+
+		"Date: Mon, 2 Feb 2026 10:00:00 +0000\r\n\r\nHello %s\r\nFrom here on\r\n>From quoted\r\n", id, id)
+}
+
+// newTestExportService serves the messages in `ids`, newest first, in raw format.
+func newTestExportService(t *testing.T, ids *[]string) *GmailService {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/me/messages", func(w http.ResponseWriter, r *http.Request) {
+		resp := gmail.ListMessagesResponse{}
+		for _, id := range *ids {
+			resp.Messages = append(resp.Messages, &gmail.Message{Id: id, ThreadId: "t" + id})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/messages/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != MessageFormatRaw {
+			t.Errorf("export get format mismatch: got (%s)", r.URL.Query().Get("format"))
+		}
+		id := r.PathValue("id")
+		_ = json.NewEncoder(w).Encode(&gmail.Message{
+			Id:           id,
+			ThreadId:     "t" + id,
+			LabelIds:     []string{"INBOX"},
+			InternalDate: time.Date(2026, 2, 2, 10, 0, 0, 0, time.UTC).UnixMilli(),
+			Raw:          base64.URLEncoding.EncodeToString([]byte(testExportRaw(id)))})
+	})
+	return newTestGmailService(t, mux)
+}
+
+func TestExportMbox(t *testing.T) {
+	ids := []string{"m2", "m1"}
+	gs := newTestExportService(t, &ids)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	res, err := gs.MessagesAPI.Export(ctx, ExportOpts{Dir: dir})
+	if err != nil {
+		t.Fatalf("Export() error: [%v]", err)
+	}
+	if res.Matched != 2 || res.Exported != 2 || res.Skipped != 0 {
+		t.Errorf("Export() result mismatch: got [%+v]", res.ExportProgress)
+	}
+
+	// resume: only the new message is exported
+	ids = []string{"m3", "m2", "m1"}
+	if res, err = gs.MessagesAPI.Export(ctx, ExportOpts{Dir: dir}); err != nil {
+		t.Fatalf("Export() resume error: [%v]", err)
+	}
+	if res.Matched != 3 || res.Exported != 1 || res.Skipped != 2 {
+		t.Errorf("Export() resume result mismatch: got [%+v]", res.ExportProgress)
+	}
+
+	idx, err := ReadExportIndex(filepath.Join(dir, ExportIndexFilename))
+	if err != nil {
+		t.Fatalf("ReadExportIndex() error: [%v]", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ExportMboxFilename))
+	if err != nil {
+		t.Fatalf("read mbox error: [%v]", err)
+	}
+	msgs, err := ReadMboxMessages(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMboxMessages() error: [%v]", err)
+	}
+	if len(idx.Messages) != 3 || len(msgs) != 3 {
+		t.Fatalf("Export() count mismatch: index (%d) mbox (%d)", len(idx.Messages), len(msgs))
+	}
+	for i, want := range []string{"m1", "m2", "m3"} {
+		e := idx.Messages[i]
+		if e.ID != want || e.Subject != "Message "+want || e.From != `"Alice" <alice@example.com>` {
+			t.Errorf("index entry (%d) mismatch: got [%+v]", i, e)
+		}
+		if got := string(data[e.Offset : e.Offset+e.Size]); !strings.HasPrefix(got, "From alice@example.com Mon Feb  2 10:00:00 2026\n") {
+			t.Errorf("index entry (%d) offset mismatch: got (%q)", i, got)
+		}
+		if wantRaw := strings.ReplaceAll(testExportRaw(want), "\r\n", "\n"); string(msgs[i]) != wantRaw {
+			t.Errorf("mbox message (%d) mismatch: want (%q), got (%q)", i, wantRaw, msgs[i])
+		}
+	}
+
+	if _, err := gs.MessagesAPI.Export(ctx, ExportOpts{Dir: dir, Format: ExportFormatEML}); err == nil {
+		t.Error("Export() with a different format want error, got nil")
+	}
+}
+
+func TestExportEMLZip(t *testing.T) {
+	ids := []string{"m1"}
+	gs := newTestExportService(t, &ids)
+	dir := t.TempDir()
+	ctx := context.Background()
+	opts := ExportOpts{Dir: dir, Format: ExportFormatEML, Zip: true}
+
+	if _, err := gs.MessagesAPI.Export(ctx, opts); err != nil {
+		t.Fatalf("Export() error: [%v]", err)
+	}
+	ids = []string{"m2", "m1"}
+	if _, err := gs.MessagesAPI.Export(ctx, opts); err != nil {
+		t.Fatalf("Export() resume error: [%v]", err)
+	}
+
+	zr, err := zip.OpenReader(filepath.Join(dir, ExportZipFilename))
+	if err != nil {
+		t.Fatalf("zip.OpenReader() error: [%v]", err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, ","); got != "m1.eml,m2.eml" {
+		t.Errorf("zip entries mismatch: want (m1.eml,m2.eml), got (%s)", got)
+	}
+}
+
+func TestExportEML(t *testing.T) {
+	ids := []string{"m1"}
+	gs := newTestExportService(t, &ids)
+	dir := t.TempDir()
+	if _, err := gs.MessagesAPI.Export(context.Background(), ExportOpts{Dir: dir, Format: ExportFormatEML}); err != nil {
+		t.Fatalf("Export() error: [%v]", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "m1.eml")); err != nil || string(data) != testExportRaw("m1") {
+		t.Errorf("Export() eml mismatch: got (%q) error [%v]", data, err)
+	}
+}
+
+func TestMboxWriter(t *testing.T) {
+	var buf bytes.Buffer
+	mw := NewMboxWriter(&buf)
+	date := time.Date(2026, 2, 2, 10, 0, 0, 0, time.UTC)
+	if _, err := mw.WriteMessage("", date, []byte("Subject: a\r\n\r\nFrom me\r\n>From you\r\nFromage")); err != nil {
+		t.Fatalf("MboxWriter.WriteMessage() error: [%v]", err)
+	}
+	want := "From MAILER-DAEMON Mon Feb  2 10:00:00 2026\nSubject: a\n\n>From me\n>>From you\nFromage\n\n"
+	if buf.String() != want {
+		t.Errorf("MboxWriter.WriteMessage() mismatch: want (%q), got (%q)", want, buf.String())
+	}
+}
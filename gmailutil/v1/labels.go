@@ -4,26 +4,351 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 
+	"github.com/grokify/mogo/errors/errorsutil"
 	gmail "google.golang.org/api/gmail/v1"
-	"google.golang.org/api/option"
 )
 
+const (
+	// LabelPathSep separates nested label names, e.g. `Parent/Child`.
+	LabelPathSep = "/"
+	// BatchModifyMaxIDs is the maximum number of message IDs accepted by a single
+	// `users.messages.batchModify` call.
+	BatchModifyMaxIDs = 1000
+
+	LabelTypeSystem = "system"
+	LabelTypeUser   = "user"
+)
+
+// GetLabelNames returns the label names for the authenticated user.
 func GetLabelNames(client *http.Client) ([]string, error) {
 	// https://developers.google.com/gmail/api/quickstart/go
 	labels := []string{}
-	srv, err := gmail.NewService(context.Background(), option.WithHTTPClient(client))
+	gs, err := NewGmailService(context.Background(), client)
 	if err != nil {
 		return labels, fmt.Errorf("unable to retrieve gmail client: err [%v]", err)
 	}
-
-	user := "me"
-	r, err := srv.Users.Labels.List(user).Do()
+	r, err := gs.LabelsAPI.List(context.Background(), UserIDMe, false)
 	if err != nil {
 		return labels, fmt.Errorf("unable to retrieve labels: err [%v]", err)
 	}
-	for _, l := range r.Labels {
+	for _, l := range r {
 		labels = append(labels, l.Name)
 	}
 	return labels, nil
 }
+
+// LabelsAPI provides label management and cached label name to ID resolution.
+type LabelsAPI struct {
+	GmailService *GmailService
+	cache        *labelCache
+}
+
+func newLabelsAPI(gs *GmailService) LabelsAPI {
+	return LabelsAPI{GmailService: gs, cache: &labelCache{users: map[string]*labelIndex{}}}
+}
+
+type labelCache struct {
+	mu    sync.Mutex
+	users map[string]*labelIndex
+}
+
+type labelIndex struct {
+	byID   map[string]*gmail.Label
+	byName map[string]*gmail.Label
+}
+
+func newLabelIndex(labels []*gmail.Label) *labelIndex {
+	idx := &labelIndex{byID: map[string]*gmail.Label{}, byName: map[string]*gmail.Label{}}
+	for _, l := range labels {
+		if l != nil {
+			idx.byID[l.Id] = l
+			idx.byName[strings.ToLower(l.Name)] = l
+		}
+	}
+	return idx
+}
+
+func (lapi *LabelsAPI) validate() error {
+	if lapi.GmailService == nil || lapi.GmailService.UsersService == nil {
+		return ErrGmailServiceCannotBeNil
+	}
+	return nil
+}
+
+func labelUserID(userID string) string {
+	if userID = strings.TrimSpace(userID); userID == "" {
+		return UserIDMe
+	}
+	return userID
+}
+
+// List returns all labels. `users.labels.list` does not return message and thread
+// counts; set `withCounts` to retrieve each label individually to include them.
+func (lapi *LabelsAPI) List(ctx context.Context, userID string, withCounts bool) ([]*gmail.Label, error) {
+	if err := lapi.validate(); err != nil {
+		return nil, err
+	}
+	userID = labelUserID(userID)
+	resp, err := lapi.GmailService.UsersService.Labels.List(userID).
+		Context(ctx).Do(lapi.GmailService.APICallOptions...)
+	if err != nil {
+		return nil, errorsutil.Wrap(err, "func LabelsAPI.List() call to Labels.List().Do()")
+	}
+	labels := resp.Labels
+	if withCounts {
+		for i, l := range labels {
+			if full, err := lapi.Get(ctx, userID, l.Id); err != nil {
+				return nil, err
+			} else {
+				labels[i] = full
+			}
+		}
+	}
+	slices.SortFunc(labels, func(a, b *gmail.Label) int { return strings.Compare(a.Name, b.Name) })
+	lapi.setCache(userID, labels)
+	return labels, nil
+}
+
+// Get returns a label, including message and thread counts.
+func (lapi *LabelsAPI) Get(ctx context.Context, userID, labelID string) (*gmail.Label, error) {
+	if err := lapi.validate(); err != nil {
+		return nil, err
+	}
+	return lapi.GmailService.UsersService.Labels.Get(labelUserID(userID), strings.TrimSpace(labelID)).
+		Context(ctx).Do(lapi.GmailService.APICallOptions...)
+}
+
+// Create creates a label. Parent labels in a nested name such as `Parent/Child` are
+// not created; use `CreatePath()` for that.
+func (lapi *LabelsAPI) Create(ctx context.Context, userID string, label *gmail.Label) (*gmail.Label, error) {
+	if err := lapi.validate(); err != nil {
+		return nil, err
+	}
+	userID = labelUserID(userID)
+	created, err := lapi.GmailService.UsersService.Labels.Create(userID, label).
+		Context(ctx).Do(lapi.GmailService.APICallOptions...)
+	lapi.InvalidateCache(userID)
+	return created, err
+}
+
+// CreatePath creates a nested label such as `Parent/Child`, creating any missing parent
+// labels. If the full path already exists, the existing label is returned.
+func (lapi *LabelsAPI) CreatePath(ctx context.Context, userID, path string) (*gmail.Label, error) {
+	parts := LabelPathParts(path)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("label path cannot be empty")
+	}
+	var label *gmail.Label
+	for i := range parts {
+		name := strings.Join(parts[:i+1], LabelPathSep)
+		if existing, err := lapi.GetByName(ctx, userID, name); err != nil {
+			return nil, err
+		} else if existing != nil {
+			label = existing
+			continue
+		}
+		created, err := lapi.Create(ctx, userID, &gmail.Label{
+			Name:                  name,
+			LabelListVisibility:   "labelShow",
+			MessageListVisibility: "show"})
+		if err != nil {
+			return nil, err
+		}
+		label = created
+	}
+	return label, nil
+}
+
+// Update replaces a label identified by `label.Id`.
+func (lapi *LabelsAPI) Update(ctx context.Context, userID string, label *gmail.Label) (*gmail.Label, error) {
+	if err := lapi.validate(); err != nil {
+		return nil, err
+	} else if label == nil || strings.TrimSpace(label.Id) == "" {
+		return nil, fmt.Errorf("label id cannot be empty")
+	}
+	userID = labelUserID(userID)
+	updated, err := lapi.GmailService.UsersService.Labels.Update(userID, label.Id, label).
+		Context(ctx).Do(lapi.GmailService.APICallOptions...)
+	lapi.InvalidateCache(userID)
+	return updated, err
+}
+
+// Delete deletes a label. Messages are not deleted.
+func (lapi *LabelsAPI) Delete(ctx context.Context, userID, labelID string) error {
+	if err := lapi.validate(); err != nil {
+		return err
+	}
+	userID = labelUserID(userID)
+	err := lapi.GmailService.UsersService.Labels.Delete(userID, strings.TrimSpace(labelID)).
+		Context(ctx).Do(lapi.GmailService.APICallOptions...)
+	lapi.InvalidateCache(userID)
+	return err
+}
+
+// GetByName returns the label with `name`, matched case-insensitively, or nil if it
+// does not exist. Labels are cached per user until a create, update or delete.
+func (lapi *LabelsAPI) GetByName(ctx context.Context, userID, name string) (*gmail.Label, error) {
+	idx, err := lapi.index(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return idx.byName[strings.ToLower(NormalizeLabelPath(name))], nil
+}
+
+// LabelID resolves a label name, such as `Work/Projects` or `INBOX`, to its ID.
+func (lapi *LabelsAPI) LabelID(ctx context.Context, userID, name string) (string, error) {
+	if label, err := lapi.GetByName(ctx, userID, name); err != nil {
+		return "", err
+	} else if label == nil {
+		return "", fmt.Errorf("label not found (%s)", name)
+	} else {
+		return label.Id, nil
+	}
+}
+
+// LabelIDs resolves label names to IDs.
+func (lapi *LabelsAPI) LabelIDs(ctx context.Context, userID string, names []string) ([]string, error) {
+	var ids []string
+	for _, name := range names {
+		if id, err := lapi.LabelID(ctx, userID, name); err != nil {
+			return ids, err
+		} else {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// LabelNames resolves label IDs, such as `MessagesListOpts.LabelIDs`, to names. Unknown
+// IDs are returned unchanged.
+func (lapi *LabelsAPI) LabelNames(ctx context.Context, userID string, labelIDs []string) ([]string, error) {
+	idx, err := lapi.index(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, id := range labelIDs {
+		if l, ok := idx.byID[id]; ok {
+			names = append(names, l.Name)
+		} else {
+			names = append(names, id)
+		}
+	}
+	return names, nil
+}
+
+// InvalidateCache clears cached labels for `userID`, or for all users if `userID` is empty.
+func (lapi *LabelsAPI) InvalidateCache(userID string) {
+	if lapi.cache == nil {
+		return
+	}
+	lapi.cache.mu.Lock()
+	defer lapi.cache.mu.Unlock()
+	if userID = strings.TrimSpace(userID); userID == "" {
+		lapi.cache.users = map[string]*labelIndex{}
+	} else {
+		delete(lapi.cache.users, userID)
+	}
+}
+
+func (lapi *LabelsAPI) setCache(userID string, labels []*gmail.Label) {
+	if lapi.cache == nil {
+		return
+	}
+	lapi.cache.mu.Lock()
+	defer lapi.cache.mu.Unlock()
+	lapi.cache.users[userID] = newLabelIndex(labels)
+}
+
+func (lapi *LabelsAPI) index(ctx context.Context, userID string) (*labelIndex, error) {
+	userID = labelUserID(userID)
+	if lapi.cache != nil {
+		lapi.cache.mu.Lock()
+		idx, ok := lapi.cache.users[userID]
+		lapi.cache.mu.Unlock()
+		if ok {
+			return idx, nil
+		}
+	}
+	labels, err := lapi.List(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	return newLabelIndex(labels), nil
+}
+
+// ModifyMessages adds and removes label IDs on messages using `users.messages.batchModify`,
+// splitting `messageIDs` into calls of at most `BatchModifyMaxIDs` each.
+func (lapi *LabelsAPI) ModifyMessages(ctx context.Context, userID string, messageIDs, addLabelIDs, removeLabelIDs []string) error {
+	if err := lapi.validate(); err != nil {
+		return err
+	}
+	userID = labelUserID(userID)
+	for ids := range slices.Chunk(messageIDs, BatchModifyMaxIDs) {
+		if err := lapi.GmailService.UsersService.Messages.BatchModify(userID,
+			&gmail.BatchModifyMessagesRequest{
+				Ids:            ids,
+				AddLabelIds:    addLabelIDs,
+				RemoveLabelIds: removeLabelIDs}).
+			Context(ctx).Do(lapi.GmailService.APICallOptions...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ModifyQuery adds and removes labels, by name, on all messages matching `opts`. It
+// returns the number of messages modified.
+func (lapi *LabelsAPI) ModifyQuery(ctx context.Context, opts MessagesListOpts, addLabelNames, removeLabelNames []string) (int, error) {
+	if err := lapi.validate(); err != nil {
+		return 0, err
+	}
+	opts.Inflate()
+	addIDs, err := lapi.LabelIDs(ctx, opts.UserID, addLabelNames)
+	if err != nil {
+		return 0, err
+	}
+	removeIDs, err := lapi.LabelIDs(ctx, opts.UserID, removeLabelNames)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := lapi.GmailService.MessagesAPI.GetMessageIDsAll(ctx, opts)
+	if err != nil {
+		return 0, err
+	} else if len(ids) == 0 {
+		return 0, nil
+	}
+	return len(ids), lapi.ModifyMessages(ctx, opts.UserID, ids, addIDs, removeIDs)
+}
+
+// LabelPathParts splits a nested label name such as `Parent/Child` into its parts,
+// trimming whitespace and dropping empty parts.
+func LabelPathParts(path string) []string {
+	var parts []string
+	for _, p := range strings.Split(path, LabelPathSep) {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// NormalizeLabelPath returns a nested label name with whitespace and empty parts removed,
+// e.g. ` Parent / Child/` becomes `Parent/Child`.
+func NormalizeLabelPath(path string) string {
+	return strings.Join(LabelPathParts(path), LabelPathSep)
+}
+
+// LabelParentPath returns the parent of a nested label name, or an empty string for a
+// top-level label.
+func LabelParentPath(path string) string {
+	parts := LabelPathParts(path)
+	if len(parts) <= 1 {
+		return ""
+	}
+	return strings.Join(parts[:len(parts)-1], LabelPathSep)
+}
@@ -0,0 +1,95 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+var labelPathTests = []struct {
+	path       string
+	normalized string
+	parent     string
+}{
+	{"Work", "Work", ""},
+	{"Work/Projects", "Work/Projects", "Work"},
+	{" Work / Projects /Alpha/", "Work/Projects/Alpha", "Work/Projects"},
+	{"", "", ""},
+}
+
+func TestLabelPath(t *testing.T) {
+	for _, tt := range labelPathTests {
+		if got := NormalizeLabelPath(tt.path); got != tt.normalized {
+			t.Errorf("NormalizeLabelPath(%q) mismatch: want [%s] got [%s]", tt.path, tt.normalized, got)
+		}
+		if got := LabelParentPath(tt.path); got != tt.parent {
+			t.Errorf("LabelParentPath(%q) mismatch: want [%s] got [%s]", tt.path, tt.parent, got)
+		}
+	}
+}
+
+func TestLabelsAPIResolveAndCreatePath(t *testing.T) {
+	labels := []*gmail.Label{
+		{Id: "INBOX", Name: "INBOX", Type: LabelTypeSystem},
+		{Id: "Label_1", Name: "Work", Type: LabelTypeUser},
+	}
+	var listCalls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/me/labels", func(w http.ResponseWriter, r *http.Request) {
+		listCalls.Add(1)
+		if err := json.NewEncoder(w).Encode(gmail.ListLabelsResponse{Labels: labels}); err != nil {
+			t.Error(err)
+		}
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/labels", func(w http.ResponseWriter, r *http.Request) {
+		label := &gmail.Label{}
+		if err := json.NewDecoder(r.Body).Decode(label); err != nil {
+			t.Error(err)
+			return
+		}
+		label.Id = "Label_" + strings.ReplaceAll(label.Name, "/", "_")
+		labels = append(labels, label)
+		if err := json.NewEncoder(w).Encode(label); err != nil {
+			t.Error(err)
+		}
+	})
+	gs := newTestGmailService(t, mux)
+	ctx := context.Background()
+
+	ids, err := gs.LabelsAPI.LabelIDs(ctx, UserIDMe, []string{"inbox", "work"})
+	if err != nil {
+		t.Fatalf("LabelsAPI.LabelIDs() error: [%v]", err)
+	}
+	if strings.Join(ids, ",") != "INBOX,Label_1" {
+		t.Errorf("LabelsAPI.LabelIDs() mismatch: want [INBOX,Label_1] got [%v]", ids)
+	}
+	names, err := gs.LabelsAPI.LabelNames(ctx, UserIDMe, []string{"Label_1", "UNKNOWN"})
+	if err != nil {
+		t.Fatalf("LabelsAPI.LabelNames() error: [%v]", err)
+	}
+	if strings.Join(names, ",") != "Work,UNKNOWN" {
+		t.Errorf("LabelsAPI.LabelNames() mismatch: want [Work,UNKNOWN] got [%v]", names)
+	}
+	if listCalls.Load() != 1 {
+		t.Errorf("LabelsAPI cache: want [1] list call got [%d]", listCalls.Load())
+	}
+
+	label, err := gs.LabelsAPI.CreatePath(ctx, UserIDMe, "Work/Projects/Alpha")
+	if err != nil {
+		t.Fatalf("LabelsAPI.CreatePath() error: [%v]", err)
+	}
+	if label.Name != "Work/Projects/Alpha" || len(labels) != 4 {
+		t.Errorf("LabelsAPI.CreatePath() mismatch: got name [%s] label count [%d]", label.Name, len(labels))
+	}
+	if id, err := gs.LabelsAPI.LabelID(ctx, UserIDMe, "Work/Projects"); err != nil || id != "Label_Work_Projects" {
+		t.Errorf("LabelsAPI.LabelID() after CreatePath() mismatch: got [%s] err [%v]", id, err)
+	}
+	if _, err := gs.LabelsAPI.LabelID(ctx, UserIDMe, "Missing"); err == nil {
+		t.Errorf("LabelsAPI.LabelID() expected error for missing label")
+	}
+}
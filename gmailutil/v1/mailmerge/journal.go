@@ -0,0 +1,89 @@
+package mailmerge
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// Row statuses for `RowResult.Status`, also written to the status column of the sheet.
+const (
+	RowStatusSent    = "SENT"
+	RowStatusDraft   = "DRAFT"
+	RowStatusFailed  = "FAILED"
+	RowStatusSkipped = "SKIPPED"
+)
+
+// RowResult is the outcome of sending the message for one recipient row.
+type RowResult struct {
+	Row       int       `json:"row"` // index in `Table.Rows`
+	Key       string    `json:"key"` // identifies the row across runs; see `rowKey()`
+	To        string    `json:"to,omitempty"`
+	Status    string    `json:"status"`
+	MessageID string    `json:"messageId,omitempty"`
+	SentAt    time.Time `json:"sentAt,omitzero"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Journal is an append-only JSON Lines file of `RowResult`s. `MailMerge.SendRows()` skips
+// rows the journal records as sent, so a failed or interrupted merge can be rerun without
+// sending any row twice. Use one journal per campaign.
+type Journal struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewJournal returns a journal backed by the file at `path`, which is created on the
+// first append.
+func NewJournal(path string) *Journal {
+	return &Journal{Path: path}
+}
+
+// Append writes `res` to the journal and syncs it to disk.
+func (j *Journal) Append(res RowResult) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f, err := os.OpenFile(j.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err == nil {
+		err = f.Sync()
+	}
+	return errors.Join(err, f.Close())
+}
+
+// Results returns the latest result for each row key. A missing journal has no results.
+func (j *Journal) Results() (map[string]RowResult, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	results := map[string]RowResult{}
+	f, err := os.Open(j.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return results, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var res RowResult
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			// A line cut short by a crash is ignored.
+			continue
+		}
+		results[res.Key] = res
+	}
+	return results, scanner.Err()
+}
@@ -5,19 +5,21 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"net/textproto"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/Iwark/spreadsheet"
 	"github.com/grokify/gocharts/v2/data/table"
 	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
-	"github.com/grokify/gogoogle/sheetsutil/iwark"
 	"github.com/grokify/mogo/encoding/jsonutil"
 	"github.com/grokify/mogo/mime/multipartutil"
 	"github.com/grokify/mogo/net/http/httputilmore"
 	"github.com/grokify/mogo/net/mailutil"
 	"github.com/grokify/mogo/type/stringsutil"
 	"github.com/grokify/sogo/text/mustacheutil"
+	drive "google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
 )
 
 const (
@@ -39,26 +41,54 @@ type MailMergeOpts struct {
 	RecipientsGoogleSheetID         string   `short:"s" long:"sheet-id" description:"The Google Sheet ID"`
 	RecipientsGoogleSheetIndex      uint     `short:"x" long:"sheet-index" description:"The Google Sheet Index"`
 	RecipientsGoogleSheetHeaderRows uint32   `short:"r" long:"sheet-header-row-count" description:"The Google Sheet header row count"`
+	RecipientsFilename              string   `long:"recipients" description:"Recipients file: CSV, TSV, XLSX, JSON or NDJSON"`
 	SubjectTemplateTextFilename     string   `short:"j" long:"subject-template" description:"Subject template"`
 	BodyTemplateHTMLFilename        string   `long:"html-template" description:"Body tmeplate for HTML"`
 	BodyTemplateTextFilename        string   `short:"t" long:"text-template" description:"Body template for text"`
 	InlineFilenames                 []string `short:"i" long:"inline-filename" description:"Inline filenames"`
 	AttachmentsFilenames            []string `short:"a" long:"attachment-filename" description:"Filenames as attachments"`
+	InlineFileTemplates             []string `long:"inline-template" description:"Per-recipient inline filename template"`
+	AttachmentFileTemplates         []string `long:"attachment-template" description:"Per-recipient attachment filename template"`
+	FilesDir                        string   `long:"files-dir" description:"Directory for relative per-recipient filenames"`
 
 	GoogleClient       *http.Client
 	BodyCommonPartsSet multipartutil.PartsSet
+	// Recipients, if set, is used instead of `RecipientsFilename` and the Google Sheet.
+	Recipients RecipientSource
+}
+
+// RecipientSource returns `opts.Recipients`, a source for `opts.RecipientsFilename`, or
+// a `GoogleSheetSource` for `opts.RecipientsGoogleSheetID`, in that order.
+func (opts MailMergeOpts) RecipientSource() (RecipientSource, error) {
+	if opts.Recipients != nil {
+		return opts.Recipients, nil
+	} else if fn := strings.TrimSpace(opts.RecipientsFilename); fn != "" {
+		return NewRecipientSource(fn)
+	}
+	return &GoogleSheetSource{
+		Client:         opts.GoogleClient,
+		SheetID:        strings.TrimSpace(opts.RecipientsGoogleSheetID),
+		SheetIndex:     opts.RecipientsGoogleSheetIndex,
+		HeaderRowCount: opts.RecipientsGoogleSheetHeaderRows,
+	}, nil
+}
+
+func (opts MailMergeOpts) useGoogleSheet() bool {
+	return opts.Recipients == nil && strings.TrimSpace(opts.RecipientsFilename) == ""
 }
 
 func (opts MailMergeOpts) Validate() error {
 	var errorMsgs []string
-	if opts.GoogleClient == nil {
-		errorMsgs = append(errorMsgs, "GoogleClient is nil")
-	}
-	if strings.TrimSpace(opts.RecipientsGoogleSheetID) == "" {
-		errorMsgs = append(errorMsgs, "RecipientsGoogleSheetID is empty")
-	}
-	if opts.RecipientsGoogleSheetHeaderRows == 0 {
-		errorMsgs = append(errorMsgs, "RecipientsGoogleSheetHeaderRows cannot be 0")
+	if opts.useGoogleSheet() {
+		if opts.GoogleClient == nil {
+			errorMsgs = append(errorMsgs, "GoogleClient is nil")
+		}
+		if strings.TrimSpace(opts.RecipientsGoogleSheetID) == "" {
+			errorMsgs = append(errorMsgs, "RecipientsGoogleSheetID and RecipientsFilename are empty")
+		}
+		if opts.RecipientsGoogleSheetHeaderRows == 0 {
+			errorMsgs = append(errorMsgs, "RecipientsGoogleSheetHeaderRows cannot be 0")
+		}
 	}
 	if strings.TrimSpace(opts.SubjectTemplateTextFilename) == "" {
 		errorMsgs = append(errorMsgs, "subject templates is empty: SubjectTemplateFilename")
@@ -74,10 +104,23 @@ func (opts MailMergeOpts) Validate() error {
 }
 
 type MailMerge struct {
-	BodyTemplateSet *mustacheutil.MustacheSet
-	Table           *table.Table
-	CommonPartsSet  multipartutil.PartsSet
-	GmailService    *gmailutil.GmailService
+	BodyTemplateSet     *mustacheutil.MustacheSet
+	Table               *table.Table
+	Sheet               *spreadsheet.Sheet // source of `Table` if read from a Google Sheet
+	SheetHeaderRowCount uint32
+	CommonPartsSet      multipartutil.PartsSet
+	GmailService        *gmailutil.GmailService
+
+	// Per-row files are read from the `INLINE` and `ATTACHMENTS` columns and from these
+	// Mustache filename templates, such as `invoices/{{INVOICE_ID}}.pdf`. See `PrepareFiles()`.
+	InlineFileTemplates     []string
+	AttachmentFileTemplates []string
+	FilesDir                string         // directory for relative per-row filenames
+	DriveService            *drive.Service // downloads `drive:` file references
+	DriveCacheDir           string         // defaults to a new temporary directory
+
+	rowFiles   map[int][]RowFile
+	driveFiles map[string]string // Drive file ID to downloaded filename
 }
 
 func NewMailMerge(ctx context.Context, opts *MailMergeOpts) (*MailMerge, error) {
@@ -94,7 +137,13 @@ func NewMailMerge(ctx context.Context, opts *MailMergeOpts) (*MailMerge, error)
 				templateTypeSubjectText: opts.SubjectTemplateTextFilename,
 			},
 		},
-		CommonPartsSet: opts.BodyCommonPartsSet.Clone(),
+		CommonPartsSet:          opts.BodyCommonPartsSet.Clone(),
+		InlineFileTemplates:     opts.InlineFileTemplates,
+		AttachmentFileTemplates: opts.AttachmentFileTemplates,
+		FilesDir:                strings.TrimSpace(opts.FilesDir),
+	}
+	if fn := strings.TrimSpace(opts.RecipientsFilename); mm.FilesDir == "" && fn != "" {
+		mm.FilesDir = filepath.Dir(fn)
 	}
 
 	if err := mm.BodyTemplateSet.ReadTemplates(); err != nil {
@@ -105,30 +154,32 @@ func NewMailMerge(ctx context.Context, opts *MailMergeOpts) (*MailMerge, error)
 		return nil, err
 	}
 
-	if strings.TrimSpace(opts.RecipientsGoogleSheetID) != "" {
-		if opts.GoogleClient == nil {
-			return nil, errors.New("google client cannot be nil with google sheet id")
-		}
-		tbl, err := iwark.ReadTableFromClient(
-			opts.GoogleClient,
-			opts.RecipientsGoogleSheetID,
-			opts.RecipientsGoogleSheetIndex,
-			&iwark.ReadSpreadsheetOpts{
-				SheetHeaderRowCount: opts.RecipientsGoogleSheetHeaderRows,
-			},
-		)
-		if err != nil {
-			return nil, err
-		} else {
-			mm.Table = tbl
-		}
+	src, err := opts.RecipientSource()
+	if err != nil {
+		return nil, err
+	}
+	if mm.Table, err = src.ReadTable(ctx); err != nil {
+		return nil, err
+	}
+	if gsrc, ok := src.(*GoogleSheetSource); ok {
+		mm.Sheet = gsrc.Sheet
+		mm.SheetHeaderRowCount = max(gsrc.HeaderRowCount, 1)
 	}
 
+	// Without a client, messages can be previewed but not sent.
+	if opts.GoogleClient == nil {
+		return &mm, nil
+	}
 	if gmSvc, err := gmailutil.NewGmailService(ctx, opts.GoogleClient); err != nil {
 		return nil, err
 	} else {
 		mm.GmailService = gmSvc
 	}
+	if driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(opts.GoogleClient)); err != nil {
+		return nil, err
+	} else {
+		mm.DriveService = driveSvc
+	}
 
 	return &mm, nil
 }
@@ -142,107 +193,115 @@ func (mm *MailMerge) loadFilesInline(filenames []string) error {
 }
 
 func (mm *MailMerge) loadFiles(dispositionType string, filenames []string) error {
-	seen := map[string]bool{}
-	for _, filename := range filenames {
-		_, filenameonly := filepath.Split(filename)
-		if _, ok := seen[filenameonly]; ok {
-			return errors.New("duplicate inline filename")
-		} else {
-			seen[filenameonly] = true
-		}
-		mm.CommonPartsSet.Parts = append(mm.CommonPartsSet.Parts, multipartutil.Part{
-			Type:             multipartutil.PartTypeFilepath,
-			BodyEncodeBase64: true,
-			BodyDataFilepath: filenameonly,
-			DispositionType:  dispositionType,
-			HeaderRaw: textproto.MIMEHeader{
-				httputilmore.HeaderContentID: []string{filenameonly},
-			},
-		})
+	parts, err := gmailutil.NewFileParts(dispositionType, filenames)
+	if err != nil {
+		return err
 	}
+	mm.CommonPartsSet.Parts = append(mm.CommonPartsSet.Parts, parts...)
 	return nil
 }
 
 func (mm *MailMerge) Messages() ([]mailutil.MessageWriter, error) {
 	var msgs []mailutil.MessageWriter
-	if mm.BodyTemplateSet == nil {
-		return msgs, errors.New("template set cannot be nil")
-	} else if mm.Table == nil {
-		return msgs, errors.New("recipient table cannot be nil")
-	} else if len(mm.Table.Rows) == 0 {
-		return msgs, errors.New("table has no recipients")
+	if err := mm.validateMessages(); err != nil {
+		return msgs, err
+	} else if err := mm.PrepareFiles(context.Background()); err != nil {
+		return msgs, err
 	}
-	tbl := mm.Table
-
-	for i, row := range tbl.Rows {
-		rowTry := stringsutil.SliceCondenseSpace(row, true, false)
-		if len(rowTry) == 0 {
+	for i, row := range mm.Table.Rows {
+		if isEmptyRow(row) {
 			continue
 		}
-		rowMap := tbl.Columns.RowMap(row, false)
-
-		toAddrs, err := mailutil.ParseAddressList(tbl.Columns.MustCellString(ColumnTo, row))
-		if err != nil {
-			return msgs, err
-		}
-		ccAddrs, err := mailutil.ParseAddressList(tbl.Columns.MustCellString(ColumnCc, row))
+		msgout, err := mm.rowMessage(i, row)
 		if err != nil {
 			return msgs, err
 		}
-		bccAddrs, err := mailutil.ParseAddressList(tbl.Columns.MustCellString(ColumnBcc, row))
-		if err != nil {
-			return msgs, err
-		}
-		if len(toAddrs.FilterInclWithoutAddress()) > 0 {
-			return msgs, fmt.Errorf("to addresses include empty (%s)", tbl.Columns.MustCellString(ColumnTo, row))
-		}
-		if len(ccAddrs.FilterInclWithoutAddress()) > 0 {
-			return msgs, fmt.Errorf("cc addresses include empty (%s)", tbl.Columns.MustCellString(ColumnCc, row))
-		}
-		if len(bccAddrs.FilterInclWithoutAddress()) > 0 {
-			return msgs, fmt.Errorf("bcc addresses include empty (%s)", tbl.Columns.MustCellString(ColumnBcc, row))
-		}
+		msgs = append(msgs, msgout)
+	}
 
-		bytesSubject, err := mm.BodyTemplateSet.RenderTemplateOrDefault(templateTypeSubjectText, rowMap, []byte{})
-		if err != nil {
-			return msgs, err
-		}
-		bytesBodyText, err := mm.BodyTemplateSet.RenderTemplateOrDefault(templateTypeBodyText, rowMap, []byte{})
-		if err != nil {
-			return msgs, err
-		}
-		bytesBodyHTML, err := mm.BodyTemplateSet.RenderTemplateOrDefault(templateTypeBodyHTML, rowMap, []byte{})
-		if err != nil {
-			return msgs, err
-		}
+	return msgs, nil
+}
 
-		msgParts, err := multipartutil.NewPartsSetMail(bytesBodyText, bytesBodyHTML, mm.CommonPartsSet.Parts)
-		if err != nil {
-			return msgs, err
-		}
+func (mm *MailMerge) validateMessages() error {
+	if mm.BodyTemplateSet == nil {
+		return errors.New("template set cannot be nil")
+	} else if mm.Table == nil {
+		return errors.New("recipient table cannot be nil")
+	} else if len(mm.Table.Rows) == 0 {
+		return errors.New("table has no recipients")
+	}
+	return nil
+}
 
-		msgout := mailutil.MessageWriter{
-			To:           toAddrs,
-			Cc:           ccAddrs,
-			Bcc:          bccAddrs,
-			Subject:      string(bytesSubject),
-			BodyPartsSet: msgParts,
-		}
-		if msgout.RecipientCount() <= 0 {
-			if out, err := jsonutil.MarshalSlice(row, false); err != nil {
-				return msgs, err
-			} else {
-				return msgs, fmt.Errorf("no recpients on row (%d) with data (%s)", i, string(out))
-			}
+func isEmptyRow(row []string) bool {
+	return len(stringsutil.SliceCondenseSpace(row, true, false)) == 0
+}
+
+// rowMessage renders the message for row `i` of the table.
+func (mm *MailMerge) rowMessage(i int, row []string) (mailutil.MessageWriter, error) {
+	tbl := mm.Table
+	rowMap := tbl.Columns.RowMap(row, false)
+
+	toAddrs, err := mailutil.ParseAddressList(tbl.Columns.MustCellString(ColumnTo, row))
+	if err != nil {
+		return mailutil.MessageWriter{}, err
+	}
+	ccAddrs, err := mailutil.ParseAddressList(tbl.Columns.MustCellString(ColumnCc, row))
+	if err != nil {
+		return mailutil.MessageWriter{}, err
+	}
+	bccAddrs, err := mailutil.ParseAddressList(tbl.Columns.MustCellString(ColumnBcc, row))
+	if err != nil {
+		return mailutil.MessageWriter{}, err
+	}
+	if len(toAddrs.FilterInclWithoutAddress()) > 0 {
+		return mailutil.MessageWriter{}, fmt.Errorf("to addresses include empty (%s)", tbl.Columns.MustCellString(ColumnTo, row))
+	}
+	if len(ccAddrs.FilterInclWithoutAddress()) > 0 {
+		return mailutil.MessageWriter{}, fmt.Errorf("cc addresses include empty (%s)", tbl.Columns.MustCellString(ColumnCc, row))
+	}
+	if len(bccAddrs.FilterInclWithoutAddress()) > 0 {
+		return mailutil.MessageWriter{}, fmt.Errorf("bcc addresses include empty (%s)", tbl.Columns.MustCellString(ColumnBcc, row))
+	}
+
+	bytesSubject, err := mm.BodyTemplateSet.RenderTemplateOrDefault(templateTypeSubjectText, rowMap, []byte{})
+	if err != nil {
+		return mailutil.MessageWriter{}, err
+	}
+	bytesBodyText, err := mm.BodyTemplateSet.RenderTemplateOrDefault(templateTypeBodyText, rowMap, []byte{})
+	if err != nil {
+		return mailutil.MessageWriter{}, err
+	}
+	bytesBodyHTML, err := mm.BodyTemplateSet.RenderTemplateOrDefault(templateTypeBodyHTML, rowMap, []byte{})
+	if err != nil {
+		return mailutil.MessageWriter{}, err
+	}
+
+	msgParts, err := multipartutil.NewPartsSetMail(bytesBodyText, bytesBodyHTML, mm.rowParts(i))
+	if err != nil {
+		return mailutil.MessageWriter{}, err
+	}
+
+	msgout := mailutil.MessageWriter{
+		To:           toAddrs,
+		Cc:           ccAddrs,
+		Bcc:          bccAddrs,
+		Subject:      string(bytesSubject),
+		BodyPartsSet: msgParts,
+	}
+	if msgout.RecipientCount() <= 0 {
+		if out, err := jsonutil.MarshalSlice(row, false); err != nil {
+			return msgout, err
 		} else {
-			msgs = append(msgs, msgout)
+			return msgout, fmt.Errorf("no recpients on row (%d) with data (%s)", i, string(out))
 		}
 	}
-
-	return msgs, nil
+	return msgout, nil
 }
 
-func (mm *MailMerge) Send(ctx context.Context, userID string) (int, error) {
+// Enqueue stages the messages in `ob` to be sent from `userID` at `sendAt`, so a
+// campaign can be sent later by `Outbox.Run()`. It returns the number of messages queued.
+func (mm *MailMerge) Enqueue(ctx context.Context, ob *gmailutil.Outbox, userID string, sendAt time.Time) (int, error) {
 	if userID = strings.TrimSpace(userID); userID == "" {
 		userID = gmailutil.UserIDMe
 	}
@@ -252,9 +311,9 @@ func (mm *MailMerge) Send(ctx context.Context, userID string) (int, error) {
 		return -1, err
 	}
 
-	for _, msg := range msgs {
-		if _, err := mm.GmailService.Send(ctx, userID, msg); err != nil {
-			return -1, err
+	for i, msg := range msgs {
+		if _, err := ob.Enqueue(ctx, userID, msg, sendAt); err != nil {
+			return i, err
 		}
 	}
 	return len(msgs), nil
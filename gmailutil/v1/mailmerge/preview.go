@@ -0,0 +1,169 @@
+package mailmerge
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+	"github.com/grokify/mogo/net/mailutil"
+)
+
+// PreviewIndexFilename is the name of the HTML index written by `MailMerge.Preview()`.
+const PreviewIndexFilename = "index.html"
+
+// PreviewEntry describes one rendered message in a preview.
+type PreviewEntry struct {
+	Number       int    // 1-based message number
+	EMLFilename  string // RFC 822 message, e.g. `0001.eml`
+	HTMLFilename string // HTML body with inline images embedded, if the message has one
+	To           string
+	Cc           string
+	Bcc          string
+	Subject      string
+	BodyText     string
+	Attachments  []string
+}
+
+// Preview renders every message to an `.eml` file in `dir`, which is created if needed,
+// and writes an `index.html` page listing the recipients, subject and body of each, so
+// a merge can be checked before it is sent. Nothing is sent. The HTML body of each
+// message is also written to its own file with inline images embedded, so it can be
+// opened in a browser. Message files from an earlier preview in `dir` are removed, so
+// the directory only holds the messages of this merge.
+func (mm *MailMerge) Preview(dir string) ([]PreviewEntry, error) {
+	msgs, err := mm.Messages()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	} else if err := removePreviewFiles(dir); err != nil {
+		return nil, err
+	}
+	var entries []PreviewEntry
+	for i, msg := range msgs {
+		entry, err := writePreviewMessage(dir, i+1, msg)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+	f, err := os.Create(filepath.Join(dir, PreviewIndexFilename))
+	if err != nil {
+		return entries, err
+	}
+	if err := previewIndexTemplate.Execute(f, entries); err != nil {
+		_ = f.Close()
+		return entries, err
+	}
+	return entries, f.Close()
+}
+
+// rxPreviewFilename matches the message files written by `writePreviewMessage()`.
+var rxPreviewFilename = regexp.MustCompile(`^[0-9]{4,}\.(eml|html)$`)
+
+// removePreviewFiles removes the message files of an earlier preview from `dir`. Other
+// files are kept.
+func removePreviewFiles(dir string) error {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, de := range des {
+		if de.Type().IsRegular() && rxPreviewFilename.MatchString(de.Name()) {
+			if err := os.Remove(filepath.Join(dir, de.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writePreviewMessage(dir string, number int, msg mailutil.MessageWriter) (PreviewEntry, error) {
+	entry := PreviewEntry{
+		Number:      number,
+		EMLFilename: fmt.Sprintf("%04d.eml", number)}
+	raw, err := msg.Bytes()
+	if err != nil {
+		return entry, err
+	} else if err := os.WriteFile(filepath.Join(dir, entry.EMLFilename), raw, 0o644); err != nil {
+		return entry, err
+	}
+	pm, err := gmailutil.ParseMessageRaw(raw)
+	if err != nil {
+		return entry, err
+	}
+	entry.To = pm.To.String(false, false, false)
+	entry.Cc = pm.Cc.String(false, false, false)
+	entry.Bcc = msg.Bcc.String(false, false, false)
+	entry.Subject = pm.Subject
+	entry.BodyText = pm.BodyText
+	for _, att := range pm.Attachments {
+		entry.Attachments = append(entry.Attachments, att.Filename)
+	}
+	if strings.TrimSpace(pm.BodyHTML) != "" {
+		entry.HTMLFilename = fmt.Sprintf("%04d.html", number)
+		if err := os.WriteFile(filepath.Join(dir, entry.HTMLFilename), []byte(embedInlineImages(pm)), 0o644); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// embedInlineImages returns the HTML body of `pm` with `cid:` references replaced by
+// `data:` URLs of the inline parts, so browsers can display it.
+func embedInlineImages(pm *gmailutil.ParsedMessage) string {
+	body := pm.BodyHTML
+	for _, att := range pm.Attachments {
+		if att.ContentID == "" || len(att.Data) == 0 {
+			continue
+		}
+		dataURL := "data:" + att.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(att.Data)
+		body = strings.ReplaceAll(body, "cid:"+url.PathEscape(att.ContentID), dataURL)
+		body = strings.ReplaceAll(body, "cid:"+att.ContentID, dataURL)
+	}
+	return body
+}
+
+var previewIndexTemplate = template.Must(template.New(PreviewIndexFilename).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Mail merge preview</title>
+<style>
+body{font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Helvetica,Arial,sans-serif;font-size:14px;margin:24px;color:#1f2328}
+table{border-collapse:collapse;width:100%}
+th,td{padding:6px 10px;border:1px solid #d1d9e0;text-align:left;vertical-align:top}
+th{background:#f6f8fa}
+pre{white-space:pre-wrap;margin:8px 0 0}
+</style>
+</head>
+<body>
+<h1>Mail merge preview</h1>
+<p>{{len .}} message(s). Nothing has been sent.</p>
+<table>
+<thead>
+<tr><th>#</th><th>Recipients</th><th>Subject</th><th>Body</th><th>Attachments</th><th>Files</th></tr>
+</thead>
+<tbody>
+{{- range .}}
+<tr>
+<td>{{.Number}}</td>
+<td>To: {{.To}}{{if .Cc}}<br>Cc: {{.Cc}}{{end}}{{if .Bcc}}<br>Bcc: {{.Bcc}}{{end}}</td>
+<td>{{.Subject}}</td>
+<td>{{if .BodyText}}<details><summary>Text</summary><pre>{{.BodyText}}</pre></details>{{end}}{{if .HTMLFilename}}<a href="{{.HTMLFilename}}">HTML</a>{{end}}</td>
+<td>{{range $i, $a := .Attachments}}{{if $i}}<br>{{end}}{{$a}}{{end}}</td>
+<td><a href="{{.EMLFilename}}">{{.EMLFilename}}</a></td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+</body>
+</html>
+`))
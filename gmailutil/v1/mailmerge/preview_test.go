@@ -0,0 +1,117 @@
+package mailmerge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grokify/gocharts/v2/data/table"
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+	"github.com/grokify/mogo/mime/multipartutil"
+	"github.com/grokify/mogo/net/http/httputilmore"
+	"github.com/grokify/sogo/text/mustacheutil"
+)
+
+// newTestMailMerge returns a `MailMerge` with templates written to `dir` and the
+// recipients in `rows`, which have the columns `TO` and `NAME`.
+func newTestMailMerge(t *testing.T, dir string, rows [][]string) *MailMerge {
+	t.Helper()
+	files := map[string]string{
+		templateTypeSubjectText: "Hello {{{NAME}}}",
+		templateTypeBodyText:    "Hi {{NAME}},\nWelcome.",
+		templateTypeBodyHTML:    `<p>Hi <b>{{NAME}}</b></p><img src="cid:logo.png">`,
+	}
+	mm := &MailMerge{
+		BodyTemplateSet: &mustacheutil.MustacheSet{Filenames: map[string]string{}},
+		CommonPartsSet:  multipartutil.NewPartsSet(httputilmore.ContentTypeMultipartMixed),
+	}
+	for key, tmpl := range files {
+		filename := filepath.Join(dir, key+".mustache")
+		if err := os.WriteFile(filename, []byte(tmpl), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		mm.BodyTemplateSet.Filenames[key] = filename
+	}
+	if err := mm.BodyTemplateSet.ReadTemplates(); err != nil {
+		t.Fatalf("ReadTemplates() error: [%v]", err)
+	}
+	logo := filepath.Join(dir, "logo.png")
+	if err := os.WriteFile(logo, []byte("\x89PNG\r\n\x1a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	} else if err := mm.loadFilesInline([]string{logo}); err != nil {
+		t.Fatalf("loadFilesInline() error: [%v]", err)
+	}
+	tbl := table.NewTable("recipients")
+	tbl.Columns = []string{ColumnTo, "NAME"}
+	tbl.Rows = rows
+	mm.Table = &tbl
+	return mm
+}
+
+func TestMailMergePreview(t *testing.T) {
+	dir := t.TempDir()
+	mm := newTestMailMerge(t, dir, [][]string{
+		{"alice@example.com", "Alice"},
+		{"", ""},
+		{"Bob <bob@example.com>", "Bob <Co>"},
+	})
+	out := filepath.Join(dir, "preview")
+	// Files from an earlier, larger preview are removed; other files are kept.
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"0003.eml", "0003.html", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(out, name), []byte("old"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := mm.Preview(out)
+	if err != nil {
+		t.Fatalf("Preview() error: [%v]", err)
+	} else if len(entries) != 2 {
+		t.Fatalf("Preview() mismatch: want (2) entries, got (%d)", len(entries))
+	}
+	if e := entries[1]; e.EMLFilename != "0002.eml" || e.Subject != "Hello Bob <Co>" ||
+		!strings.Contains(e.To, "bob@example.com") || e.HTMLFilename != "0002.html" {
+		t.Errorf("Preview() entry mismatch: got [%+v]", e)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(out, "0001.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, err := gmailutil.ParseMessageRaw(raw)
+	if err != nil {
+		t.Fatalf("ParseMessageRaw() error: [%v]", err)
+	} else if pm.Subject != "Hello Alice" || !strings.Contains(pm.BodyText, "Hi Alice") || len(pm.Attachments) != 1 {
+		t.Errorf("0001.eml mismatch: subject (%s) text (%s) attachments (%d)", pm.Subject, pm.BodyText, len(pm.Attachments))
+	}
+
+	htmlBody, err := os.ReadFile(filepath.Join(out, "0001.html"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(htmlBody), `src="data:image/png;base64,`) {
+		t.Errorf("0001.html does not embed the inline image: [%s]", htmlBody)
+	}
+
+	for _, name := range []string{"0003.eml", "0003.html"} {
+		if _, err := os.Stat(filepath.Join(out, name)); !os.IsNotExist(err) {
+			t.Errorf("Preview() did not remove earlier file (%s): [%v]", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "notes.txt")); err != nil {
+		t.Errorf("Preview() removed other file: [%v]", err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(out, PreviewIndexFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"2 message(s)", "Hello Bob &lt;Co&gt;", `href="0002.eml"`, "logo.png"} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("index.html missing (%s)", want)
+		}
+	}
+}
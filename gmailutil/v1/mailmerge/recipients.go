@@ -0,0 +1,268 @@
+package mailmerge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Iwark/spreadsheet"
+	"github.com/grokify/gocharts/v2/data/table"
+	"github.com/grokify/gogoogle/sheetsutil/iwark"
+)
+
+var (
+	ErrRecipientsFormatNotSupported = errors.New("recipients file format not supported")
+	ErrRecipientsJSONTrailingData   = errors.New("unexpected data after recipients JSON array")
+)
+
+// RecipientSource loads the recipient table for a mail merge. The table has a header
+// row of column names such as `TO`, `CC` and `BCC`, and template variables.
+type RecipientSource interface {
+	ReadTable(ctx context.Context) (*table.Table, error)
+}
+
+// NewRecipientSource returns a source for the local file `filename` based on its
+// extension: `.csv`, `.tsv`, `.xlsx`, `.json`, `.ndjson` or `.jsonl`.
+func NewRecipientSource(filename string) (RecipientSource, error) {
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".csv":
+		return &CSVSource{Filename: filename}, nil
+	case ".tsv":
+		return &CSVSource{Filename: filename, Comma: '\t'}, nil
+	case ".xlsx":
+		return &XLSXSource{Filename: filename}, nil
+	case ".json", ".ndjson", ".jsonl":
+		return &JSONSource{Filename: filename}, nil
+	default:
+		return nil, fmt.Errorf("%w: (%s)", ErrRecipientsFormatNotSupported, ext)
+	}
+}
+
+// CSVSource reads recipients from a delimited file with a header row.
+type CSVSource struct {
+	Filename string
+	Comma    rune // defaults to `,`
+}
+
+// ReadTable implements `RecipientSource`.
+func (src *CSVSource) ReadTable(ctx context.Context) (*table.Table, error) {
+	opts := &table.ParseOptions{FieldsPerRecord: -1, TrimSpace: true}
+	if src.Comma != 0 {
+		opts.UseComma = true
+		opts.Comma = src.Comma
+	}
+	tbl, err := table.ReadFile(opts, src.Filename)
+	if err != nil {
+		return nil, err
+	}
+	tbl.Columns = table.Columns(trimSpaceColumns(tbl.Columns))
+	return &tbl, nil
+}
+
+// XLSXSource reads recipients from a sheet of an Excel workbook. The first row is the
+// header row.
+type XLSXSource struct {
+	Filename       string
+	SheetIndex     uint32
+	HeaderRowCount uint32 // defaults to 1
+}
+
+// ReadTable implements `RecipientSource`.
+func (src *XLSXSource) ReadTable(ctx context.Context) (*table.Table, error) {
+	return table.ReadTableXLSXIndexFile(src.Filename, src.SheetIndex, max(src.HeaderRowCount, 1), true)
+}
+
+// JSONSource reads recipients from a JSON array of objects or from newline delimited
+// JSON with one object per line. Columns are the object keys in order of first use.
+// Numbers and booleans are formatted as in JSON, null is empty, and arrays of scalars
+// are joined with `, `, so `"to": ["a@example.com", "b@example.com"]` is a list.
+type JSONSource struct {
+	Filename string
+}
+
+// ReadTable implements `RecipientSource`.
+func (src *JSONSource) ReadTable(ctx context.Context) (*table.Table, error) {
+	data, err := os.ReadFile(src.Filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJSONRecipients(data)
+}
+
+// ParseJSONRecipients parses a JSON array of objects or newline delimited JSON objects
+// as described for `JSONSource`.
+func ParseJSONRecipients(data []byte) (*table.Table, error) {
+	tbl := table.NewTable("")
+	colIdx := map[string]int{}
+	var records []map[string]string
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	isArray := bytes.HasPrefix(bytes.TrimSpace(data), []byte("["))
+	if isArray {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; !isArray || dec.More(); i++ {
+		rec, keys, err := decodeJSONRecord(dec)
+		if err == io.EOF && !isArray {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("recipient (%d): %w", i, err)
+		}
+		for _, k := range keys {
+			if _, ok := colIdx[k]; !ok {
+				colIdx[k] = len(tbl.Columns)
+				tbl.Columns = append(tbl.Columns, k)
+			}
+		}
+		records = append(records, rec)
+	}
+	if isArray {
+		// consume the closing `]` and require nothing after it
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		} else if _, err := dec.Token(); err != io.EOF {
+			return nil, ErrRecipientsJSONTrailingData
+		}
+	}
+	for _, rec := range records {
+		row := make([]string, len(tbl.Columns))
+		for k, v := range rec {
+			row[colIdx[k]] = v
+		}
+		tbl.Rows = append(tbl.Rows, row)
+	}
+	return &tbl, nil
+}
+
+// decodeJSONRecord decodes one object with scalar values, returning the values and the
+// keys in document order.
+func decodeJSONRecord(dec *json.Decoder) (map[string]string, []string, error) {
+	var obj map[string]json.RawMessage
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return nil, nil, err
+	} else if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, nil, errors.New("recipient must be a JSON object")
+	}
+	keys, err := jsonObjectKeys(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	rec := map[string]string{}
+	for k, v := range obj {
+		s, err := jsonScalarString(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field (%s): %w", k, err)
+		}
+		rec[strings.TrimSpace(k)] = s
+	}
+	for i, k := range keys {
+		keys[i] = strings.TrimSpace(k)
+	}
+	return rec, keys, nil
+}
+
+// jsonObjectKeys returns the keys of the JSON object `raw` in document order.
+func jsonObjectKeys(raw json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, tok.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func jsonScalarString(v json.RawMessage) (string, error) {
+	var x any
+	dec := json.NewDecoder(bytes.NewReader(v))
+	dec.UseNumber()
+	if err := dec.Decode(&x); err != nil {
+		return "", err
+	}
+	switch t := x.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case []any:
+		var parts []string
+		for _, e := range t {
+			s, err := jsonScalarString(mustMarshal(e))
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ", "), nil
+	default:
+		return "", errors.New("nested objects are not supported")
+	}
+}
+
+func mustMarshal(v any) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+func trimSpaceColumns(cols []string) []string {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		out[i] = strings.TrimSpace(c)
+	}
+	return out
+}
+
+// GoogleSheetSource reads recipients from a Google Sheet. After `ReadTable`, `Sheet` is
+// the sheet read, which `MailMerge.NewSheetStatusWriter()` uses to write send status.
+type GoogleSheetSource struct {
+	Client         *http.Client
+	SheetID        string // ID or URL
+	SheetIndex     uint
+	HeaderRowCount uint32 // defaults to 1
+	Sheet          *spreadsheet.Sheet
+}
+
+// ReadTable implements `RecipientSource`.
+func (src *GoogleSheetSource) ReadTable(ctx context.Context) (*table.Table, error) {
+	if src.Client == nil {
+		return nil, errors.New("google client cannot be nil with google sheet id")
+	}
+	sheet, err := iwark.ReadSheetFromClient(src.Client, src.SheetID, src.SheetIndex)
+	if err != nil {
+		return nil, err
+	}
+	tbl, err := iwark.ParseTableFromSheet(sheet, &iwark.ReadSpreadsheetOpts{
+		SheetHeaderRowCount: max(src.HeaderRowCount, 1),
+	})
+	if err != nil {
+		return nil, err
+	}
+	src.Sheet = sheet
+	return tbl, nil
+}
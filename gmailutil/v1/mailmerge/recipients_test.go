@@ -0,0 +1,95 @@
+package mailmerge
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecipientSources(t *testing.T) {
+	dir := t.TempDir()
+	wantCols := []string{"TO", "NAME"}
+	wantRows := [][]string{{"alice@example.com", "Alice"}, {"bob@example.com", "Bob"}}
+
+	tests := []struct {
+		filename string
+		data     string
+		wantCols []string
+		wantRows [][]string
+	}{
+		{"a.csv", "TO, NAME\nalice@example.com, Alice\nbob@example.com,Bob\n", wantCols, wantRows},
+		{"b.tsv", "TO\tNAME\nalice@example.com\tAlice\nbob@example.com\tBob\n", wantCols, wantRows},
+		{"c.json", `[{"TO": "alice@example.com", "NAME": "Alice"}, {"NAME": "Bob", "TO": "bob@example.com"}]`, wantCols, wantRows},
+		{"d.ndjson", "{\"TO\": \"alice@example.com\", \"NAME\": \"Alice\"}\n\n{\"TO\": \"bob@example.com\", \"NAME\": \"Bob\"}\n", wantCols, wantRows},
+		{"e.jsonl", `{"TO": ["a@example.com", "b@example.com"], "ID": 42, "VIP": true, "NOTE": null}` + "\n" + `{"TO": "c@example.com", "CITY": "Paris"}`,
+			[]string{"TO", "ID", "VIP", "NOTE", "CITY"},
+			[][]string{{"a@example.com, b@example.com", "42", "true", "", ""}, {"c@example.com", "", "", "", "Paris"}}},
+	}
+	for _, tt := range tests {
+		filename := filepath.Join(dir, tt.filename)
+		if err := os.WriteFile(filename, []byte(tt.data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		src, err := NewRecipientSource(filename)
+		if err != nil {
+			t.Fatalf("NewRecipientSource(%s) error: [%v]", tt.filename, err)
+		}
+		tbl, err := src.ReadTable(context.Background())
+		if err != nil {
+			t.Errorf("ReadTable(%s) error: [%v]", tt.filename, err)
+			continue
+		}
+		if !reflect.DeepEqual([]string(tbl.Columns), tt.wantCols) || !reflect.DeepEqual(tbl.Rows, tt.wantRows) {
+			t.Errorf("ReadTable(%s) mismatch: columns (%v) rows (%v)", tt.filename, tbl.Columns, tbl.Rows)
+		}
+	}
+
+	if _, err := NewRecipientSource("contacts.txt"); !errors.Is(err, ErrRecipientsFormatNotSupported) {
+		t.Errorf("NewRecipientSource(contacts.txt) mismatch: got [%v]", err)
+	}
+	if _, err := ParseJSONRecipients([]byte(`[{"TO": {"a": 1}}]`)); err == nil {
+		t.Error("ParseJSONRecipients() with nested object: want error")
+	}
+	for _, data := range []string{`[{"TO": "a@example.com"}] junk`, `[{"TO": "a@example.com"}] {"TO": "b@example.com"}`} {
+		if _, err := ParseJSONRecipients([]byte(data)); !errors.Is(err, ErrRecipientsJSONTrailingData) {
+			t.Errorf("ParseJSONRecipients(%s) mismatch: want [%v], got [%v]", data, ErrRecipientsJSONTrailingData, err)
+		}
+	}
+}
+
+func TestNewMailMergeRecipientsFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"subject.mustache": "Hello {{{NAME}}}",
+		"body.mustache":    "Hi {{NAME}}",
+		"contacts.csv":     "TO,NAME\nalice@example.com,Alice\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// No Google client is needed to render messages from a local file.
+	mm, err := NewMailMerge(context.Background(), &MailMergeOpts{
+		RecipientsFilename:          filepath.Join(dir, "contacts.csv"),
+		SubjectTemplateTextFilename: filepath.Join(dir, "subject.mustache"),
+		BodyTemplateTextFilename:    filepath.Join(dir, "body.mustache"),
+	})
+	if err != nil {
+		t.Fatalf("NewMailMerge() error: [%v]", err)
+	}
+	msgs, err := mm.Messages()
+	if err != nil {
+		t.Fatalf("Messages() error: [%v]", err)
+	}
+	if len(msgs) != 1 || msgs[0].Subject != "Hello Alice" || mm.GmailService != nil || mm.Sheet != nil {
+		t.Errorf("NewMailMerge() mismatch: messages (%d)", len(msgs))
+	}
+
+	if err := (MailMergeOpts{SubjectTemplateTextFilename: "s", BodyTemplateTextFilename: "b"}).Validate(); err == nil {
+		t.Error("MailMergeOpts.Validate() without recipients: want error")
+	}
+}
@@ -0,0 +1,305 @@
+package mailmerge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cbroglie/mustache"
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+	"github.com/grokify/mogo/mime/multipartutil"
+	"github.com/grokify/mogo/net/http/httputilmore"
+	"github.com/grokify/mogo/type/stringsutil"
+)
+
+// Per-row file columns. Values are file references separated by `;` or line breaks.
+const (
+	ColumnAttachments = "ATTACHMENTS"
+	ColumnInline      = "INLINE"
+)
+
+// DriveFilePrefix marks a file reference as a Google Drive file ID, as in `drive:1AbC...`.
+// Drive URLs such as `https://drive.google.com/file/d/1AbC.../view` are also accepted.
+const DriveFilePrefix = "drive:"
+
+// driveExportMIMEType is the format Google Docs, Sheets and Slides files are exported as.
+const driveExportMIMEType = "application/pdf"
+
+var (
+	ErrRowFileNotFound         = errors.New("mail merge file not found")
+	ErrDriveServiceCannotBeNil = errors.New("drive service cannot be nil for drive file")
+
+	rxDriveFileURL      = regexp.MustCompile(`^https://(?:drive|docs)\.google\.com/.*?/d/([A-Za-z0-9_-]+)`)
+	rxDriveFileURLQuery = regexp.MustCompile(`^https://drive\.google\.com/.*[?&]id=([A-Za-z0-9_-]+)`)
+)
+
+// RowFile is a file attached to, or shown inline in, the message for one row.
+type RowFile struct {
+	Ref             string // reference from the row or template, such as a path or Drive ID
+	Filename        string // local path, downloaded to `MailMerge.DriveCacheDir` for Drive files
+	DispositionType string // `httputilmore.DispositionTypeInline` or `DispositionTypeAttachment`
+}
+
+// HasRowFiles reports if any row can have its own files, from the `ATTACHMENTS` or
+// `INLINE` columns or from file templates.
+func (mm *MailMerge) HasRowFiles() bool {
+	if len(mm.AttachmentFileTemplates) > 0 || len(mm.InlineFileTemplates) > 0 {
+		return true
+	} else if mm.Table == nil {
+		return false
+	}
+	return columnIndex(mm.Table.Columns, ColumnAttachments) >= 0 || columnIndex(mm.Table.Columns, ColumnInline) >= 0
+}
+
+// PrepareFiles resolves the files of every row, downloading Drive files, and checks they
+// exist. Every row is checked so all missing files are reported together, before any
+// message is sent. `Messages()` and `SendRows()` call it before rendering.
+func (mm *MailMerge) PrepareFiles(ctx context.Context) error {
+	mm.rowFiles = map[int][]RowFile{}
+	if !mm.HasRowFiles() {
+		return nil
+	}
+	tmpls, err := mm.fileTemplates()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i, row := range mm.Table.Rows {
+		if isEmptyRow(row) {
+			continue
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		files, err := mm.resolveRowFiles(ctx, row, tmpls)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("row (%d): %w", i, err))
+			continue
+		}
+		mm.rowFiles[i] = files
+	}
+	return errors.Join(errs...)
+}
+
+// RowFiles returns the files for row `i` resolved by `PrepareFiles()`.
+func (mm *MailMerge) RowFiles(i int) []RowFile {
+	return mm.rowFiles[i]
+}
+
+type fileTemplate struct {
+	dispositionType string
+	tmpl            *mustache.Template
+}
+
+// fileTemplates parses `AttachmentFileTemplates` and `InlineFileTemplates`. Values are
+// not HTML escaped.
+func (mm *MailMerge) fileTemplates() ([]fileTemplate, error) {
+	var tmpls []fileTemplate
+	for _, set := range []struct {
+		dispositionType string
+		templates       []string
+	}{
+		{httputilmore.DispositionTypeInline, mm.InlineFileTemplates},
+		{httputilmore.DispositionTypeAttachment, mm.AttachmentFileTemplates},
+	} {
+		for _, t := range set.templates {
+			tmpl, err := mustache.ParseStringRaw(t, true)
+			if err != nil {
+				return nil, fmt.Errorf("file template (%s): %w", t, err)
+			}
+			tmpls = append(tmpls, fileTemplate{dispositionType: set.dispositionType, tmpl: tmpl})
+		}
+	}
+	return tmpls, nil
+}
+
+// resolveRowFiles returns the files for `row`, joining every error found.
+func (mm *MailMerge) resolveRowFiles(ctx context.Context, row []string, tmpls []fileTemplate) ([]RowFile, error) {
+	var refs []RowFile
+	for _, col := range []struct {
+		name            string
+		dispositionType string
+	}{
+		{ColumnInline, httputilmore.DispositionTypeInline},
+		{ColumnAttachments, httputilmore.DispositionTypeAttachment},
+	} {
+		if idx := columnIndex(mm.Table.Columns, col.name); idx >= 0 && idx < len(row) {
+			for _, ref := range SplitFileRefs(row[idx]) {
+				refs = append(refs, RowFile{Ref: ref, DispositionType: col.dispositionType})
+			}
+		}
+	}
+	if len(tmpls) > 0 {
+		rowMap := mm.Table.Columns.RowMap(row, false)
+		for _, ft := range tmpls {
+			ref, err := ft.tmpl.Render(rowMap)
+			if err != nil {
+				return nil, fmt.Errorf("file template: %w", err)
+			}
+			if ref = strings.TrimSpace(ref); ref != "" {
+				refs = append(refs, RowFile{Ref: ref, DispositionType: ft.dispositionType})
+			}
+		}
+	}
+
+	var errs []error
+	var files []RowFile
+	for _, rf := range refs {
+		filename, err := mm.resolveFile(ctx, rf.Ref)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rf.Filename = filename
+		files = append(files, rf)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if err := mm.checkDuplicateRowFiles(files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// resolveFile returns the local filename for a file reference.
+func (mm *MailMerge) resolveFile(ctx context.Context, ref string) (string, error) {
+	if id := DriveFileID(ref); id != "" {
+		return mm.downloadDriveFile(ctx, id)
+	}
+	filename := ref
+	if !filepath.IsAbs(filename) && mm.FilesDir != "" {
+		filename = filepath.Join(mm.FilesDir, filename)
+	}
+	if fi, err := os.Stat(filename); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: (%s)", ErrRowFileNotFound, filename)
+		}
+		return "", err
+	} else if fi.IsDir() {
+		return "", fmt.Errorf("%w: (%s) is a directory", ErrRowFileNotFound, filename)
+	}
+	return filename, nil
+}
+
+// checkDuplicateRowFiles checks that base filenames, used as the `Content-ID`, are unique
+// among the files of a row and the files common to all rows.
+func (mm *MailMerge) checkDuplicateRowFiles(files []RowFile) error {
+	seen := map[string]bool{}
+	for _, p := range mm.CommonPartsSet.Parts {
+		if p.BodyDataFilepath != "" {
+			seen[filepath.Base(p.BodyDataFilepath)] = true
+		}
+	}
+	for _, rf := range files {
+		base := filepath.Base(rf.Filename)
+		if seen[base] {
+			return fmt.Errorf("%w: %s", gmailutil.ErrDuplicateFilename, base)
+		}
+		seen[base] = true
+	}
+	return nil
+}
+
+// rowParts returns the message parts for the files common to all rows and the files
+// of row `i`.
+func (mm *MailMerge) rowParts(i int) multipartutil.Parts {
+	files := mm.rowFiles[i]
+	if len(files) == 0 {
+		return mm.CommonPartsSet.Parts
+	}
+	parts := append(multipartutil.Parts{}, mm.CommonPartsSet.Parts...)
+	for _, rf := range files {
+		parts = append(parts, gmailutil.NewFilePart(rf.DispositionType, rf.Filename))
+	}
+	return parts
+}
+
+// SplitFileRefs splits a cell of file references separated by `;` or line breaks.
+func SplitFileRefs(s string) []string {
+	return stringsutil.SliceCondenseSpace(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ';' || r == '\n' || r == '\r'
+	}), true, false)
+}
+
+// DriveFileID returns the Google Drive file ID of a `drive:` reference or Drive URL, or
+// an empty string for other references.
+func DriveFileID(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if id, ok := strings.CutPrefix(ref, DriveFilePrefix); ok {
+		return strings.TrimSpace(id)
+	} else if m := rxDriveFileURL.FindStringSubmatch(ref); len(m) > 1 {
+		return m[1]
+	} else if m := rxDriveFileURLQuery.FindStringSubmatch(ref); len(m) > 1 {
+		return m[1]
+	}
+	return ""
+}
+
+// downloadDriveFile downloads a Drive file to `<DriveCacheDir>/<fileID>/<name>` and
+// returns the path. Google Docs, Sheets and Slides files are exported as PDF. Files are
+// downloaded once per `MailMerge`.
+func (mm *MailMerge) downloadDriveFile(ctx context.Context, fileID string) (string, error) {
+	if filename, ok := mm.driveFiles[fileID]; ok {
+		return filename, nil
+	} else if mm.DriveService == nil {
+		return "", ErrDriveServiceCannotBeNil
+	}
+	meta, err := mm.DriveService.Files.Get(fileID).SupportsAllDrives(true).
+		Fields("id", "name", "mimeType").Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("%w: drive file (%s): %w", ErrRowFileNotFound, fileID, err)
+	}
+	name := filepath.Base(filepath.Clean("/" + strings.ReplaceAll(meta.Name, `\`, "/")))
+	if name == "/" || name == "." {
+		name = fileID
+	}
+
+	var resp io.ReadCloser
+	if strings.HasPrefix(meta.MimeType, "application/vnd.google-apps.") {
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + ".pdf"
+		r, err := mm.DriveService.Files.Export(fileID, driveExportMIMEType).Context(ctx).Download()
+		if err != nil {
+			return "", fmt.Errorf("drive file (%s) export: %w", fileID, err)
+		}
+		resp = r.Body
+	} else {
+		r, err := mm.DriveService.Files.Get(fileID).SupportsAllDrives(true).Context(ctx).Download()
+		if err != nil {
+			return "", fmt.Errorf("drive file (%s) download: %w", fileID, err)
+		}
+		resp = r.Body
+	}
+	defer resp.Close()
+
+	if mm.DriveCacheDir == "" {
+		if mm.DriveCacheDir, err = os.MkdirTemp("", "gogoogle-mailmerge-"); err != nil {
+			return "", err
+		}
+	}
+	dir := filepath.Join(mm.DriveCacheDir, url.PathEscape(fileID))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	filename := filepath.Join(dir, name)
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(f, resp); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("drive file (%s) download: %w", fileID, err)
+	} else if err := f.Close(); err != nil {
+		return "", err
+	}
+	if mm.driveFiles == nil {
+		mm.driveFiles = map[string]string{}
+	}
+	mm.driveFiles[fileID] = filename
+	return filename, nil
+}
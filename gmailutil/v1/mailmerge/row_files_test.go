@@ -0,0 +1,164 @@
+package mailmerge
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grokify/mogo/net/http/httputilmore"
+	drive "google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+func TestMailMergeRowFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.pdf", "b.pdf", "cert-Alice.png", "cert-Bob.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mm := newTestMailMerge(t, dir, [][]string{
+		{"alice@example.com", "Alice", "a.pdf; b.pdf"},
+		{"bob@example.com", "Bob", ""},
+	})
+	mm.Table.Columns = append(mm.Table.Columns, ColumnAttachments)
+	mm.InlineFileTemplates = []string{"cert-{{NAME}}.png"}
+	mm.FilesDir = dir
+
+	entries, err := mm.Preview(filepath.Join(dir, "preview"))
+	if err != nil {
+		t.Fatalf("Preview() error: [%v]", err)
+	}
+	want := [][]string{
+		{"logo.png", "a.pdf", "b.pdf", "cert-Alice.png"},
+		{"logo.png", "cert-Bob.png"},
+	}
+	for i, e := range entries {
+		if !reflect.DeepEqual(e.Attachments, want[i]) {
+			t.Errorf("Preview() row (%d) files mismatch: want (%v), got (%v)", i, want[i], e.Attachments)
+		}
+	}
+	if files := mm.RowFiles(0); len(files) != 3 || files[2].DispositionType != httputilmore.DispositionTypeInline {
+		t.Errorf("RowFiles(0) mismatch: got [%+v]", files)
+	}
+}
+
+func TestMailMergeRowFilesMissing(t *testing.T) {
+	var sends int
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/messages/send", func(w http.ResponseWriter, r *http.Request) {
+		sends++
+	})
+	dir := t.TempDir()
+	mm := newTestMailMerge(t, dir, [][]string{
+		{"alice@example.com", "Alice", "missing-1.pdf"},
+		{"bob@example.com", "Bob", "logo.png"},
+		{"carol@example.com", "Carol", "missing-2.pdf"},
+	})
+	mm.Table.Columns = append(mm.Table.Columns, ColumnAttachments)
+	mm.FilesDir = dir
+	mm.GmailService = newTestGmailService(t, mux)
+
+	// Nothing is sent when any row references a missing file.
+	res, err := mm.SendRows(context.Background(), SendOpts{})
+	if !errors.Is(err, ErrRowFileNotFound) || sends != 0 || res.Sent != 0 {
+		t.Fatalf("SendRows() mismatch: sends (%d) error [%v]", sends, err)
+	}
+	for _, want := range []string{"row (0)", "missing-1.pdf", "row (1)", "duplicate filename: logo.png", "row (2)", "missing-2.pdf"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("SendRows() error missing (%s): [%v]", want, err)
+		}
+	}
+}
+
+func TestMailMergeRowFilesDrive(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /drive/v3/files/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch id := r.PathValue("id"); {
+		case id == "gone":
+			http.Error(w, `{"error":{"code":404,"message":"File not found"}}`, http.StatusNotFound)
+		case r.URL.Query().Get("alt") == "media":
+			_, _ = w.Write([]byte("%PDF invoice " + id))
+		case id == "doc1":
+			_, _ = w.Write([]byte(`{"id":"doc1","name":"Certificate","mimeType":"application/vnd.google-apps.document"}`))
+		default:
+			_, _ = w.Write([]byte(`{"id":"` + id + `","name":"invoice.pdf","mimeType":"application/pdf"}`))
+		}
+	})
+	mux.HandleFunc("GET /drive/v3/files/{id}/export", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("%PDF export " + r.URL.Query().Get("mimeType")))
+	})
+
+	dir := t.TempDir()
+	mm := newTestMailMerge(t, dir, [][]string{
+		{"alice@example.com", "Alice", "drive:file1;https://docs.google.com/document/d/doc1/edit"},
+	})
+	mm.Table.Columns = append(mm.Table.Columns, ColumnAttachments)
+	mm.DriveCacheDir = filepath.Join(dir, "drive")
+	mm.DriveService = newTestDriveService(t, mux)
+
+	if err := mm.PrepareFiles(context.Background()); err != nil {
+		t.Fatalf("PrepareFiles() error: [%v]", err)
+	}
+	files := mm.RowFiles(0)
+	if len(files) != 2 || filepath.Base(files[0].Filename) != "invoice.pdf" || filepath.Base(files[1].Filename) != "Certificate.pdf" {
+		t.Fatalf("PrepareFiles() files mismatch: got [%+v]", files)
+	}
+	if data, err := os.ReadFile(files[1].Filename); err != nil || string(data) != "%PDF export application/pdf" {
+		t.Errorf("exported file mismatch: (%s) error [%v]", data, err)
+	}
+
+	mm.Table.Rows[0][2] = "drive:gone"
+	if err := mm.PrepareFiles(context.Background()); !errors.Is(err, ErrRowFileNotFound) {
+		t.Errorf("PrepareFiles() missing drive file mismatch: got [%v]", err)
+	}
+	mm.DriveService = nil
+	mm.Table.Rows[0][2] = "drive:other"
+	if err := mm.PrepareFiles(context.Background()); !errors.Is(err, ErrDriveServiceCannotBeNil) {
+		t.Errorf("PrepareFiles() without drive service mismatch: got [%v]", err)
+	}
+}
+
+func newTestDriveService(t *testing.T, handler http.Handler) *drive.Service {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := drive.NewService(context.Background(), option.WithHTTPClient(
+		&http.Client{Transport: rewriteTransport{target: target}}))
+	if err != nil {
+		t.Fatalf("drive.NewService() error: [%v]", err)
+	}
+	return svc
+}
+
+func TestDriveFileID(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"drive:1AbC_d-9", "1AbC_d-9"},
+		{"https://drive.google.com/file/d/1AbC_d-9/view?usp=sharing", "1AbC_d-9"},
+		{"https://docs.google.com/document/d/1AbC_d-9/edit", "1AbC_d-9"},
+		{"https://drive.google.com/open?id=1AbC_d-9", "1AbC_d-9"},
+		{"invoices/1AbC_d-9.pdf", ""},
+	}
+	for _, tt := range tests {
+		if got := DriveFileID(tt.ref); got != tt.want {
+			t.Errorf("DriveFileID(%s) mismatch: want (%s), got (%s)", tt.ref, tt.want, got)
+		}
+	}
+	if got := SplitFileRefs(" a.pdf; ;b.pdf\nc.pdf "); !reflect.DeepEqual(got, []string{"a.pdf", "b.pdf", "c.pdf"}) {
+		t.Errorf("SplitFileRefs() mismatch: got (%v)", got)
+	}
+}
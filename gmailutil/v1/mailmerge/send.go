@@ -0,0 +1,226 @@
+package mailmerge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+)
+
+// Default status columns, see `SendOpts`.
+const (
+	ColumnStatus = "STATUS"
+	ColumnSentAt = "SENT_AT"
+)
+
+// StatusWriter records row results in the recipient source, such as the status columns
+// of a Google Sheet. See `SheetStatusWriter`.
+type StatusWriter interface {
+	// WriteStatus records `res` for row `res.Row` of the table. Writes may be buffered.
+	WriteStatus(res RowResult) error
+	// Flush writes buffered results.
+	Flush() error
+}
+
+// SendOpts configures `MailMerge.SendRows()`.
+type SendOpts struct {
+	UserID string // defaults to `me`
+	// Journal, if set, records every result and is used to skip rows already sent.
+	Journal *Journal
+	// StatusWriter, if set, records every result in the recipient source.
+	StatusWriter StatusWriter
+	// StatusColumn is a table column whose value `SENT` marks a row as already sent.
+	// Defaults to `ColumnStatus`; rows are not checked if the table has no such column.
+	StatusColumn string
+	// StopOnError stops at the first failed row. By default failed rows are recorded
+	// and the merge continues.
+	StopOnError bool
+	// OnResult, if set, is called after each row is sent, fails or is skipped.
+	OnResult func(res RowResult)
+}
+
+// SendResult summarizes `MailMerge.SendRows()`.
+type SendResult struct {
+	Sent    int
+	Skipped int // rows already sent in a previous run
+	Failed  int
+	Results []RowResult
+}
+
+// Send sends a message for each row and returns the number sent. Failed rows do not stop
+// the merge; if any fail, an error describing the first failure is returned with the
+// count. Use `SendRows()` for per-row results and resuming.
+func (mm *MailMerge) Send(ctx context.Context, userID string) (int, error) {
+	res, err := mm.SendRows(ctx, SendOpts{UserID: userID})
+	if err != nil {
+		return -1, err
+	}
+	return res.Sent, res.Err()
+}
+
+// Err returns an error describing the first failed row, or nil if no rows failed.
+func (res *SendResult) Err() error {
+	for _, r := range res.Results {
+		if r.Status == RowStatusFailed {
+			return fmt.Errorf("%d row(s) failed; first failure on row (%d) to (%s): %s", res.Failed, r.Row, r.To, r.Error)
+		}
+	}
+	return nil
+}
+
+// SendRows sends a message for each row of the table, recording each result in
+// `opts.Journal` and `opts.StatusWriter`. Rows recorded as sent in the journal, or marked
+// `SENT` in the status column, are skipped. If `mm.GmailService.DraftOnly` is set, drafts
+// are created and recorded as `DRAFT`, and rows already sent or drafted are skipped.
+//
+// Row failures, such as invalid addresses or API errors, are recorded and the merge
+// continues unless `opts.StopOnError` is set. An error is returned if the merge cannot
+// start, the journal cannot be written, `ctx` is cancelled, or a row fails with
+// `opts.StopOnError`; the result always reflects the rows processed.
+func (mm *MailMerge) SendRows(ctx context.Context, opts SendOpts) (*SendResult, error) {
+	res := &SendResult{}
+	if err := mm.validateMessages(); err != nil {
+		return res, err
+	} else if mm.GmailService == nil {
+		return res, gmailutil.ErrGmailServiceCannotBeNil
+	} else if err := mm.PrepareFiles(ctx); err != nil {
+		// Missing files fail the merge before any row is sent.
+		return res, err
+	}
+	userID := strings.TrimSpace(opts.UserID)
+	if userID == "" {
+		userID = gmailutil.UserIDMe
+	}
+	doneStatus := RowStatusSent
+	if mm.GmailService.DraftOnly {
+		doneStatus = RowStatusDraft
+	}
+	done := map[string]RowResult{}
+	if opts.Journal != nil {
+		var err error
+		if done, err = opts.Journal.Results(); err != nil {
+			return res, err
+		}
+	}
+	statusColumn := strings.TrimSpace(opts.StatusColumn)
+	if statusColumn == "" {
+		statusColumn = ColumnStatus
+	}
+	statusIdx := columnIndex(mm.Table.Columns, statusColumn)
+
+	err := mm.sendRows(ctx, opts, userID, doneStatus, done, statusIdx, res)
+	if opts.StatusWriter != nil {
+		err = errors.Join(err, opts.StatusWriter.Flush())
+	}
+	return res, err
+}
+
+func (mm *MailMerge) sendRows(ctx context.Context, opts SendOpts, userID, doneStatus string, done map[string]RowResult, statusIdx int, res *SendResult) error {
+	keys := map[string]int{}
+	for i, row := range mm.Table.Rows {
+		if isEmptyRow(row) {
+			continue
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		r := RowResult{
+			Row: i,
+			Key: rowKey(mm.Table.Columns.MustCellString(ColumnTo, row), mm.Table.Columns.MustCellString(ColumnCc, row),
+				mm.Table.Columns.MustCellString(ColumnBcc, row), keys),
+			To: strings.TrimSpace(mm.Table.Columns.MustCellString(ColumnTo, row))}
+
+		if prev, ok := done[r.Key]; (ok && isRowDone(prev.Status, doneStatus)) ||
+			(statusIdx >= 0 && statusIdx < len(row) && isRowDone(row[statusIdx], doneStatus)) {
+			r.Status = RowStatusSkipped
+			r.MessageID = prev.MessageID
+			res.Skipped++
+			res.Results = append(res.Results, r)
+			if opts.OnResult != nil {
+				opts.OnResult(r)
+			}
+			continue
+		}
+
+		errRow := mm.sendRow(ctx, userID, i, row, &r)
+		if errRow != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errRow != nil {
+			r.Status = RowStatusFailed
+			r.Error = errRow.Error()
+			res.Failed++
+		} else {
+			r.Status = doneStatus
+			res.Sent++
+		}
+		res.Results = append(res.Results, r)
+		if opts.Journal != nil {
+			if err := opts.Journal.Append(r); err != nil {
+				return fmt.Errorf("journal write failed after row (%d): %w", i, err)
+			}
+		}
+		if opts.StatusWriter != nil {
+			if err := opts.StatusWriter.WriteStatus(r); err != nil {
+				return err
+			}
+		}
+		if opts.OnResult != nil {
+			opts.OnResult(r)
+		}
+		if errRow != nil && opts.StopOnError {
+			return fmt.Errorf("row (%d): %w", i, errRow)
+		}
+	}
+	return nil
+}
+
+// sendRow renders and sends the message for a row, setting the message ID and time on `r`.
+func (mm *MailMerge) sendRow(ctx context.Context, userID string, i int, row []string, r *RowResult) error {
+	msg, err := mm.rowMessage(i, row)
+	if err != nil {
+		return err
+	}
+	gmsg, err := mm.GmailService.Send(ctx, userID, msg)
+	if err != nil {
+		return err
+	}
+	r.MessageID = gmsg.Id
+	r.SentAt = time.Now().UTC()
+	return nil
+}
+
+// isRowDone reports if a row with `status` from an earlier run is skipped. Sent rows are
+// always skipped, and rows with `doneStatus` are skipped, so drafting does not repeat
+// earlier drafts or sends.
+func isRowDone(status, doneStatus string) bool {
+	status = strings.TrimSpace(status)
+	return strings.EqualFold(status, RowStatusSent) || strings.EqualFold(status, doneStatus)
+}
+
+// rowKey identifies a row by its normalized recipients, so rows keep their key when
+// rows are inserted or reordered. Repeated recipients are numbered in table order using
+// `seen`.
+func rowKey(to, cc, bcc string, seen map[string]int) string {
+	norm := func(s string) string { return strings.ToLower(strings.Join(strings.Fields(s), " ")) }
+	key := norm(to) + "|" + norm(cc) + "|" + norm(bcc)
+	seen[key]++
+	if n := seen[key]; n > 1 {
+		key += "#" + strconv.Itoa(n)
+	}
+	return key
+}
+
+// columnIndex returns the index of the column named `name`, ignoring case and
+// surrounding space, or -1.
+func columnIndex(cols []string, name string) int {
+	for i, col := range cols {
+		if strings.EqualFold(strings.TrimSpace(col), name) {
+			return i
+		}
+	}
+	return -1
+}
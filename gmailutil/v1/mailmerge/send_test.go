@@ -0,0 +1,208 @@
+package mailmerge
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	gmailutil "github.com/grokify/gogoogle/gmailutil/v1"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+// rewriteTransport sends all requests to a test server.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (rt rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = rt.target.Scheme
+	r.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func newTestGmailService(t *testing.T, handler http.Handler) *gmailutil.GmailService {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs, err := gmailutil.NewGmailService(context.Background(), &http.Client{Transport: rewriteTransport{target: target}})
+	if err != nil {
+		t.Fatalf("NewGmailService() error: [%v]", err)
+	}
+	return gs
+}
+
+// fakeStatusWriter records results passed to `WriteStatus`.
+type fakeStatusWriter struct {
+	results []RowResult
+	flushes int
+}
+
+func (w *fakeStatusWriter) WriteStatus(res RowResult) error {
+	w.results = append(w.results, res)
+	return nil
+}
+
+func (w *fakeStatusWriter) Flush() error {
+	w.flushes++
+	return nil
+}
+
+func TestMailMergeSendRows(t *testing.T) {
+	var sent []string
+	failBob := true
+	toRx := regexp.MustCompile(`(?m)^To: (.*)\r?$`)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/messages/send", func(w http.ResponseWriter, r *http.Request) {
+		var msg gmail.Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decode send request error: [%v]", err)
+			return
+		}
+		raw, err := base64.URLEncoding.DecodeString(msg.Raw)
+		if err != nil {
+			t.Errorf("decode raw message error: [%v]", err)
+			return
+		}
+		m := toRx.FindStringSubmatch(string(raw))
+		if len(m) < 2 {
+			t.Errorf("message has no To header: [%s]", raw)
+			return
+		}
+		if failBob && strings.Contains(m[1], "bob@") {
+			http.Error(w, `{"error":{"code":500,"message":"Backend Error"}}`, http.StatusInternalServerError)
+			return
+		}
+		sent = append(sent, m[1])
+		_ = json.NewEncoder(w).Encode(&gmail.Message{Id: "id-" + m[1]})
+	})
+
+	dir := t.TempDir()
+	mm := newTestMailMerge(t, dir, [][]string{
+		{"alice@example.com", "Alice"},
+		{"bob@example.com", "Bob"},
+		{"not an address", "Nobody"},
+		{"carol@example.com", "Carol"},
+	})
+	mm.GmailService = newTestGmailService(t, mux)
+	journal := NewJournal(filepath.Join(dir, "journal.jsonl"))
+	sw := &fakeStatusWriter{}
+
+	// Failed rows are recorded and the merge continues.
+	res, err := mm.SendRows(context.Background(), SendOpts{Journal: journal, StatusWriter: sw})
+	if err != nil {
+		t.Fatalf("SendRows() error: [%v]", err)
+	}
+	if res.Sent != 2 || res.Failed != 2 || res.Skipped != 0 || len(sent) != 2 {
+		t.Errorf("SendRows() first run mismatch: got [%+v], sent (%v)", res, sent)
+	}
+	if r := res.Results[0]; r.Status != RowStatusSent || r.MessageID == "" || r.SentAt.IsZero() {
+		t.Errorf("SendRows() sent row mismatch: got [%+v]", r)
+	}
+	if r := res.Results[1]; r.Status != RowStatusFailed || r.Row != 1 || !strings.Contains(r.Error, "Backend Error") {
+		t.Errorf("SendRows() failed row mismatch: got [%+v]", r)
+	}
+	if len(sw.results) != 4 || sw.flushes != 1 {
+		t.Errorf("StatusWriter mismatch: results (%d) flushes (%d)", len(sw.results), sw.flushes)
+	}
+	if err := res.Err(); err == nil || !strings.Contains(err.Error(), "2 row(s) failed") {
+		t.Errorf("SendResult.Err() mismatch: got [%v]", err)
+	}
+
+	// A rerun skips rows already sent and retries the failures.
+	failBob = false
+	sent = nil
+	res, err = mm.SendRows(context.Background(), SendOpts{Journal: journal})
+	if err != nil {
+		t.Fatalf("SendRows() rerun error: [%v]", err)
+	}
+	if res.Sent != 1 || res.Skipped != 2 || res.Failed != 1 || len(sent) != 1 || !strings.Contains(sent[0], "bob@") {
+		t.Errorf("SendRows() rerun mismatch: got [%+v], sent (%v)", res, sent)
+	}
+
+	// StopOnError stops at the first failure.
+	mm.Table.Rows = append(mm.Table.Rows, []string{"dave@example.com", "Dave"})
+	res, err = mm.SendRows(context.Background(), SendOpts{Journal: journal, StopOnError: true})
+	if err == nil || res.Failed != 1 || res.Sent != 0 {
+		t.Errorf("SendRows() StopOnError mismatch: error [%v] result [%+v]", err, res)
+	}
+}
+
+func TestMailMergeSendRowsStatusColumn(t *testing.T) {
+	var sends int
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/messages/send", func(w http.ResponseWriter, r *http.Request) {
+		sends++
+		_ = json.NewEncoder(w).Encode(&gmail.Message{Id: "id"})
+	})
+	mm := newTestMailMerge(t, t.TempDir(), [][]string{
+		{"alice@example.com", "Alice", "sent"},
+		{"bob@example.com", "Bob", ""},
+	})
+	mm.Table.Columns = append(mm.Table.Columns, ColumnStatus)
+	mm.GmailService = newTestGmailService(t, mux)
+
+	n, err := mm.Send(context.Background(), "")
+	if err != nil || n != 1 || sends != 1 {
+		t.Errorf("Send() mismatch: sent (%d) sends (%d) error [%v]", n, sends, err)
+	}
+}
+
+func TestMailMergeSendRowsDraftOnly(t *testing.T) {
+	var drafts int
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/drafts", func(w http.ResponseWriter, r *http.Request) {
+		drafts++
+		_ = json.NewEncoder(w).Encode(&gmail.Draft{Id: "draft", Message: &gmail.Message{Id: "id"}})
+	})
+	dir := t.TempDir()
+	mm := newTestMailMerge(t, dir, [][]string{
+		{"alice@example.com", "Alice", ""},
+		{"bob@example.com", "Bob", "SENT"},
+		{"carol@example.com", "Carol", ""},
+	})
+	mm.Table.Columns = append(mm.Table.Columns, ColumnStatus)
+	mm.GmailService = newTestGmailService(t, mux)
+	mm.GmailService.DraftOnly = true
+	journal := NewJournal(filepath.Join(dir, "journal.jsonl"))
+	if err := journal.Append(RowResult{Key: rowKey("alice@example.com", "", "", map[string]int{}),
+		To: "alice@example.com", Status: RowStatusSent, MessageID: "sent-alice"}); err != nil {
+		t.Fatalf("Journal.Append() error: [%v]", err)
+	}
+
+	// Rows sent in the journal or the status column are not drafted.
+	res, err := mm.SendRows(context.Background(), SendOpts{Journal: journal})
+	if err != nil {
+		t.Fatalf("SendRows() error: [%v]", err)
+	} else if res.Sent != 1 || res.Skipped != 2 || drafts != 1 || res.Results[2].Status != RowStatusDraft {
+		t.Errorf("SendRows() draft mismatch: got [%+v], drafts (%d)", res, drafts)
+	}
+
+	// A rerun also skips the row already drafted.
+	if res, err = mm.SendRows(context.Background(), SendOpts{Journal: journal}); err != nil {
+		t.Fatalf("SendRows() rerun error: [%v]", err)
+	} else if res.Sent != 0 || res.Skipped != 3 || drafts != 1 {
+		t.Errorf("SendRows() draft rerun mismatch: got [%+v], drafts (%d)", res, drafts)
+	}
+}
+
+func TestRowKey(t *testing.T) {
+	seen := map[string]int{}
+	a := rowKey(" Alice@Example.com ", "", "", seen)
+	b := rowKey("alice@example.com", "", "", seen)
+	c := rowKey("alice@example.com", "bob@example.com", "", seen)
+	if a != "alice@example.com||" || b != "alice@example.com||#2" || c != "alice@example.com|bob@example.com|" {
+		t.Errorf("rowKey() mismatch: got (%s) (%s) (%s)", a, b, c)
+	}
+}
@@ -0,0 +1,102 @@
+package mailmerge
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Iwark/spreadsheet"
+	"github.com/grokify/gogoogle/sheetsutil/iwark"
+)
+
+// DefaultSheetStatusFlushEvery is how many rows `SheetStatusWriter` buffers before
+// writing to the sheet. Batching keeps a large merge within the Sheets API write quota.
+const DefaultSheetStatusFlushEvery = 20
+
+// SheetStatusWriter writes row results to the status and sent-at columns of the Google
+// Sheet the recipients were read from. Missing columns are added after the last column.
+// Failed rows have the status `FAILED: <error>`.
+type SheetStatusWriter struct {
+	Sheet          *spreadsheet.Sheet
+	HeaderRowCount int // sheet rows before the first table row
+	StatusColumn   int // 0-based sheet column index
+	SentAtColumn   int // 0-based sheet column index
+	FlushEvery     int // defaults to `DefaultSheetStatusFlushEvery`
+	pending        int
+}
+
+// NewSheetStatusWriter returns a writer for `mm.Sheet` using the columns named
+// `statusColumn` and `sentAtColumn`, which default to `ColumnStatus` and `ColumnSentAt`.
+// Header cells for missing columns are written with the first flush.
+func (mm *MailMerge) NewSheetStatusWriter(statusColumn, sentAtColumn string) (*SheetStatusWriter, error) {
+	if mm.Sheet == nil {
+		return nil, iwark.ErrSheetCannotBeNil
+	}
+	statusColumn = strings.TrimSpace(statusColumn)
+	if statusColumn == "" {
+		statusColumn = ColumnStatus
+	}
+	sentAtColumn = strings.TrimSpace(sentAtColumn)
+	if sentAtColumn == "" {
+		sentAtColumn = ColumnSentAt
+	}
+	w := &SheetStatusWriter{
+		Sheet:          mm.Sheet,
+		HeaderRowCount: int(max(mm.SheetHeaderRowCount, 1)),
+	}
+	var cols []string
+	if mm.Table != nil {
+		cols = mm.Table.Columns
+	}
+	next := len(cols)
+	for _, c := range []struct {
+		name string
+		idx  *int
+	}{{statusColumn, &w.StatusColumn}, {sentAtColumn, &w.SentAtColumn}} {
+		if *c.idx = columnIndex(cols, c.name); *c.idx < 0 {
+			*c.idx = next
+			next++
+			w.Sheet.Update(0, *c.idx, c.name)
+			w.pending++
+		}
+	}
+	return w, nil
+}
+
+// WriteStatus implements `StatusWriter`. Skipped rows are not written.
+func (w *SheetStatusWriter) WriteStatus(res RowResult) error {
+	status := res.Status
+	switch status {
+	case RowStatusSkipped:
+		return nil
+	case RowStatusFailed:
+		status += ": " + res.Error
+	}
+	sentAt := ""
+	if !res.SentAt.IsZero() {
+		sentAt = res.SentAt.Format(time.RFC3339)
+	}
+	row := w.HeaderRowCount + res.Row
+	w.Sheet.Update(row, w.StatusColumn, status)
+	w.Sheet.Update(row, w.SentAtColumn, sentAt)
+	w.pending++
+	flushEvery := w.FlushEvery
+	if flushEvery <= 0 {
+		flushEvery = DefaultSheetStatusFlushEvery
+	}
+	if w.pending >= flushEvery {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush implements `StatusWriter`.
+func (w *SheetStatusWriter) Flush() error {
+	if w.pending == 0 {
+		return nil
+	}
+	if err := w.Sheet.Synchronize(); err != nil {
+		return err
+	}
+	w.pending = 0
+	return nil
+}
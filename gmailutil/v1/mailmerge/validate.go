@@ -0,0 +1,314 @@
+package mailmerge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cbroglie/mustache"
+	"github.com/grokify/mogo/net/mailutil"
+)
+
+// Validation defaults for `ValidateOpts`. The recipient limits are those of a free Gmail
+// account; Google Workspace accounts can send to 2,000 recipients a day.
+const (
+	DefaultMaxMessageSize          = 25 * 1024 * 1024
+	DefaultMaxRecipientsPerMessage = 500
+	DefaultMaxRecipientsPerDay     = 500
+)
+
+// Issue severities. Errors should be fixed before sending; warnings may be intended.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue codes for `Issue.Code`.
+const (
+	IssueInvalidAddress     = "invalid-address"
+	IssueNoRecipients       = "no-recipients"
+	IssueDuplicateRecipient = "duplicate-recipient"
+	IssueMissingVariable    = "missing-variable"
+	IssueUnusedColumn       = "unused-column"
+	IssueEmptySubject       = "empty-subject"
+	IssueRenderFailed       = "render-failed"
+	IssueMissingFile        = "missing-file"
+	IssueInvalidFile        = "invalid-file"
+	IssueMessageTooLarge    = "message-too-large"
+	IssueRecipientLimit     = "recipient-limit"
+	IssueDailyLimit         = "daily-limit"
+)
+
+// IssueRowAll is `Issue.Row` for issues with the merge as a whole, such as templates.
+const IssueRowAll = -1
+
+// Issue is a problem found by `MailMerge.Validate()`.
+type Issue struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Row      int    `json:"row"` // index in `Table.Rows`, or `IssueRowAll`
+	Column   string `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport lists the issues found by `MailMerge.Validate()`, ordered by row.
+type ValidationReport struct {
+	Rows       int     `json:"rows"`       // rows checked, excluding empty rows
+	Recipients int     `json:"recipients"` // To, Cc and Bcc addresses in all rows
+	Issues     []Issue `json:"issues"`
+}
+
+// ValidateOpts configures `MailMerge.Validate()`. Zero values use the defaults.
+type ValidateOpts struct {
+	MaxMessageSize          int // bytes of the encoded message
+	MaxRecipientsPerMessage int
+	MaxRecipientsPerDay     int
+	// StatusColumn is not reported as unused. Defaults to `ColumnStatus`.
+	StatusColumn string
+	// SentAtColumn is not reported as unused. Defaults to `ColumnSentAt`.
+	SentAtColumn string
+}
+
+// HasErrors reports if any issue has `SeverityError`.
+func (r *ValidationReport) HasErrors() bool {
+	return r.Count(SeverityError) > 0
+}
+
+// Count returns the number of issues with `severity`.
+func (r *ValidationReport) Count(severity string) int {
+	n := 0
+	for _, is := range r.Issues {
+		if is.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *ValidationReport) add(severity, code string, row int, column, format string, a ...any) {
+	r.Issues = append(r.Issues, Issue{
+		Severity: severity,
+		Code:     code,
+		Row:      row,
+		Column:   column,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// Validate checks every row without sending and reports all issues found: invalid and
+// duplicate addresses, template variables without a column, columns not used by any
+// template, empty subjects, missing files, messages over the size limit, and recipient
+// counts over Gmail's sending limits. An error is returned only if the merge cannot be
+// checked, such as when it has no templates.
+func (mm *MailMerge) Validate(ctx context.Context, opts *ValidateOpts) (*ValidationReport, error) {
+	if err := mm.validateMessages(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &ValidateOpts{}
+	}
+	rpt := &ValidationReport{}
+	mm.validateColumns(rpt, opts)
+
+	tmpls, errTmpls := mm.fileTemplates()
+	if errTmpls != nil {
+		rpt.add(SeverityError, IssueRenderFailed, IssueRowAll, "", "%s", errTmpls.Error())
+	}
+	mm.rowFiles = map[int][]RowFile{}
+	seen := map[string]bool{}
+	for i, row := range mm.Table.Rows {
+		if isEmptyRow(row) {
+			continue
+		} else if err := ctx.Err(); err != nil {
+			return rpt, err
+		}
+		rpt.Rows++
+		addrsOK := mm.validateRowAddresses(rpt, opts, i, row, seen)
+		filesOK := true
+		if mm.HasRowFiles() && errTmpls == nil {
+			files, err := mm.resolveRowFiles(ctx, row, tmpls)
+			if err != nil {
+				filesOK = false
+				for _, e := range joinedErrors(err) {
+					code := IssueInvalidFile
+					if errors.Is(e, ErrRowFileNotFound) {
+						code = IssueMissingFile
+					}
+					rpt.add(SeverityError, code, i, "", "%s", e.Error())
+				}
+			}
+			mm.rowFiles[i] = files
+		}
+		if addrsOK && filesOK {
+			mm.validateRowMessage(rpt, opts, i, row)
+		}
+	}
+
+	if limit := orDefault(opts.MaxRecipientsPerDay, DefaultMaxRecipientsPerDay); rpt.Recipients > limit {
+		rpt.add(SeverityWarning, IssueDailyLimit, IssueRowAll, "",
+			"merge has (%d) recipients, over the daily sending limit of (%d); send in batches over several days",
+			rpt.Recipients, limit)
+	}
+	sort.SliceStable(rpt.Issues, func(i, j int) bool { return rpt.Issues[i].Row < rpt.Issues[j].Row })
+	return rpt, nil
+}
+
+// validateColumns reports template variables without a column and columns not used by
+// any template.
+func (mm *MailMerge) validateColumns(rpt *ValidationReport, opts *ValidateOpts) {
+	used := map[string]bool{}
+	var names []string
+	for name := range mm.BodyTemplateSet.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if tmpl := mm.BodyTemplateSet.Templates[name]; tmpl != nil {
+			collectTagNames(tmpl.Tags(), used)
+		}
+	}
+	for _, t := range slices.Concat(mm.InlineFileTemplates, mm.AttachmentFileTemplates) {
+		if tmpl, err := mustache.ParseStringRaw(t, true); err == nil {
+			collectTagNames(tmpl.Tags(), used)
+		}
+	}
+
+	cols := map[string]bool{}
+	for _, col := range mm.Table.Columns {
+		cols[strings.TrimSpace(col)] = true
+	}
+	var missing []string
+	for name := range used {
+		if !cols[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		rpt.add(SeverityError, IssueMissingVariable, IssueRowAll, name,
+			"template variable (%s) has no matching column and renders empty", name)
+	}
+
+	known := []string{ColumnTo, ColumnCc, ColumnBcc, ColumnFrom, ColumnAttachments, ColumnInline,
+		orDefaultString(opts.StatusColumn, ColumnStatus), orDefaultString(opts.SentAtColumn, ColumnSentAt)}
+	for _, col := range mm.Table.Columns {
+		col = strings.TrimSpace(col)
+		if col == "" || used[col] || columnIndex(known, col) >= 0 {
+			continue
+		}
+		rpt.add(SeverityWarning, IssueUnusedColumn, IssueRowAll, col,
+			"column (%s) is not used by any template", col)
+	}
+}
+
+// collectTagNames adds the names of variable and section tags to `names`, including tags
+// within sections. Row values are strings, so names in sections are also columns. For
+// dotted names, only the first name is a column.
+func collectTagNames(tags []mustache.Tag, names map[string]bool) {
+	for _, tag := range tags {
+		switch tag.Type() {
+		case mustache.Variable:
+		case mustache.Section, mustache.InvertedSection:
+			collectTagNames(tag.Tags(), names)
+		default:
+			continue
+		}
+		if name, _, _ := strings.Cut(tag.Name(), "."); name != "" {
+			names[name] = true
+		}
+	}
+}
+
+// validateRowAddresses reports invalid and duplicate addresses and recipient limits for
+// a row. It returns false if the row has an invalid address or no recipients.
+func (mm *MailMerge) validateRowAddresses(rpt *ValidationReport, opts *ValidateOpts, i int, row []string, seen map[string]bool) bool {
+	ok := true
+	count := 0
+	inRow := map[string]bool{}
+	for _, col := range []string{ColumnTo, ColumnCc, ColumnBcc} {
+		val := strings.TrimSpace(mm.Table.Columns.MustCellString(col, row))
+		if val == "" {
+			continue
+		}
+		addrs, err := mailutil.ParseAddressList(val)
+		if err != nil {
+			rpt.add(SeverityError, IssueInvalidAddress, i, col, "invalid address (%s): %s", val, err.Error())
+			ok = false
+			continue
+		} else if len(addrs.FilterInclWithoutAddress()) > 0 {
+			rpt.add(SeverityError, IssueInvalidAddress, i, col, "empty address in (%s)", val)
+			ok = false
+			continue
+		}
+		for _, addr := range addrs.Strings(true, true, false) {
+			count++
+			if inRow[addr] {
+				rpt.add(SeverityWarning, IssueDuplicateRecipient, i, col, "address (%s) is repeated in the row", addr)
+				continue
+			}
+			inRow[addr] = true
+			if seen[addr] {
+				rpt.add(SeverityWarning, IssueDuplicateRecipient, i, col,
+					"address (%s) also receives the message for an earlier row", addr)
+			}
+			seen[addr] = true
+		}
+	}
+	rpt.Recipients += count
+	if count == 0 && ok {
+		rpt.add(SeverityError, IssueNoRecipients, i, ColumnTo, "row has no recipients")
+		return false
+	}
+	if limit := orDefault(opts.MaxRecipientsPerMessage, DefaultMaxRecipientsPerMessage); count > limit {
+		rpt.add(SeverityError, IssueRecipientLimit, i, "",
+			"message has (%d) recipients, over the limit of (%d) per message", count, limit)
+	}
+	return ok
+}
+
+// validateRowMessage renders the message for a row and reports an empty subject or
+// oversized message.
+func (mm *MailMerge) validateRowMessage(rpt *ValidationReport, opts *ValidateOpts, i int, row []string) {
+	msg, err := mm.rowMessage(i, row)
+	if err != nil {
+		rpt.add(SeverityError, IssueRenderFailed, i, "", "%s", err.Error())
+		return
+	}
+	if strings.TrimSpace(msg.Subject) == "" {
+		rpt.add(SeverityWarning, IssueEmptySubject, i, "", "subject is empty")
+	}
+	raw, err := msg.Bytes()
+	if err != nil {
+		rpt.add(SeverityError, IssueRenderFailed, i, "", "%s", err.Error())
+		return
+	}
+	if limit := orDefault(opts.MaxMessageSize, DefaultMaxMessageSize); len(raw) > limit {
+		rpt.add(SeverityError, IssueMessageTooLarge, i, "",
+			"message is (%d) bytes, over the limit of (%d)", len(raw), limit)
+	}
+}
+
+// joinedErrors returns the errors joined in `err` by `errors.Join()`, or `err`.
+func joinedErrors(err error) []error {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+func orDefaultString(v, def string) string {
+	if v = strings.TrimSpace(v); v == "" {
+		return def
+	}
+	return v
+}
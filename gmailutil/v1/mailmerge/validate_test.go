@@ -0,0 +1,76 @@
+package mailmerge
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cbroglie/mustache"
+)
+
+func TestMailMergeValidate(t *testing.T) {
+	dir := t.TempDir()
+	mm := newTestMailMerge(t, dir, [][]string{
+		{"alice@example.com", "Alice", "Hello", "Paris"},
+		{"not an address", "Bob", "Hi", ""},
+		{"Al <Alice@Example.com>", "Al", "", ""},
+		{"", "", "", ""},
+		{"", "Nobody", "Hi", ""},
+	})
+	mm.Table.Columns = append(mm.Table.Columns, "SUBJECT", "CITY", ColumnStatus)
+	for key, tmpl := range map[string]string{
+		templateTypeSubjectText: "{{#NAME}}{{SUBJECT}}{{/NAME}}",
+		templateTypeBodyText:    "Hi {{NAME}}, use {{COUPON}}.",
+	} {
+		var err error
+		if mm.BodyTemplateSet.Templates[key], err = mustache.ParseString(tmpl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rpt, err := mm.Validate(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Validate() error: [%v]", err)
+	}
+	type issue struct {
+		code string
+		row  int
+		col  string
+	}
+	var got []issue
+	for _, is := range rpt.Issues {
+		got = append(got, issue{is.Code, is.Row, is.Column})
+	}
+	want := []issue{
+		{IssueMissingVariable, IssueRowAll, "COUPON"},
+		{IssueUnusedColumn, IssueRowAll, "CITY"},
+		{IssueInvalidAddress, 1, ColumnTo},
+		{IssueDuplicateRecipient, 2, ColumnTo},
+		{IssueEmptySubject, 2, ""},
+		{IssueNoRecipients, 4, ColumnTo},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() issues mismatch:\nwant (%v)\ngot  (%v)", want, got)
+	}
+	if rpt.Rows != 4 || rpt.Recipients != 2 || !rpt.HasErrors() || rpt.Count(SeverityWarning) != 3 {
+		t.Errorf("Validate() report mismatch: rows (%d) recipients (%d) warnings (%d)",
+			rpt.Rows, rpt.Recipients, rpt.Count(SeverityWarning))
+	}
+
+	// Size and recipient limits.
+	mm.Table.Rows = [][]string{{"a@example.com, b@example.com", "A", "Hi", ""}}
+	rpt, err = mm.Validate(context.Background(), &ValidateOpts{
+		MaxMessageSize: 100, MaxRecipientsPerMessage: 1, MaxRecipientsPerDay: 1})
+	if err != nil {
+		t.Fatalf("Validate() error: [%v]", err)
+	}
+	codes := map[string]bool{}
+	for _, is := range rpt.Issues {
+		codes[is.Code] = true
+	}
+	for _, code := range []string{IssueMessageTooLarge, IssueRecipientLimit, IssueDailyLimit} {
+		if !codes[code] {
+			t.Errorf("Validate() limits: missing issue (%s) in (%v)", code, rpt.Issues)
+		}
+	}
+}
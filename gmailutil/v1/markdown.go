@@ -0,0 +1,98 @@
+package gmailutil
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/grokify/gogoogle/gmailutil/v1/markdown"
+	"github.com/grokify/mogo/mime/multipartutil"
+	"github.com/grokify/mogo/net/http/httputilmore"
+)
+
+// MarkdownToHTML renders CommonMark and GitHub Flavored Markdown as an HTML fragment
+// with email-safe inline CSS, suitable for message bodies, vacation responses and
+// signatures. Use the `markdown` package directly to change styles.
+func MarkdownToHTML(md string) string {
+	return markdown.ToHTML(md)
+}
+
+// MarkdownToText renders Markdown as plain text for the `text/plain` alternative of a
+// message.
+func MarkdownToText(md string) string {
+	return markdown.ToText(md)
+}
+
+// NewMarkdownPartsSet returns a `multipart/alternative` body with plain text and HTML
+// parts rendered from Markdown.
+func NewMarkdownPartsSet(md string) multipartutil.PartsSet {
+	return multipartutil.NewPartsSetAlternative(
+		[]byte(MarkdownToText(md)), []byte(htmlDocument(MarkdownToHTML(md))))
+}
+
+// NewMarkdownPartsSetFiles returns a body rendered from Markdown with files. Local image
+// references such as `![logo](./logo.png)` are read relative to `baseDir` and sent as
+// inline parts referenced by `cid:`, and `attachments` are added as attachments. Base
+// filenames must be unique. Without files, the body is the same as `NewMarkdownPartsSet`.
+func NewMarkdownPartsSetFiles(md, baseDir string, attachments []string) (multipartutil.PartsSet, error) {
+	doc := markdown.Parse(md)
+	images, err := markdownLocalImages(doc, baseDir)
+	if err != nil {
+		return multipartutil.PartsSet{}, err
+	} else if err := checkDuplicateFilenames(append(slices.Clone(images), attachments...)); err != nil {
+		return multipartutil.PartsSet{}, err
+	}
+	var parts multipartutil.Parts
+	for _, filename := range images {
+		parts = append(parts, NewFilePart(httputilmore.DispositionTypeInline, filename))
+	}
+	for _, filename := range attachments {
+		if _, err := os.Stat(filename); err != nil {
+			return multipartutil.PartsSet{}, err
+		}
+		parts = append(parts, NewFilePart(httputilmore.DispositionTypeAttachment, filename))
+	}
+	r := markdown.NewRenderer()
+	return multipartutil.NewPartsSetMail(
+		[]byte(r.Text(doc)), []byte(htmlDocument(r.HTML(doc))), parts)
+}
+
+// markdownLocalImages rewrites the sources of images in `doc` which are local files to
+// `cid:` references and returns the files in order of first use.
+func markdownLocalImages(doc *markdown.Node, baseDir string) ([]string, error) {
+	var filenames []string
+	seen := map[string]bool{}
+	var err error
+	markdown.Walk(doc, func(n *markdown.Node) bool {
+		if err != nil {
+			return false
+		} else if n.Kind != markdown.KindImage {
+			return true
+		}
+		u, errParse := url.Parse(n.Destination)
+		if errParse != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+			return true
+		}
+		filename := filepath.FromSlash(u.Path)
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(baseDir, filename)
+		}
+		if !seen[filename] {
+			if _, err = os.Stat(filename); err != nil {
+				return false
+			}
+			seen[filename] = true
+			filenames = append(filenames, filename)
+		}
+		n.Destination = "cid:" + url.PathEscape(filepath.Base(filename))
+		return true
+	})
+	return filenames, err
+}
+
+// htmlDocument wraps an HTML fragment in a document with a UTF-8 charset.
+func htmlDocument(body string) string {
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n</head>\n<body>\n" +
+		body + "</body>\n</html>\n"
+}
@@ -0,0 +1,21 @@
+package markdown
+
+import "testing"
+
+func FuzzToHTML(f *testing.F) {
+	for _, tt := range htmlTests {
+		f.Add(tt.md)
+	}
+	f.Fuzz(func(t *testing.T, md string) {
+		ToHTML(md)
+	})
+}
+
+func FuzzToText(f *testing.F) {
+	for _, tt := range textTests {
+		f.Add(tt.md)
+	}
+	f.Fuzz(func(t *testing.T, md string) {
+		ToText(md)
+	})
+}
@@ -0,0 +1,89 @@
+// Package markdown renders CommonMark and GitHub Flavored Markdown to email-safe HTML
+// with inline CSS and to plain text for the alternative part of a message. Parsing is
+// done by goldmark with its GFM extension.
+//
+// It supports headings, paragraphs, emphasis, strikethrough, links, images, autolinks,
+// code spans and blocks, blockquotes, ordered, bullet and task lists, tables, thematic
+// breaks, link reference definitions and raw HTML.
+package markdown
+
+import "github.com/yuin/goldmark/text"
+
+// NodeKind is the type of a `Node`.
+type NodeKind int
+
+// Block node kinds.
+const (
+	KindDocument NodeKind = iota
+	KindParagraph
+	KindHeading
+	KindThematicBreak
+	KindCodeBlock
+	KindBlockquote
+	KindList
+	KindListItem
+	KindTable
+	KindTableRow
+	KindTableCell
+	KindHTMLBlock
+)
+
+// Inline node kinds.
+const (
+	KindText NodeKind = iota + 100
+	KindSoftBreak
+	KindLineBreak
+	KindEmphasis
+	KindStrong
+	KindStrikethrough
+	KindCode
+	KindLink
+	KindImage
+	KindHTMLInline
+)
+
+// Node is an element of a parsed document.
+type Node struct {
+	Kind        NodeKind
+	Children    []*Node
+	Literal     string // text, code and raw HTML
+	Level       int    // heading level, 1 to 6
+	Info        string // fenced code block info string, e.g. `go`
+	Destination string // link URL or image source
+	Title       string // link or image title
+	Ordered     bool   // ordered list
+	Start       int    // ordered list start number
+	Tight       bool   // list items are not separated by blank lines
+	Task        bool   // list item is a task list item
+	Checked     bool   // task list item is checked
+	Header      bool   // table row is the header row
+	Align       string // table cell alignment: `left`, `center`, `right` or empty
+}
+
+// Walk calls `fn` for `n` and its descendants in document order. If `fn` returns false,
+// the children of that node are skipped.
+func Walk(n *Node, fn func(n *Node) bool) {
+	if n == nil || !fn(n) {
+		return
+	}
+	for _, c := range n.Children {
+		Walk(c, fn)
+	}
+}
+
+// Parse parses `src` into a document.
+func Parse(src string) *Node {
+	c := &converter{src: []byte(src)}
+	root := gfm.Parser().Parse(text.NewReader(c.src))
+	return &Node{Kind: KindDocument, Children: c.blocks(root)}
+}
+
+// ToHTML renders `src` as an HTML fragment with the default inline styles.
+func ToHTML(src string) string {
+	return NewRenderer().HTML(Parse(src))
+}
+
+// ToText renders `src` as plain text.
+func ToText(src string) string {
+	return NewRenderer().Text(Parse(src))
+}
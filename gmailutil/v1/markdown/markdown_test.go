@@ -0,0 +1,103 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+var htmlTests = []struct {
+	name string
+	md   string
+	want string
+}{
+	{"heading", "# Title #\n\nSub\n---", "<h1>Title</h1>\n<h2>Sub</h2>\n"},
+	{"emphasis", "*a* **b** ***c*** ~~d~~ snake_case_word 2*3*4",
+		"<p><em>a</em> <strong>b</strong> <em><strong>c</strong></em> <del>d</del> snake_case_word 2<em>3</em>4</p>\n"},
+	{"nested emphasis", "*foo**bar**baz* **foo*", "<p><em>foo<strong>bar</strong>baz</em> *<em>foo</em></p>\n"},
+	{"breaks", "a  \nb\\\nc\nd", "<p>a<br>\nb<br>\nc\nd</p>\n"},
+	{"escapes and entities", "\\*a\\* &amp; &copy; <b>", "<p>*a* &amp; © <b></p>\n"},
+	{"code span", "`a < b` and `` x ` y ``", "<p><code>a &lt; b</code> and <code>x ` y</code></p>\n"},
+	{"links", "[a](http://x.com \"T\") [b](<c d>) [r] [s][r]\n\n[r]: https://r.com",
+		`<p><a href="http://x.com" title="T">a</a> <a href="c%20d">b</a> <a href="https://r.com">r</a> <a href="https://r.com">s</a></p>` + "\n"},
+	{"unsafe link", "[x](javascript:alert(1))", "<p><a>x</a></p>\n"},
+	{"image", `![alt *text*](img.png "T")`, `<p><img src="img.png" alt="alt text" title="T"></p>` + "\n"},
+	{"nested link", "[a [b](c) d](e)", "<p>[a <a href=\"c\">b</a> d](e)</p>\n"},
+	{"autolinks", "<https://a.com> <me@b.co> www.c.org, https://d.com/x). e@f.io",
+		`<p><a href="https://a.com">https://a.com</a> <a href="mailto:me@b.co">me@b.co</a> <a href="http://www.c.org">www.c.org</a>, <a href="https://d.com/x">https://d.com/x</a>). <a href="mailto:e@f.io">e@f.io</a></p>` + "\n"},
+	{"bare www", "Go to www.!\n\nPrefix www.: see below", "<p>Go to www.!</p>\n<p>Prefix www.: see below</p>\n"},
+	{"tight list", "- a\n- b\n  - c\n- [x] d",
+		"<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul>\n</li>\n<li style=\"list-style-type:none\">&#9745; d</li>\n</ul>\n"},
+	{"loose ordered list", "3. a\n\n4. b", "<ol start=\"3\">\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ol>\n"},
+	{"list interrupt", "Para\n- item\n\nPara\n2. not a list", "<p>Para</p>\n<ul>\n<li>item</li>\n</ul>\n<p>Para\n2. not a list</p>\n"},
+	{"blockquote", "> a\nlazy\n> > b", "<blockquote>\n<p>a\nlazy</p>\n<blockquote>\n<p>b</p>\n</blockquote>\n</blockquote>\n"},
+	{"code blocks", "```go\nx := 1 < 2\n```\n\n    indented", "<pre><code class=\"language-go\">x := 1 &lt; 2\n</code></pre>\n<pre><code>indented\n</code></pre>\n"},
+	{"thematic break", "* * *", "<hr>\n"},
+	{"table", "| a | b |\n|:--|--:|\n| 1 | \\| |",
+		"<table>\n<thead>\n<tr>\n<th style=\"text-align:left\">a</th>\n<th style=\"text-align:right\">b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td style=\"text-align:left\">1</td>\n<td style=\"text-align:right\">|</td>\n</tr>\n</tbody>\n</table>\n"},
+	{"html", "<div>\n*raw*\n</div>\n\n<!-- c -->\ntext <span>x</span>", "<div>\n*raw*\n</div>\n<!-- c -->\n<p>text <span>x</span></p>\n"},
+}
+
+func TestRendererHTML(t *testing.T) {
+	r := &Renderer{}
+	for _, tt := range htmlTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.HTML(Parse(tt.md)); got != tt.want {
+				t.Errorf("Renderer.HTML(%q) mismatch: want\n%s\ngot\n%s", tt.md, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRendererStyles(t *testing.T) {
+	got := ToHTML("# Hi\n\nSee `x`.")
+	for _, want := range []string{
+		`<div style="font-family:`,
+		`<h1 style="margin:24px 0 16px;font-size:2em;`,
+		`<code style="font-family:SFMono-Regular,Consolas,&#39;Liberation Mono&#39;`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ToHTML() want (%s) in\n%s", want, got)
+		}
+	}
+
+	r := (&Renderer{SkipHTML: true}).WithStyles(map[string]string{"p": "margin:0"})
+	if got := r.HTML(Parse("<div>x</div>\n\na <b>b</b>")); got != "<p style=\"margin:0\">a b</p>\n" {
+		t.Errorf("Renderer.HTML() with styles mismatch: got (%s)", got)
+	}
+}
+
+var textTests = []struct {
+	name string
+	md   string
+	want string
+}{
+	{"headings", "# Title\n\n## Sub\n\n### Small", "Title\n=====\n\nSub\n---\n\nSmall\n"},
+	{"inline", "*a* **b** `c` [d](http://e.com) [http://f.com](http://f.com) ![g](g.png) <me@h.io>",
+		"a b c d (http://e.com) http://f.com g me@h.io\n"},
+	{"lists", "- a\n- b\n  - c\n- [ ] d\n\n1. x\n2. y", "- a\n- b\n  - c\n- [ ] d\n\n1. x\n2. y\n"},
+	{"quote and code", "> a\n> b\n\n```\ncode\n```", "> a\n> b\n\n    code\n"},
+	{"table", "| Name | Qty |\n|---|--:|\n| Apple | 3 |\n| Kiwi | 10 |", "Name  | Qty\n------|----\nApple |   3\nKiwi  |  10\n"},
+}
+
+func TestRendererText(t *testing.T) {
+	for _, tt := range textTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToText(tt.md); got != tt.want {
+				t.Errorf("ToText(%q) mismatch: want\n%s\ngot\n%s", tt.md, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWalk(t *testing.T) {
+	var srcs []string
+	Walk(Parse("![a](a.png)\n\n- ![b](b.png) [![c](c.png)](x)"), func(n *Node) bool {
+		if n.Kind == KindImage {
+			srcs = append(srcs, n.Destination)
+		}
+		return true
+	})
+	if got := strings.Join(srcs, ","); got != "a.png,b.png,c.png" {
+		t.Errorf("Walk() images mismatch: want (a.png,b.png,c.png), got (%s)", got)
+	}
+}
@@ -0,0 +1,199 @@
+package markdown
+
+import (
+	"bufio"
+	"bytes"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// gfm parses CommonMark with the GitHub Flavored Markdown extensions: tables,
+// strikethrough, autolinks and task lists.
+var gfm = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// converter converts a goldmark syntax tree to `Node`s.
+type converter struct {
+	src []byte
+}
+
+func (c *converter) blocks(parent ast.Node) []*Node {
+	var nodes []*Node
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		if b := c.block(n); b != nil {
+			nodes = append(nodes, b)
+		}
+	}
+	return nodes
+}
+
+func (c *converter) block(n ast.Node) *Node {
+	switch n := n.(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		return &Node{Kind: KindParagraph, Children: c.inlines(n)}
+	case *ast.Heading:
+		return &Node{Kind: KindHeading, Level: n.Level, Children: c.inlines(n)}
+	case *ast.ThematicBreak:
+		return &Node{Kind: KindThematicBreak}
+	case *ast.CodeBlock:
+		return &Node{Kind: KindCodeBlock, Literal: c.lines(n.Lines())}
+	case *ast.FencedCodeBlock:
+		return &Node{Kind: KindCodeBlock, Literal: c.lines(n.Lines()), Info: c.unescape(n.Language(c.src))}
+	case *ast.Blockquote:
+		return &Node{Kind: KindBlockquote, Children: c.blocks(n)}
+	case *ast.List:
+		list := &Node{Kind: KindList, Ordered: n.IsOrdered(), Start: n.Start, Tight: n.IsTight}
+		for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+			list.Children = append(list.Children, c.listItem(item))
+		}
+		return list
+	case *ast.HTMLBlock:
+		lit := c.lines(n.Lines())
+		if n.HasClosure() {
+			lit += string(n.ClosureLine.Value(c.src))
+		}
+		return &Node{Kind: KindHTMLBlock, Literal: lit}
+	case *east.Table:
+		table := &Node{Kind: KindTable}
+		for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+			_, header := row.(*east.TableHeader)
+			tr := &Node{Kind: KindTableRow, Header: header}
+			for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+				td := &Node{Kind: KindTableCell, Children: c.inlines(cell)}
+				if tc, ok := cell.(*east.TableCell); ok && tc.Alignment != east.AlignNone {
+					td.Align = tc.Alignment.String()
+				}
+				tr.Children = append(tr.Children, td)
+			}
+			table.Children = append(table.Children, tr)
+		}
+		return table
+	}
+	return nil
+}
+
+// listItem converts a list item. A leading task list checkbox is moved from the first
+// paragraph to `Task` and `Checked`.
+func (c *converter) listItem(n ast.Node) *Node {
+	item := &Node{Kind: KindListItem}
+	if first := n.FirstChild(); first != nil {
+		if cb, ok := first.FirstChild().(*east.TaskCheckBox); ok {
+			item.Task, item.Checked = true, cb.IsChecked
+			first.RemoveChild(first, cb)
+		}
+	}
+	item.Children = c.blocks(n)
+	return item
+}
+
+func (c *converter) inlines(parent ast.Node) []*Node {
+	var nodes []*Node
+	add := func(n *Node) {
+		// merge adjacent text, which goldmark splits at delimiters and escapes
+		if last := len(nodes) - 1; n.Kind == KindText && last >= 0 && nodes[last].Kind == KindText {
+			nodes[last].Literal += n.Literal
+		} else if n.Kind != KindText || n.Literal != "" {
+			nodes = append(nodes, n)
+		}
+	}
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		switch n := n.(type) {
+		case *ast.Text:
+			if n.IsRaw() {
+				add(&Node{Kind: KindText, Literal: string(n.Value(c.src))})
+			} else {
+				add(&Node{Kind: KindText, Literal: c.unescape(n.Value(c.src))})
+			}
+			if n.HardLineBreak() {
+				add(&Node{Kind: KindLineBreak})
+			} else if n.SoftLineBreak() {
+				add(&Node{Kind: KindSoftBreak})
+			}
+		case *ast.String:
+			if n.IsCode() || n.IsRaw() {
+				add(&Node{Kind: KindText, Literal: string(n.Value)})
+			} else {
+				add(&Node{Kind: KindText, Literal: c.unescape(n.Value)})
+			}
+		case *ast.CodeSpan:
+			var b bytes.Buffer
+			for t := n.FirstChild(); t != nil; t = t.NextSibling() {
+				switch t := t.(type) {
+				case *ast.Text:
+					// line endings in code spans are rendered as spaces
+					if v, ok := bytes.CutSuffix(t.Value(c.src), []byte("\n")); ok {
+						b.Write(v)
+						b.WriteByte(' ')
+					} else {
+						b.Write(v)
+					}
+				case *ast.String:
+					b.Write(t.Value)
+				}
+			}
+			add(&Node{Kind: KindCode, Literal: b.String()})
+		case *ast.Emphasis:
+			kind := KindEmphasis
+			if n.Level >= 2 {
+				kind = KindStrong
+			}
+			add(&Node{Kind: kind, Children: c.inlines(n)})
+		case *east.Strikethrough:
+			add(&Node{Kind: KindStrikethrough, Children: c.inlines(n)})
+		case *ast.Link:
+			add(&Node{Kind: KindLink, Destination: c.unescape(n.Destination), Title: c.unescape(n.Title),
+				Children: c.inlines(n)})
+		case *ast.Image:
+			add(&Node{Kind: KindImage, Destination: c.unescape(n.Destination), Title: c.unescape(n.Title),
+				Children: c.inlines(n)})
+		case *ast.AutoLink:
+			dest, label := string(n.URL(c.src)), string(n.Label(c.src))
+			if n.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(strings.ToLower(dest), "mailto:") {
+				dest = "mailto:" + dest
+			}
+			// goldmark keeps an unbalanced `)` followed by punctuation, as in `(see https://x.com).`
+			var rest string
+			for strings.HasSuffix(label, ")") && strings.Count(label, "(") < strings.Count(label, ")") {
+				label, dest, rest = label[:len(label)-1], dest[:len(dest)-1], ")"+rest
+			}
+			add(&Node{Kind: KindLink, Destination: dest, Children: []*Node{{Kind: KindText, Literal: label}}})
+			add(&Node{Kind: KindText, Literal: rest})
+		case *ast.RawHTML:
+			add(&Node{Kind: KindHTMLInline, Literal: string(n.Segments.Value(c.src))})
+		default:
+			for _, child := range c.inlines(n) {
+				add(child)
+			}
+		}
+	}
+	return nodes
+}
+
+// lines returns the source of `segs` joined.
+func (c *converter) lines(segs *text.Segments) string {
+	var b bytes.Buffer
+	for i := range segs.Len() {
+		seg := segs.At(i)
+		b.Write(seg.Value(c.src))
+	}
+	return b.String()
+}
+
+// unescape resolves backslash escapes and character references the way goldmark does
+// when it writes HTML, and returns the unescaped text.
+func (c *converter) unescape(v []byte) string {
+	if len(v) == 0 {
+		return ""
+	}
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	gmhtml.DefaultWriter.Write(w, v)
+	_ = w.Flush()
+	return html.UnescapeString(b.String())
+}
@@ -0,0 +1,265 @@
+package markdown
+
+import (
+	"html"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+const (
+	fontSans = `-apple-system,BlinkMacSystemFont,'Segoe UI',Helvetica,Arial,sans-serif`
+	fontMono = `SFMono-Regular,Consolas,'Liberation Mono',Menlo,monospace`
+)
+
+// DefaultStyles returns inline CSS by element name which renders like GitHub in email
+// clients that ignore `<style>` elements. The `body` style is applied to a wrapping
+// `<div>`.
+func DefaultStyles() map[string]string {
+	return map[string]string{
+		"body":       "font-family:" + fontSans + ";font-size:14px;line-height:1.5;color:#1f2328",
+		"h1":         "margin:24px 0 16px;font-size:2em;font-weight:600;line-height:1.25;padding-bottom:.3em;border-bottom:1px solid #d1d9e0",
+		"h2":         "margin:24px 0 16px;font-size:1.5em;font-weight:600;line-height:1.25;padding-bottom:.3em;border-bottom:1px solid #d1d9e0",
+		"h3":         "margin:24px 0 16px;font-size:1.25em;font-weight:600;line-height:1.25",
+		"h4":         "margin:24px 0 16px;font-size:1em;font-weight:600;line-height:1.25",
+		"h5":         "margin:24px 0 16px;font-size:.875em;font-weight:600;line-height:1.25",
+		"h6":         "margin:24px 0 16px;font-size:.85em;font-weight:600;line-height:1.25;color:#59636e",
+		"p":          "margin:0 0 16px",
+		"a":          "color:#0969da;text-decoration:underline",
+		"blockquote": "margin:0 0 16px;padding:0 1em;color:#59636e;border-left:.25em solid #d1d9e0",
+		"ul":         "margin:0 0 16px;padding-left:2em",
+		"ol":         "margin:0 0 16px;padding-left:2em",
+		"li":         "margin:.25em 0",
+		"code":       "font-family:" + fontMono + ";font-size:85%;padding:.2em .4em;background-color:#eff1f3;border-radius:6px",
+		"pre":        "margin:0 0 16px;padding:16px;font-family:" + fontMono + ";font-size:85%;line-height:1.45;background-color:#f6f8fa;border-radius:6px;overflow:auto",
+		"hr":         "height:1px;margin:24px 0;padding:0;border:0;background-color:#d1d9e0",
+		"table":      "margin:0 0 16px;border-collapse:collapse;border-spacing:0",
+		"th":         "padding:6px 13px;border:1px solid #d1d9e0;font-weight:600;background-color:#f6f8fa",
+		"td":         "padding:6px 13px;border:1px solid #d1d9e0",
+		"img":        "max-width:100%;border:0",
+	}
+}
+
+// Renderer renders documents to HTML and plain text.
+type Renderer struct {
+	// Styles is inline CSS by element name, such as `h1`, `code` or `td`. Nil renders
+	// plain HTML without `style` attributes.
+	Styles map[string]string
+	// SkipHTML omits raw HTML from the source instead of passing it through.
+	SkipHTML bool
+}
+
+// NewRenderer returns a `Renderer` with `DefaultStyles()`.
+func NewRenderer() *Renderer {
+	return &Renderer{Styles: DefaultStyles()}
+}
+
+// WithStyles returns a copy of `r` with `styles` merged into its styles.
+func (r *Renderer) WithStyles(styles map[string]string) *Renderer {
+	out := *r
+	out.Styles = maps.Clone(r.Styles)
+	if out.Styles == nil {
+		out.Styles = map[string]string{}
+	}
+	maps.Copy(out.Styles, styles)
+	return &out
+}
+
+// HTML renders `doc` as an HTML fragment.
+func (r *Renderer) HTML(doc *Node) string {
+	var b strings.Builder
+	body := r.Styles["body"]
+	if body != "" {
+		b.WriteString(`<div style="` + html.EscapeString(body) + `">` + "\n")
+	}
+	for _, n := range doc.Children {
+		r.renderBlock(&b, n, false)
+	}
+	if body != "" {
+		b.WriteString("</div>\n")
+	}
+	return b.String()
+}
+
+func (r *Renderer) openTag(b *strings.Builder, tag, extraStyle string, attrs ...string) {
+	b.WriteString("<" + tag)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			b.WriteString(" " + attrs[i] + `="` + html.EscapeString(attrs[i+1]) + `"`)
+		}
+	}
+	style := r.Styles[tag]
+	if extraStyle != "" {
+		style = strings.TrimSuffix(style, ";")
+		if style != "" {
+			style += ";"
+		}
+		style += extraStyle
+	}
+	if style != "" {
+		b.WriteString(` style="` + html.EscapeString(style) + `"`)
+	}
+	b.WriteString(">")
+}
+
+func (r *Renderer) renderBlock(b *strings.Builder, n *Node, tight bool) {
+	switch n.Kind {
+	case KindParagraph:
+		if tight {
+			r.renderInlines(b, n.Children)
+			return
+		}
+		r.openTag(b, "p", "")
+		r.renderInlines(b, n.Children)
+		b.WriteString("</p>\n")
+	case KindHeading:
+		tag := "h" + strconv.Itoa(min(max(n.Level, 1), 6))
+		r.openTag(b, tag, "")
+		r.renderInlines(b, n.Children)
+		b.WriteString("</" + tag + ">\n")
+	case KindThematicBreak:
+		r.openTag(b, "hr", "")
+		b.WriteString("\n")
+	case KindCodeBlock:
+		r.openTag(b, "pre", "")
+		if n.Info != "" {
+			b.WriteString(`<code class="language-` + html.EscapeString(n.Info) + `">`)
+		} else {
+			b.WriteString("<code>")
+		}
+		b.WriteString(html.EscapeString(n.Literal))
+		b.WriteString("</code></pre>\n")
+	case KindBlockquote:
+		r.openTag(b, "blockquote", "")
+		b.WriteString("\n")
+		for _, c := range n.Children {
+			r.renderBlock(b, c, false)
+		}
+		b.WriteString("</blockquote>\n")
+	case KindList:
+		tag := "ul"
+		var attrs []string
+		if n.Ordered {
+			tag = "ol"
+			if n.Start != 1 {
+				attrs = []string{"start", strconv.Itoa(n.Start)}
+			}
+		}
+		r.openTag(b, tag, "", attrs...)
+		b.WriteString("\n")
+		for _, item := range n.Children {
+			r.renderListItem(b, item, n.Tight)
+		}
+		b.WriteString("</" + tag + ">\n")
+	case KindTable:
+		r.renderTable(b, n)
+	case KindHTMLBlock:
+		if !r.SkipHTML {
+			b.WriteString(n.Literal)
+		}
+	}
+}
+
+func (r *Renderer) renderListItem(b *strings.Builder, item *Node, tight bool) {
+	extra := ""
+	if item.Task {
+		extra = "list-style-type:none"
+	}
+	r.openTag(b, "li", extra)
+	if item.Task {
+		if item.Checked {
+			b.WriteString("&#9745; ")
+		} else {
+			b.WriteString("&#9744; ")
+		}
+	}
+	for i, c := range item.Children {
+		if tight && c.Kind == KindParagraph {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			r.renderBlock(b, c, true)
+			continue
+		}
+		if i == 0 || (tight && item.Children[i-1].Kind == KindParagraph) {
+			b.WriteString("\n")
+		}
+		r.renderBlock(b, c, false)
+	}
+	b.WriteString("</li>\n")
+}
+
+func (r *Renderer) renderTable(b *strings.Builder, n *Node) {
+	r.openTag(b, "table", "")
+	b.WriteString("\n")
+	for i, row := range n.Children {
+		tag := "td"
+		if row.Header {
+			tag = "th"
+			b.WriteString("<thead>\n")
+		} else if i == 1 {
+			b.WriteString("<tbody>\n")
+		}
+		b.WriteString("<tr>\n")
+		for _, cell := range row.Children {
+			extra := ""
+			if cell.Align != "" {
+				extra = "text-align:" + cell.Align
+			}
+			r.openTag(b, tag, extra)
+			r.renderInlines(b, cell.Children)
+			b.WriteString("</" + tag + ">\n")
+		}
+		b.WriteString("</tr>\n")
+		if row.Header {
+			b.WriteString("</thead>\n")
+		}
+	}
+	if len(n.Children) > 1 {
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n")
+}
+
+func (r *Renderer) renderInlines(b *strings.Builder, nodes []*Node) {
+	for _, n := range nodes {
+		switch n.Kind {
+		case KindText:
+			b.WriteString(html.EscapeString(n.Literal))
+		case KindSoftBreak:
+			b.WriteString("\n")
+		case KindLineBreak:
+			b.WriteString("<br>\n")
+		case KindEmphasis, KindStrong, KindStrikethrough:
+			tag := map[NodeKind]string{KindEmphasis: "em", KindStrong: "strong", KindStrikethrough: "del"}[n.Kind]
+			r.openTag(b, tag, "")
+			r.renderInlines(b, n.Children)
+			b.WriteString("</" + tag + ">")
+		case KindCode:
+			r.openTag(b, "code", "")
+			b.WriteString(html.EscapeString(n.Literal))
+			b.WriteString("</code>")
+		case KindLink:
+			r.openTag(b, "a", "", "href", safeURL(n.Destination), "title", n.Title)
+			r.renderInlines(b, n.Children)
+			b.WriteString("</a>")
+		case KindImage:
+			r.openTag(b, "img", "", "src", safeURL(n.Destination), "alt", inlineText(n.Children), "title", n.Title)
+		case KindHTMLInline:
+			if !r.SkipHTML {
+				b.WriteString(n.Literal)
+			}
+		}
+	}
+}
+
+// safeURL returns `u` with spaces encoded, or empty if it uses a scheme which runs script.
+func safeURL(u string) string {
+	lower := strings.ToLower(strings.TrimSpace(u))
+	for _, scheme := range []string{"javascript:", "vbscript:", "data:"} {
+		if strings.HasPrefix(lower, scheme) && !strings.HasPrefix(lower, "data:image/") {
+			return ""
+		}
+	}
+	return strings.ReplaceAll(u, " ", "%20")
+}
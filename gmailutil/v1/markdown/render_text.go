@@ -0,0 +1,170 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Text renders `doc` as plain text for the `text/plain` alternative of a message. Markup
+// is removed, link URLs follow their text in parentheses, headings are underlined and
+// lists, quotes, code and tables keep their layout.
+func (r *Renderer) Text(doc *Node) string {
+	text := strings.Join(textBlocks(doc.Children), "\n\n")
+	if text = strings.Trim(text, "\n"); strings.TrimSpace(text) == "" {
+		return ""
+	}
+	return text + "\n"
+}
+
+func textBlocks(nodes []*Node) []string {
+	var blocks []string
+	for _, n := range nodes {
+		if s := textBlock(n); s != "" {
+			blocks = append(blocks, s)
+		}
+	}
+	return blocks
+}
+
+func textBlock(n *Node) string {
+	switch n.Kind {
+	case KindParagraph:
+		return inlineText(n.Children)
+	case KindHeading:
+		text := inlineText(n.Children)
+		switch n.Level {
+		case 1:
+			return text + "\n" + strings.Repeat("=", textWidth(text))
+		case 2:
+			return text + "\n" + strings.Repeat("-", textWidth(text))
+		}
+		return text
+	case KindThematicBreak:
+		return strings.Repeat("-", 40)
+	case KindCodeBlock:
+		return prefixLines(strings.TrimSuffix(n.Literal, "\n"), "    ", "")
+	case KindBlockquote:
+		return prefixLines(strings.Join(textBlocks(n.Children), "\n\n"), "> ", ">")
+	case KindList:
+		sep := "\n\n"
+		if n.Tight {
+			sep = "\n"
+		}
+		var items []string
+		for i, item := range n.Children {
+			marker := "- "
+			if n.Ordered {
+				marker = fmt.Sprintf("%d. ", n.Start+i)
+			}
+			if item.Task {
+				if item.Checked {
+					marker += "[x] "
+				} else {
+					marker += "[ ] "
+				}
+			}
+			text := prefixLines(strings.Join(textBlocks(item.Children), sep), strings.Repeat(" ", len(marker)), "")
+			items = append(items, marker+strings.TrimLeft(text, " "))
+		}
+		return strings.Join(items, sep)
+	case KindTable:
+		return textTable(n)
+	case KindHTMLBlock:
+		return strings.TrimSpace(stripTags(n.Literal))
+	}
+	return ""
+}
+
+func textTable(n *Node) string {
+	var rows [][]string
+	var widths []int
+	for _, row := range n.Children {
+		var cells []string
+		for i, cell := range row.Children {
+			text := strings.ReplaceAll(inlineText(cell.Children), "\n", " ")
+			cells = append(cells, text)
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], textWidth(text))
+		}
+		rows = append(rows, cells)
+	}
+	var lines []string
+	for i, cells := range rows {
+		for k, c := range cells {
+			pad := strings.Repeat(" ", widths[k]-textWidth(c))
+			if k < len(n.Children[i].Children) && n.Children[i].Children[k].Align == "right" {
+				cells[k] = pad + c
+			} else {
+				cells[k] = c + pad
+			}
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, " | "), " "))
+		if i == 0 && n.Children[0].Header {
+			var rule []string
+			for _, w := range widths {
+				rule = append(rule, strings.Repeat("-", max(w, 1)))
+			}
+			lines = append(lines, strings.Join(rule, "-|-"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlineText returns the text of inline nodes without markup.
+func inlineText(nodes []*Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n.Kind {
+		case KindText, KindCode:
+			b.WriteString(n.Literal)
+		case KindSoftBreak, KindLineBreak:
+			b.WriteString("\n")
+		case KindEmphasis, KindStrong, KindStrikethrough, KindImage:
+			b.WriteString(inlineText(n.Children))
+		case KindLink:
+			text := inlineText(n.Children)
+			b.WriteString(text)
+			if dest := n.Destination; dest != "" && dest != text && strings.TrimPrefix(dest, "mailto:") != text &&
+				strings.TrimPrefix(dest, "http://") != text {
+				b.WriteString(" (" + dest + ")")
+			}
+		}
+	}
+	return b.String()
+}
+
+func prefixLines(s, prefix, blankPrefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = blankPrefix
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func stripTags(s string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return html.UnescapeString(b.String())
+}
+
+func textWidth(s string) int {
+	return utf8.RuneCountInString(s)
+}
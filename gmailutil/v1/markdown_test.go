@@ -0,0 +1,68 @@
+package gmailutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grokify/mogo/net/http/httputilmore"
+)
+
+func TestNewMarkdownPartsSetFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"logo.png", "report.pdf"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	md := "# News\n\n![Logo](./logo.png) ![Again](logo.png) ![Remote](https://example.com/x.png)\n"
+
+	ps, err := NewMarkdownPartsSetFiles(md, dir, []string{filepath.Join(dir, "report.pdf")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.ContentType != httputilmore.ContentTypeMultipartMixed {
+		t.Errorf("ContentType = %q, want %q", ps.ContentType, httputilmore.ContentTypeMultipartMixed)
+	}
+	if len(ps.Parts) != 3 {
+		t.Fatalf("len(Parts) = %d, want 3 (body, inline image, attachment)", len(ps.Parts))
+	}
+	if got := ps.Parts[1].DispositionType; got != httputilmore.DispositionTypeInline {
+		t.Errorf("image DispositionType = %q, want inline", got)
+	}
+	if got := ps.Parts[2].DispositionType; got != httputilmore.DispositionTypeAttachment {
+		t.Errorf("attachment DispositionType = %q, want attachment", got)
+	}
+
+	_, body, err := ps.Strings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`src="cid:logo.png"`,
+		`src="https://example.com/x.png"`,
+		"Content-Id: <logo.png>",
+		"attachment; filename=report.pdf",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q", want)
+		}
+	}
+
+	// Without files the body is multipart/alternative.
+	ps, err = NewMarkdownPartsSetFiles("Hello", dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(ps.Parts) != 2 {
+		t.Errorf("len(Parts) = %d, want 2 (text, html)", len(ps.Parts))
+	}
+
+	if _, err := NewMarkdownPartsSetFiles("![x](missing.png)", dir, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing image error = %v, want os.ErrNotExist", err)
+	}
+	if _, err := NewMarkdownPartsSetFiles("![x](logo.png)", dir, []string{"other/logo.png"}); !errors.Is(err, ErrDuplicateFilename) {
+		t.Errorf("duplicate filename error = %v, want ErrDuplicateFilename", err)
+	}
+}
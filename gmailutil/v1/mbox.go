@@ -0,0 +1,95 @@
+package gmailutil
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"time"
+)
+
+// MboxFromLineDateFormat is the `asctime` date format used in mbox `From ` lines.
+const MboxFromLineDateFormat = "Mon Jan _2 15:04:05 2006"
+
+// MboxWriter writes messages in the mboxrd format: each message is preceded by a `From `
+// line, lines matching `^>*From ` are quoted with an additional `>`, line endings are
+// converted to LF and messages are separated by a blank line.
+type MboxWriter struct {
+	w io.Writer
+}
+
+// NewMboxWriter returns a `MboxWriter` which appends messages to `w`.
+func NewMboxWriter(w io.Writer) *MboxWriter {
+	return &MboxWriter{w: w}
+}
+
+// WriteMessage writes the RFC 822 message `raw` with a `From ` line for the envelope
+// sender `from`, which defaults to `MAILER-DAEMON`, and `date`. It returns the number of
+// bytes written.
+func (mw *MboxWriter) WriteMessage(from string, date time.Time, raw []byte) (int64, error) {
+	if from = strings.TrimSpace(from); from == "" || strings.ContainsAny(from, " \t\r\n") {
+		from = "MAILER-DAEMON"
+	}
+	if date.IsZero() {
+		date = time.Unix(0, 0)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("From " + from + " " + date.UTC().Format(MboxFromLineDateFormat) + "\n")
+
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	sc.Buffer(make([]byte, 0, 64*1024), len(raw)+1)
+	for sc.Scan() {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if isMboxFromLine(line) {
+			buf.WriteByte('>')
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	buf.WriteByte('\n')
+	n, err := mw.w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// isMboxFromLine reports if `line` needs mboxrd quoting.
+func isMboxFromLine(line string) bool {
+	return strings.HasPrefix(strings.TrimLeft(line, ">"), "From ")
+}
+
+// ReadMboxMessages splits an mboxrd file into its messages, removing the `From ` lines
+// and one level of `>` quoting. Line endings are LF.
+func ReadMboxMessages(r io.Reader) ([][]byte, error) {
+	var msgs [][]byte
+	var cur *bytes.Buffer
+	flush := func() {
+		if cur != nil {
+			// drop the blank separator line
+			msgs = append(msgs, bytes.TrimSuffix(cur.Bytes(), []byte("\n")))
+		}
+	}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			text := strings.TrimSuffix(line, "\n")
+			if strings.HasPrefix(text, "From ") {
+				flush()
+				cur = &bytes.Buffer{}
+			} else if cur != nil {
+				if strings.HasPrefix(text, ">") && isMboxFromLine(text) {
+					text = text[1:]
+				}
+				cur.WriteString(text + "\n")
+			}
+		}
+		if err == io.EOF {
+			flush()
+			return msgs, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
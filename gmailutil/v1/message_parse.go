@@ -0,0 +1,290 @@
+package gmailutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/grokify/mogo/net/http/httputilmore"
+	"github.com/grokify/mogo/net/mailutil"
+	"golang.org/x/text/encoding/htmlindex"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+const (
+	HeaderDate       = "Date"
+	HeaderInReplyTo  = "In-Reply-To"
+	HeaderReferences = "References"
+	HeaderReplyTo    = "Reply-To"
+)
+
+var ErrMessagePayloadCannotBeEmpty = errors.New("gmail message payload and raw cannot both be empty")
+
+// ParsedMessage is a Gmail message with decoded headers, bodies and a flattened
+// attachment list. It is produced from either API shape by `ParseMessage()` or
+// from RFC 822 bytes by `ParseMessageRaw()`.
+type ParsedMessage struct {
+	ID           string
+	ThreadID     string
+	LabelIDs     []string
+	Snippet      string
+	SizeEstimate int64
+	InternalDate time.Time
+	Header       textproto.MIMEHeader // raw header values, not MIME word decoded
+	From         *mail.Address
+	To           mailutil.Addresses
+	Cc           mailutil.Addresses
+	Bcc          mailutil.Addresses
+	ReplyTo      mailutil.Addresses
+	Subject      string
+	Date         time.Time
+	MessageID    string
+	InReplyTo    string
+	References   []string
+	BodyText     string // first `text/plain` body part, converted to UTF-8
+	BodyHTML     string // first `text/html` body part, converted to UTF-8
+	Attachments  []ParsedAttachment
+	// HeaderErrors holds errors for address headers that could not be parsed, keyed by
+	// header name. The field is left empty and the raw value remains in `Header`.
+	HeaderErrors map[string]error
+	// PartErrors holds errors for body parts that could not be converted to UTF-8, such
+	// as an unknown charset, keyed by part ID. The body keeps the raw bytes with invalid
+	// UTF-8 replaced by U+FFFD.
+	PartErrors map[string]error
+}
+
+// ParsedAttachment is a non-body message part, including inline images.
+type ParsedAttachment struct {
+	PartID       string
+	Filename     string
+	MIMEType     string
+	Size         int64
+	AttachmentID string // Gmail attachment ID for `users.messages.attachments.get`, if the data is not included
+	ContentID    string // without angle brackets
+	Inline       bool
+	Data         []byte // decoded data, if included in the message
+}
+
+// ParseMessage parses a message retrieved with `MessageFormatFull`, `MessageFormatMetadata`
+// or `MessageFormatRaw`.
+func ParseMessage(msg *gmail.Message) (*ParsedMessage, error) {
+	if msg == nil {
+		return nil, errors.New("gmail message cannot be nil")
+	}
+	var pm *ParsedMessage
+	if msg.Raw != "" {
+		raw, err := decodeBase64URL(msg.Raw)
+		if err != nil {
+			return nil, err
+		}
+		if pm, err = ParseMessageRaw(raw); err != nil {
+			return nil, err
+		}
+	} else if msg.Payload != nil {
+		pm = &ParsedMessage{Header: messagePartHeader(msg.Payload)}
+		pm.parseHeader()
+		if err := pm.parseMessagePart(msg.Payload); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, ErrMessagePayloadCannotBeEmpty
+	}
+	pm.ID = msg.Id
+	pm.ThreadID = msg.ThreadId
+	pm.LabelIDs = msg.LabelIds
+	pm.Snippet = msg.Snippet
+	pm.SizeEstimate = msg.SizeEstimate
+	if msg.InternalDate > 0 {
+		pm.InternalDate = time.UnixMilli(msg.InternalDate)
+	}
+	return pm, nil
+}
+
+func messagePartHeader(part *gmail.MessagePart) textproto.MIMEHeader {
+	h := textproto.MIMEHeader{}
+	for _, ph := range part.Headers {
+		if ph != nil {
+			h.Add(ph.Name, ph.Value)
+		}
+	}
+	return h
+}
+
+// parseHeader populates the typed header fields from `pm.Header`. A malformed address
+// header is recorded in `pm.HeaderErrors` and does not fail the message.
+func (pm *ParsedMessage) parseHeader() {
+	dec := newWordDecoder()
+	ap := mail.AddressParser{WordDecoder: dec}
+	parseList := func(key string) mailutil.Addresses {
+		v := strings.TrimSpace(pm.Header.Get(key))
+		if v == "" {
+			return nil
+		}
+		addrs, err := ap.ParseList(v)
+		if err != nil {
+			if pm.HeaderErrors == nil {
+				pm.HeaderErrors = map[string]error{}
+			}
+			pm.HeaderErrors[key] = fmt.Errorf("header (%s): %w", key, err)
+			return nil
+		}
+		out := mailutil.Addresses{}
+		for _, addr := range addrs {
+			out = append(out, *addr)
+		}
+		return out
+	}
+
+	if from := parseList(mailutil.HeaderFrom); len(from) > 0 {
+		pm.From = &from[0]
+	}
+	pm.To = parseList(mailutil.HeaderTo)
+	pm.Cc = parseList(mailutil.HeaderCc)
+	pm.Bcc = parseList(mailutil.HeaderBcc)
+	pm.ReplyTo = parseList(HeaderReplyTo)
+	if subject, err := dec.DecodeHeader(pm.Header.Get(mailutil.HeaderSubject)); err != nil {
+		pm.Subject = pm.Header.Get(mailutil.HeaderSubject)
+	} else {
+		pm.Subject = subject
+	}
+	if v := strings.TrimSpace(pm.Header.Get(HeaderDate)); v != "" {
+		if dt, err := mail.ParseDate(v); err == nil {
+			pm.Date = dt
+		}
+	}
+	pm.MessageID = strings.TrimSpace(pm.Header.Get(mailutil.HeaderMessageID))
+	pm.InReplyTo = strings.TrimSpace(pm.Header.Get(HeaderInReplyTo))
+	pm.References = strings.Fields(pm.Header.Get(HeaderReferences))
+}
+
+func (pm *ParsedMessage) parseMessagePart(part *gmail.MessagePart) error {
+	if part == nil {
+		return nil
+	}
+	if len(part.Parts) > 0 {
+		for _, child := range part.Parts {
+			if err := pm.parseMessagePart(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	header := messagePartHeader(part)
+	var data []byte
+	var size int64
+	attachmentID := ""
+	if part.Body != nil {
+		size = part.Body.Size
+		attachmentID = part.Body.AttachmentId
+		if part.Body.Data != "" {
+			var err error
+			if data, err = decodeBase64URL(part.Body.Data); err != nil {
+				return fmt.Errorf("part (%s): %w", part.PartId, err)
+			}
+		}
+	}
+	return pm.addPart(part.PartId, part.MimeType, part.Filename, header, data, size, attachmentID)
+}
+
+// addPart adds a leaf part as the text or HTML body or as an attachment. `data` has
+// any Content-Transfer-Encoding already removed.
+func (pm *ParsedMessage) addPart(partID, mimeType, filename string, header textproto.MIMEHeader, data []byte, size int64, attachmentID string) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get(httputilmore.HeaderContentType))
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(mimeType))
+		params = map[string]string{}
+	} else if mimeType == "" {
+		mimeType = mediaType
+	}
+	disposition, dispParams, err := mime.ParseMediaType(header.Get(httputilmore.HeaderContentDisposition))
+	if err != nil {
+		disposition = ""
+		dispParams = map[string]string{}
+	}
+	if filename == "" {
+		if filename = dispParams["filename"]; filename == "" {
+			filename = params["name"]
+		}
+	}
+	contentID := strings.Trim(strings.TrimSpace(header.Get(httputilmore.HeaderContentID)), "<>")
+
+	isBody := filename == "" && attachmentID == "" && disposition != httputilmore.DispositionTypeAttachment
+	if isBody && mediaType == httputilmore.ContentTypeTextPlain && pm.BodyText == "" {
+		pm.BodyText = pm.decodeBody(partID, data, params["charset"])
+		return nil
+	} else if isBody && mediaType == httputilmore.ContentTypeTextHTML && pm.BodyHTML == "" {
+		pm.BodyHTML = pm.decodeBody(partID, data, params["charset"])
+		return nil
+	} else if isBody && strings.HasPrefix(mediaType, "text/") && contentID == "" {
+		// Additional text parts, such as a second `text/plain` part, are not attachments.
+		return nil
+	}
+
+	if size == 0 {
+		size = int64(len(data))
+	}
+	pm.Attachments = append(pm.Attachments, ParsedAttachment{
+		PartID:       partID,
+		Filename:     filename,
+		MIMEType:     mimeType,
+		Size:         size,
+		AttachmentID: attachmentID,
+		ContentID:    contentID,
+		Inline:       disposition == httputilmore.DispositionTypeInline || (disposition == "" && contentID != ""),
+		Data:         data,
+	})
+	return nil
+}
+
+// decodeBody converts body part `data` in `charset` to UTF-8. If it cannot be converted,
+// the error is recorded in `pm.PartErrors` and the raw bytes are returned as valid UTF-8.
+func (pm *ParsedMessage) decodeBody(partID string, data []byte, charset string) string {
+	text, err := decodeCharset(data, charset)
+	if err != nil {
+		if pm.PartErrors == nil {
+			pm.PartErrors = map[string]error{}
+		}
+		pm.PartErrors[partID] = fmt.Errorf("part (%s): %w", partID, err)
+		return strings.ToValidUTF8(string(data), "\uFFFD")
+	}
+	return text
+}
+
+// decodeBase64URL decodes Gmail API base64url data which may or may not be padded.
+func decodeBase64URL(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// decodeCharset converts `data` in `charset` to a UTF-8 string.
+func decodeCharset(data []byte, charset string) (string, error) {
+	r, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	out, err := io.ReadAll(r)
+	return string(out), err
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset (%s): %w", charset, err)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+func newWordDecoder() *mime.WordDecoder {
+	return &mime.WordDecoder{CharsetReader: charsetReader}
+}
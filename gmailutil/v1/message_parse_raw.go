@@ -0,0 +1,89 @@
+package gmailutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/grokify/mogo/net/http/httputilmore"
+)
+
+// ParseMessageRaw parses RFC 822 message bytes, such as a `.eml` file or the decoded `Raw`
+// field of a message retrieved with `MessageFormatRaw`. Gmail-specific fields such as `ID`
+// and `LabelIDs` are not populated.
+func ParseMessageRaw(raw []byte) (*ParsedMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	pm := &ParsedMessage{
+		Header:       textproto.MIMEHeader(msg.Header),
+		SizeEstimate: int64(len(raw))}
+	pm.parseHeader()
+	if err := pm.parseMIMEPart("", pm.Header, msg.Body); err != nil {
+		return nil, err
+	}
+	return pm, nil
+}
+
+// parseMIMEPart walks a MIME entity. Part IDs follow Gmail numbering where the root is
+// the empty string and children are numbered `0`, `1`, `1.0`, etc.
+func (pm *ParsedMessage) parseMIMEPart(partID string, header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get(httputilmore.HeaderContentType))
+	if err != nil {
+		mediaType = httputilmore.ContentTypeTextPlain
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for i := 0; ; i++ {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("part (%s): %w", partID, err)
+			}
+			childID := strconv.Itoa(i)
+			if partID != "" {
+				childID = partID + "." + childID
+			}
+			if err := pm.parseMIMEPart(childID, part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+	data, err := io.ReadAll(transferDecoder(header, body))
+	if err != nil {
+		return fmt.Errorf("part (%s): %w", partID, err)
+	}
+	filename := ""
+	if _, dispParams, err := mime.ParseMediaType(header.Get(httputilmore.HeaderContentDisposition)); err == nil {
+		filename = dispParams["filename"]
+	}
+	if filename == "" {
+		filename = params["name"]
+	}
+	if decoded, err := newWordDecoder().DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+	return pm.addPart(partID, mediaType, filename, header, data, 0, "")
+}
+
+// transferDecoder returns a reader which removes the part's Content-Transfer-Encoding.
+func transferDecoder(header textproto.MIMEHeader, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(header.Get(httputilmore.HeaderContentTransferEncoding))) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
@@ -0,0 +1,163 @@
+package gmailutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+func readTestMessageFull(t *testing.T) *gmail.Message {
+	t.Helper()
+	data, err := os.ReadFile("testdata/message_full.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &gmail.Message{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func readTestMessageRaw(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/message_multipart.eml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkParsedMessageCommon checks the fields which are the same for the API and raw shapes.
+func checkParsedMessageCommon(t *testing.T, name string, pm *ParsedMessage) {
+	t.Helper()
+	if pm.From == nil || pm.From.Name != "René Dupont" || pm.From.Address != "rene@example.com" {
+		t.Errorf("%s: From mismatch: got [%v]", name, pm.From)
+	}
+	if len(pm.To) != 2 || pm.To[0].Name != "Jürgen Müller" || pm.To[1].Address != "bob@example.com" {
+		t.Errorf("%s: To name mismatch: got [%v]", name, pm.To)
+	}
+	if len(pm.Cc) != 1 || pm.Cc[0].Address != "carol@example.com" {
+		t.Errorf("%s: Cc mismatch: got [%v]", name, pm.Cc)
+	}
+	if pm.Subject != "Übersicht Q3" {
+		t.Errorf("%s: Subject mismatch: want [%s] got [%s]", name, "Übersicht Q3", pm.Subject)
+	}
+	if want := time.Date(2024, 10, 15, 7, 30, 0, 0, time.UTC); !pm.Date.Equal(want) {
+		t.Errorf("%s: Date mismatch: want [%v] got [%v]", name, want, pm.Date)
+	}
+	if pm.MessageID != "<msg-3@example.com>" || pm.InReplyTo != "<msg-2@example.com>" {
+		t.Errorf("%s: Message-ID/In-Reply-To mismatch: got [%s] [%s]", name, pm.MessageID, pm.InReplyTo)
+	}
+	if strings.Join(pm.References, " ") != "<msg-1@example.com> <msg-2@example.com>" {
+		t.Errorf("%s: References mismatch: got [%v]", name, pm.References)
+	}
+	if want := "Hallo Jürgen,\r\n\r\nDie Übersicht ist angehängt.\r\n"; pm.BodyText != want {
+		t.Errorf("%s: BodyText mismatch: want [%q] got [%q]", name, want, pm.BodyText)
+	}
+	if want := `<p>Hallo Jürgen,</p><p>Die Übersicht ist angehängt. ✓</p><img src="cid:logo@example.com">`; pm.BodyHTML != want {
+		t.Errorf("%s: BodyHTML mismatch: want [%q] got [%q]", name, want, pm.BodyHTML)
+	}
+	if len(pm.Attachments) != 2 {
+		t.Fatalf("%s: attachment count mismatch: want [2] got [%d]", name, len(pm.Attachments))
+	}
+	logo, report := pm.Attachments[0], pm.Attachments[1]
+	if logo.PartID != "0.1" || logo.Filename != "logo.png" || logo.MIMEType != "image/png" ||
+		logo.Size != 70 || logo.ContentID != "logo@example.com" || !logo.Inline {
+		t.Errorf("%s: inline attachment mismatch: got [%+v]", name, logo)
+	}
+	if report.PartID != "1" || report.Filename != "report.pdf" || report.MIMEType != "application/pdf" ||
+		report.Size != 36 || report.Inline {
+		t.Errorf("%s: attachment mismatch: got [%+v]", name, report)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	pm, err := ParseMessage(readTestMessageFull(t))
+	if err != nil {
+		t.Fatalf("ParseMessage() error: [%v]", err)
+	}
+	checkParsedMessageCommon(t, "ParseMessage()", pm)
+	if pm.ID != "18f0a1b2c3d4e5f6" || pm.ThreadID != "18f0a1b2c3d4e000" {
+		t.Errorf("ParseMessage(): ID mismatch: got [%s] [%s]", pm.ID, pm.ThreadID)
+	}
+	if strings.Join(pm.LabelIDs, ",") != "INBOX,UNREAD,Label_12" {
+		t.Errorf("ParseMessage(): LabelIDs mismatch: got [%v]", pm.LabelIDs)
+	}
+	if pm.Attachments[1].AttachmentID != "ANGjdJ_report" || len(pm.Attachments[1].Data) != 0 {
+		t.Errorf("ParseMessage(): AttachmentID mismatch: got [%s]", pm.Attachments[1].AttachmentID)
+	}
+}
+
+func TestParseMessageRaw(t *testing.T) {
+	pm, err := ParseMessageRaw(readTestMessageRaw(t))
+	if err != nil {
+		t.Fatalf("ParseMessageRaw() error: [%v]", err)
+	}
+	checkParsedMessageCommon(t, "ParseMessageRaw()", pm)
+	if !strings.HasPrefix(string(pm.Attachments[1].Data), "%PDF-1.4") {
+		t.Errorf("ParseMessageRaw(): attachment data mismatch: got [%q]", pm.Attachments[1].Data)
+	}
+}
+
+func TestParseMessageRawFormat(t *testing.T) {
+	raw := readTestMessageRaw(t)
+	full := readTestMessageFull(t)
+	pm, err := ParseMessage(&gmail.Message{
+		Id:       full.Id,
+		LabelIds: full.LabelIds,
+		Raw:      base64.URLEncoding.EncodeToString(raw)})
+	if err != nil {
+		t.Fatalf("ParseMessage(raw) error: [%v]", err)
+	}
+	checkParsedMessageCommon(t, "ParseMessage(raw)", pm)
+	if pm.ID != full.Id || len(pm.LabelIDs) != 3 {
+		t.Errorf("ParseMessage(raw): Gmail fields not populated")
+	}
+}
+
+func TestParseMessageMalformedHeader(t *testing.T) {
+	raw := "From: alice@example.com\r\nTo: bob@example.com\r\nCc: carol@example.com, <not closed\r\n" +
+		"Subject: Hello\r\n\r\nHi Bob\r\n"
+	pm, err := ParseMessageRaw([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseMessageRaw() error: [%v]", err)
+	}
+	if pm.From == nil || pm.From.Address != "alice@example.com" || len(pm.To) != 1 || pm.Subject != "Hello" {
+		t.Errorf("ParseMessageRaw(): header mismatch: got [%v] [%v] [%s]", pm.From, pm.To, pm.Subject)
+	}
+	if len(pm.Cc) != 0 || pm.Header.Get("Cc") != "carol@example.com, <not closed" {
+		t.Errorf("ParseMessageRaw(): Cc mismatch: got [%v] raw [%s]", pm.Cc, pm.Header.Get("Cc"))
+	}
+	if len(pm.HeaderErrors) != 1 || pm.HeaderErrors["Cc"] == nil {
+		t.Errorf("ParseMessageRaw(): HeaderErrors mismatch: got [%v]", pm.HeaderErrors)
+	}
+	if pm.BodyText != "Hi Bob\r\n" {
+		t.Errorf("ParseMessageRaw(): BodyText mismatch: got [%q]", pm.BodyText)
+	}
+}
+
+func TestParseMessageUnknownCharset(t *testing.T) {
+	raw, err := os.ReadFile("testdata/unknown_charset.eml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, err := ParseMessageRaw(raw)
+	if err != nil {
+		t.Fatalf("ParseMessageRaw() error: [%v]", err)
+	}
+	if want := "Caf\uFFFD au lait"; pm.BodyText != want {
+		t.Errorf("ParseMessageRaw(): BodyText mismatch: want [%q] got [%q]", want, pm.BodyText)
+	}
+	if want := "<p>Caf&eacute; au lait</p>"; pm.BodyHTML != want {
+		t.Errorf("ParseMessageRaw(): BodyHTML mismatch: want [%q] got [%q]", want, pm.BodyHTML)
+	}
+	if len(pm.PartErrors) != 1 || pm.PartErrors["0"] == nil || !strings.Contains(pm.PartErrors["0"].Error(), "x-unknown") {
+		t.Errorf("ParseMessageRaw(): PartErrors mismatch: got [%v]", pm.PartErrors)
+	}
+}
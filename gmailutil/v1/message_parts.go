@@ -0,0 +1,55 @@
+package gmailutil
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"path/filepath"
+
+	"github.com/grokify/mogo/mime/multipartutil"
+	"github.com/grokify/mogo/net/http/httputilmore"
+)
+
+var ErrDuplicateFilename = errors.New("duplicate filename")
+
+// NewFilePart returns a base64 encoded part which reads `filename` when the message is
+// written. The `Content-ID` is the base filename, so inline parts can be referenced in
+// HTML as `cid:<base filename>`. `dispositionType` is `httputilmore.DispositionTypeInline`
+// or `httputilmore.DispositionTypeAttachment`.
+func NewFilePart(dispositionType, filename string) multipartutil.Part {
+	_, filenameonly := filepath.Split(filename)
+	return multipartutil.Part{
+		Type:             multipartutil.PartTypeFilepath,
+		BodyEncodeBase64: true,
+		BodyDataFilepath: filename,
+		DispositionType:  dispositionType,
+		HeaderRaw: textproto.MIMEHeader{
+			httputilmore.HeaderContentID: []string{"<" + filenameonly + ">"},
+		},
+	}
+}
+
+// NewFileParts returns a part for each of `filenames` using `NewFilePart`. Base filenames
+// must be unique because they are used as the `Content-ID`.
+func NewFileParts(dispositionType string, filenames []string) (multipartutil.Parts, error) {
+	if err := checkDuplicateFilenames(filenames); err != nil {
+		return nil, err
+	}
+	var parts multipartutil.Parts
+	for _, filename := range filenames {
+		parts = append(parts, NewFilePart(dispositionType, filename))
+	}
+	return parts, nil
+}
+
+func checkDuplicateFilenames(filenames []string) error {
+	seen := map[string]bool{}
+	for _, filename := range filenames {
+		_, filenameonly := filepath.Split(filename)
+		if seen[filenameonly] {
+			return fmt.Errorf("%w: %s", ErrDuplicateFilename, filenameonly)
+		}
+		seen[filenameonly] = true
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package gmailutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+)
+
+// maxAttachmentFilenameCollisions limits the numbered variants tried by `createUniqueFile()`.
+const maxAttachmentFilenameCollisions = 10000
+
+// AttachmentFilter selects attachments by MIME type and filename. Empty filter fields
+// match all attachments.
+type AttachmentFilter struct {
+	MIMETypes     []string // e.g. `application/pdf` or `image/*`
+	FilenameGlobs []string // `path.Match` patterns, e.g. `*.pdf`, matched case-insensitively
+	ExcludeInline bool     // skip inline parts such as embedded images
+}
+
+// Match reports if `att` is selected by the filter.
+func (f AttachmentFilter) Match(att ParsedAttachment) bool {
+	if f.ExcludeInline && att.Inline {
+		return false
+	}
+	if len(f.MIMETypes) > 0 {
+		mimeType := strings.ToLower(att.MIMEType)
+		matched := false
+		for _, mt := range f.MIMETypes {
+			mt = strings.ToLower(strings.TrimSpace(mt))
+			if mt == mimeType || (strings.HasSuffix(mt, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(mt, "*"))) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(f.FilenameGlobs) > 0 {
+		filename := strings.ToLower(att.Filename)
+		for _, glob := range f.FilenameGlobs {
+			if ok, err := path.Match(strings.ToLower(strings.TrimSpace(glob)), filename); err == nil && ok {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// GetAttachment retrieves decoded attachment data using `users.messages.attachments.get`.
+func (mapi *MessagesAPI) GetAttachment(ctx context.Context, userID, messageID, attachmentID string) ([]byte, error) {
+	if mapi.GmailService == nil {
+		return nil, ErrGmailServiceCannotBeNil
+	}
+	body, err := mapi.GmailService.UsersService.Messages.Attachments.Get(
+		strings.TrimSpace(userID),
+		strings.TrimSpace(messageID),
+		strings.TrimSpace(attachmentID)).
+		Context(ctx).
+		Do(mapi.GmailService.APICallOptions...)
+	if err != nil {
+		return nil, errorsutil.Wrap(err, "func GetAttachment() call to Attachments.Get().Do()")
+	}
+	return decodeBase64URL(body.Data)
+}
+
+// WriteAttachment writes the decoded attachment data to `w`, using `att.Data` if the data
+// was included in the message and retrieving it by `att.AttachmentID` otherwise.
+func (mapi *MessagesAPI) WriteAttachment(ctx context.Context, userID, messageID string, att ParsedAttachment, w io.Writer) error {
+	data := att.Data
+	if len(data) == 0 && att.AttachmentID != "" {
+		var err error
+		if data, err = mapi.GetAttachment(ctx, userID, messageID, att.AttachmentID); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// GetMessageAttachments retrieves a message and returns its attachments, including parts
+// nested in multipart containers and inline images, which match `filter`.
+func (mapi *MessagesAPI) GetMessageAttachments(ctx context.Context, userID, messageID string, filter AttachmentFilter) ([]ParsedAttachment, error) {
+	msg, err := mapi.GetMessageWithOpts(ctx, userID, messageID, &GetMessageOpts{Format: MessageFormatFull})
+	if err != nil {
+		return nil, err
+	}
+	pm, err := ParseMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	var atts []ParsedAttachment
+	for _, att := range pm.Attachments {
+		if filter.Match(att) {
+			atts = append(atts, att)
+		}
+	}
+	return atts, nil
+}
+
+// SavedAttachment describes an attachment written to disk.
+type SavedAttachment struct {
+	MessageID string
+	Filename  string // filename in the message
+	Path      string // path written to
+	MIMEType  string
+	Size      int64
+}
+
+// SaveMessageAttachments writes the attachments of a message matching `filter` to `dir`
+// using sanitized filenames. Existing files are not overwritten; a numbered suffix is
+// added instead.
+func (mapi *MessagesAPI) SaveMessageAttachments(ctx context.Context, userID, messageID, dir string, filter AttachmentFilter) ([]SavedAttachment, error) {
+	atts, err := mapi.GetMessageAttachments(ctx, userID, messageID, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(atts) > 0 {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+	}
+	var saved []SavedAttachment
+	for _, att := range atts {
+		f, err := createUniqueFile(dir, SanitizeFilename(att.Filename, att.MIMEType, att.PartID))
+		if err != nil {
+			return saved, err
+		}
+		cw := &countWriter{w: f}
+		err = mapi.WriteAttachment(ctx, userID, messageID, att, cw)
+		if errClose := f.Close(); err == nil {
+			err = errClose
+		}
+		if err != nil {
+			return saved, errors.Join(err, os.Remove(f.Name()))
+		}
+		saved = append(saved, SavedAttachment{
+			MessageID: messageID,
+			Filename:  att.Filename,
+			Path:      f.Name(),
+			MIMEType:  att.MIMEType,
+			Size:      cw.n})
+	}
+	return saved, nil
+}
+
+// SaveAttachments writes the matching attachments of all messages matching `opts` to `dir`.
+func (mapi *MessagesAPI) SaveAttachments(ctx context.Context, opts MessagesListOpts, dir string, filter AttachmentFilter) ([]SavedAttachment, error) {
+	opts.Inflate()
+	var saved []SavedAttachment
+	for msg, err := range mapi.ListAll(ctx, opts) {
+		if err != nil {
+			return saved, err
+		}
+		s, err := mapi.SaveMessageAttachments(ctx, opts.UserID, msg.Id, dir, filter)
+		saved = append(saved, s...)
+		if err != nil {
+			return saved, fmt.Errorf("message id (%s): %w", msg.Id, err)
+		}
+	}
+	return saved, nil
+}
+
+// SanitizeFilename returns a filename which is safe to create on common filesystems.
+// Path separators, control and reserved characters are replaced. If the result is
+// empty, a name is generated from `partID` and an extension for `mimeType`.
+func SanitizeFilename(filename, mimeType, partID string) string {
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, filename)
+	filename = strings.Trim(strings.TrimSpace(filename), ". ")
+	if filename == "" || strings.Trim(filename, "_") == "" {
+		filename = "attachment"
+		if partID != "" {
+			filename += "-" + strings.ReplaceAll(partID, ".", "-")
+		}
+		if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+			filename += exts[0]
+		}
+	}
+	if len(filename) > 200 {
+		ext := filepath.Ext(filename)
+		if len(ext) > 20 {
+			ext = ""
+		}
+		filename = filename[:200-len(ext)] + ext
+	}
+	return filename
+}
+
+// createUniqueFile creates `filename` in `dir`, adding a ` (N)` suffix before the extension
+// if the file already exists.
+func createUniqueFile(dir, filename string) (*os.File, error) {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	for i := 0; i < maxAttachmentFilenameCollisions; i++ {
+		name := filename
+		if i > 0 {
+			name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+		}
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			return f, nil
+		} else if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("too many files named (%s) in (%s)", filename, dir)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,97 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+var sanitizeFilenameTests = []struct {
+	filename string
+	mimeType string
+	partID   string
+	want     string
+}{
+	{"report.pdf", "application/pdf", "1", "report.pdf"},
+	{"../../etc/passwd", "text/plain", "1", "_.._etc_passwd"},
+	{`a:b*c?"d".txt`, "text/plain", "1", "a_b_c__d_.txt"},
+	{"  .hidden. ", "", "2", "hidden"},
+	{"", "application/pdf", "0.1", "attachment-0-1.pdf"},
+	{"///", "", "", "attachment"},
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	for _, tt := range sanitizeFilenameTests {
+		if got := SanitizeFilename(tt.filename, tt.mimeType, tt.partID); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) mismatch: want [%s] got [%s]", tt.filename, tt.want, got)
+		}
+	}
+}
+
+var attachmentFilterTests = []struct {
+	filter AttachmentFilter
+	att    ParsedAttachment
+	want   bool
+}{
+	{AttachmentFilter{}, ParsedAttachment{Filename: "a.pdf", MIMEType: "application/pdf"}, true},
+	{AttachmentFilter{MIMETypes: []string{"image/*"}}, ParsedAttachment{Filename: "a.png", MIMEType: "image/png"}, true},
+	{AttachmentFilter{MIMETypes: []string{"image/*"}}, ParsedAttachment{Filename: "a.pdf", MIMEType: "application/pdf"}, false},
+	{AttachmentFilter{FilenameGlobs: []string{"*.PDF"}}, ParsedAttachment{Filename: "Invoice.pdf"}, true},
+	{AttachmentFilter{FilenameGlobs: []string{"*.csv", "*.xlsx"}}, ParsedAttachment{Filename: "a.pdf"}, false},
+	{AttachmentFilter{ExcludeInline: true}, ParsedAttachment{Filename: "logo.png", Inline: true}, false},
+}
+
+func TestAttachmentFilterMatch(t *testing.T) {
+	for _, tt := range attachmentFilterTests {
+		if got := tt.filter.Match(tt.att); got != tt.want {
+			t.Errorf("AttachmentFilter.Match(%+v, %s) mismatch: want [%v] got [%v]", tt.filter, tt.att.Filename, tt.want, got)
+		}
+	}
+}
+
+func TestSaveMessageAttachments(t *testing.T) {
+	msg := readTestMessageFull(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/me/messages/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewEncoder(w).Encode(msg); err != nil {
+			t.Error(err)
+		}
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/messages/{id}/attachments/{attachmentId}", func(w http.ResponseWriter, r *http.Request) {
+		data := []byte("data for " + r.PathValue("attachmentId"))
+		if err := json.NewEncoder(w).Encode(gmail.MessagePartBody{
+			Data: base64.URLEncoding.EncodeToString(data),
+			Size: int64(len(data))}); err != nil {
+			t.Error(err)
+		}
+	})
+	gs := newTestGmailService(t, mux)
+	dir := t.TempDir()
+
+	for _, want := range []string{"report.pdf", "report (1).pdf"} {
+		saved, err := gs.MessagesAPI.SaveMessageAttachments(context.Background(), UserIDMe, msg.Id, dir,
+			AttachmentFilter{FilenameGlobs: []string{"*.pdf"}})
+		if err != nil {
+			t.Fatalf("MessagesAPI.SaveMessageAttachments() error: [%v]", err)
+		}
+		if len(saved) != 1 {
+			t.Fatalf("MessagesAPI.SaveMessageAttachments() count mismatch: want [1] got [%d]", len(saved))
+		}
+		if got := filepath.Base(saved[0].Path); got != want {
+			t.Errorf("MessagesAPI.SaveMessageAttachments() filename mismatch: want [%s] got [%s]", want, got)
+		}
+		data, err := os.ReadFile(saved[0].Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "data for ANGjdJ_report" || saved[0].Size != int64(len(data)) {
+			t.Errorf("MessagesAPI.SaveMessageAttachments() data mismatch: got [%s]", data)
+		}
+	}
+}
@@ -1,12 +1,19 @@
 package gmailutil
 
 import (
-	"fmt"
+	"context"
+	"slices"
 	"strings"
 
 	gmail "google.golang.org/api/gmail/v1"
 )
 
+// BatchDeleteMaxIDs is the maximum number of message IDs accepted by a single
+// `users.messages.batchDelete` call.
+const BatchDeleteMaxIDs = 1000
+
+// BatchDeleteMessages permanently deletes `messageIDs`, splitting them into
+// multiple `users.messages.batchDelete` calls of at most `BatchDeleteMaxIDs` each.
 func (mapi *MessagesAPI) BatchDeleteMessages(userID string, messageIDs []string) error {
 	if mapi.GmailService == nil {
 		return ErrGmailServiceCannotBeNil
@@ -17,12 +24,20 @@ func (mapi *MessagesAPI) BatchDeleteMessages(userID string, messageIDs []string)
 		return ErrGmailUserIDCannotBeEmpty
 	}
 
-	return mapi.GmailService.UsersService.Messages.BatchDelete(
-		userID,
-		&gmail.BatchDeleteMessagesRequest{Ids: messageIDs}).
-		Do(mapi.GmailService.APICallOptions...)
+	for ids := range slices.Chunk(messageIDs, BatchDeleteMaxIDs) {
+		if err := mapi.GmailService.UsersService.Messages.BatchDelete(
+			userID,
+			&gmail.BatchDeleteMessagesRequest{Ids: ids}).
+			Do(mapi.GmailService.APICallOptions...); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// DeleteMessagesFrom permanently deletes all messages from each address in `rfc822s`. It
+// returns the number of messages deleted and the number of addresses with at least 100
+// messages. Use `BulkAction()` for trash mode, dry runs and progress reporting.
 func (mapi *MessagesAPI) DeleteMessagesFrom(rfc822s []string) (int, int, error) {
 	if mapi.GmailService == nil {
 		return -1, -1, ErrGmailServiceCannotBeNil
@@ -30,49 +45,20 @@ func (mapi *MessagesAPI) DeleteMessagesFrom(rfc822s []string) (int, int, error)
 
 	deletedCount := 0
 	gte100Count := 0
-	rfc822Count := len(rfc822s)
-	for i, rfc822 := range rfc822s {
-		ids, err := mapi.deleteMessagesFromSingle(rfc822)
+	for _, rfc822 := range rfc822s {
+		res, err := mapi.BulkAction(context.Background(), BulkActionOpts{
+			UserID: UserIDMe,
+			Query:  MessagesListQueryOpts{From: rfc822},
+			Action: BulkActionDelete})
+		if res != nil {
+			deletedCount += res.Processed
+			if res.Matched >= 100 {
+				gte100Count++
+			}
+		}
 		if err != nil {
 			return deletedCount, gte100Count, err
 		}
-		numDeleted := len(ids)
-		alert := ""
-		if numDeleted >= 100 {
-			alert = " (>100)"
-			gte100Count++
-		}
-		for numDeleted >= 100 {
-			ids, err := mapi.deleteMessagesFromSingle(rfc822)
-			if err != nil {
-				return deletedCount, gte100Count, err
-			}
-			numDeleted = len(ids)
-		}
-		fmt.Printf("[%d/%d] DELETED [%v]%s messages [from:%v]\n", i+1, rfc822Count, numDeleted, alert, rfc822)
-		fmt.Printf("{\"addressNum\":%d, \"addressTotal\": %d, \"deletedCount\": %d}", i+1, rfc822Count, numDeleted)
-		/*log.Info().
-		Int("address_num", i+1).
-		Int("address_total", rfc822Count).
-		Int("deleted_count", numDeleted)*/
-		deletedCount += numDeleted
 	}
 	return deletedCount, gte100Count, nil
 }
-
-func (mapi *MessagesAPI) deleteMessagesFromSingle(rfc822 string) ([]string, error) {
-	ids := []string{}
-	listRes, err := mapi.GetMessagesFrom(rfc822)
-	if err != nil {
-		return ids, err
-	}
-
-	for _, msg := range listRes.Messages {
-		ids = append(ids, msg.Id)
-	}
-
-	if len(ids) == 0 {
-		return ids, nil
-	}
-	return ids, mapi.BatchDeleteMessages(UserIDMe, ids)
-}
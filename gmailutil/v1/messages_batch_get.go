@@ -0,0 +1,179 @@
+package gmailutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grokify/mogo/net/http/httputilmore"
+	gmail "google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// BatchPath is the Gmail HTTP batch endpoint path relative to the service base path.
+const BatchPath = "batch/gmail/v1"
+
+var ErrBatchResponseMissing = errors.New("gmail batch response missing for request")
+
+const batchContentIDPrefix = "item-"
+
+// batchGetMessages retrieves up to `BatchGetMaxSize` messages in a single Gmail HTTP batch
+// request. Results and errors are keyed by message ID.
+func (mapi *MessagesAPI) batchGetMessages(ctx context.Context, userID string, messageIDs []string, opts *GetMessageOpts) (map[string]*gmail.Message, map[string]error) {
+	msgs := map[string]*gmail.Message{}
+	errs := map[string]error{}
+	setErrAll := func(err error) (map[string]*gmail.Message, map[string]error) {
+		for _, id := range messageIDs {
+			errs[id] = err
+		}
+		return msgs, errs
+	}
+	if len(messageIDs) > BatchGetMaxSize {
+		return setErrAll(fmt.Errorf("gmail batch request size (%d) exceeds maximum (%d)", len(messageIDs), BatchGetMaxSize))
+	} else if mapi.GmailService == nil || mapi.GmailService.Service == nil {
+		return setErrAll(ErrGmailServiceCannotBeNil)
+	} else if mapi.GmailService.httpClient == nil {
+		return setErrAll(ErrHTTPClientCannotBeNil)
+	}
+
+	callOpts := mapi.GmailService.APICallOptions
+	body, ct, err := batchGetMessagesRequestBody(userID, messageIDs, opts, callOpts...)
+	if err != nil {
+		return setErrAll(err)
+	}
+	batchURL := strings.TrimSuffix(mapi.GmailService.Service.BasePath, "/") + "/" + BatchPath
+	if qry := callOptionsValues(callOpts...); len(qry) > 0 {
+		batchURL += "?" + qry.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, batchURL, body)
+	if err != nil {
+		return setErrAll(err)
+	}
+	req.Header.Set(httputilmore.HeaderContentType, ct)
+
+	resp, err := mapi.GmailService.httpClient.Do(req)
+	if err != nil {
+		return setErrAll(err)
+	}
+	defer resp.Body.Close()
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return setErrAll(err)
+	}
+
+	_, params, err := mime.ParseMediaType(resp.Header.Get(httputilmore.HeaderContentType))
+	if err != nil {
+		return setErrAll(err)
+	}
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return setErrAll(err)
+		}
+		i, ok := batchContentIDIndex(part.Header.Get(httputilmore.HeaderContentID))
+		if !ok || i >= len(messageIDs) {
+			continue
+		}
+		id := messageIDs[i]
+		if msg, err := parseBatchPartMessage(part); err != nil {
+			errs[id] = err
+		} else {
+			msgs[id] = msg
+		}
+	}
+	for _, id := range messageIDs {
+		if _, ok := msgs[id]; !ok {
+			if _, ok := errs[id]; !ok {
+				errs[id] = ErrBatchResponseMissing
+			}
+		}
+	}
+	return msgs, errs
+}
+
+// batchGetMessagesRequestBody builds the multipart batch body. `callOpts` are set on each
+// request, as the Gmail client does for a single `users.messages.get` call.
+func batchGetMessagesRequestBody(userID string, messageIDs []string, opts *GetMessageOpts, callOpts ...googleapi.CallOption) (io.Reader, string, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	qry := callOptionsValues(callOpts...)
+	if opts != nil {
+		if format := strings.TrimSpace(opts.Format); format != "" {
+			qry.Set("format", format)
+		}
+		for _, h := range opts.MetadataHeaders {
+			qry.Add("metadataHeaders", h)
+		}
+	}
+	for i, id := range messageIDs {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			httputilmore.HeaderContentType: []string{"application/http"},
+			httputilmore.HeaderContentID:   []string{"<" + batchContentIDPrefix + strconv.Itoa(i) + ">"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		reqURL := "/gmail/v1/users/" + url.PathEscape(userID) + "/messages/" + url.PathEscape(id)
+		if len(qry) > 0 {
+			reqURL += "?" + qry.Encode()
+		}
+		if _, err := fmt.Fprintf(pw, "GET %s HTTP/1.1\r\n\r\n", reqURL); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &b, "multipart/mixed; boundary=" + w.Boundary(), nil
+}
+
+// callOptionsValues returns the query parameters set by `opts`, such as `quotaUser`.
+func callOptionsValues(opts ...googleapi.CallOption) url.Values {
+	qry := url.Values{}
+	for _, o := range opts {
+		if m, ok := o.(googleapi.MultiCallOption); ok {
+			k, v := m.GetMulti()
+			qry[k] = v
+		} else if k, v := o.Get(); k != "" {
+			qry.Set(k, v)
+		}
+	}
+	return qry
+}
+
+// batchContentIDIndex parses response Content-IDs of the form `<response-item-N>`.
+func batchContentIDIndex(contentID string) (int, bool) {
+	contentID = strings.Trim(strings.TrimSpace(contentID), "<>")
+	_, idx, ok := strings.Cut(contentID, batchContentIDPrefix)
+	if !ok {
+		return -1, false
+	}
+	i, err := strconv.Atoi(idx)
+	return i, err == nil
+}
+
+func parseBatchPartMessage(part *multipart.Part) (*gmail.Message, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(part), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+	msg := &gmail.Message{}
+	return msg, json.NewDecoder(resp.Body).Decode(msg)
+}
@@ -0,0 +1,152 @@
+package gmailutil
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+const (
+	// BulkActionTrash moves messages to the trash, where Gmail deletes them after 30 days.
+	BulkActionTrash = "trash"
+	// BulkActionDelete permanently deletes messages. It requires `MailGoogleComScope`.
+	BulkActionDelete = "delete"
+
+	BulkStageList    = "list"
+	BulkStagePreview = "preview"
+	BulkStageApply   = "apply"
+
+	LabelIDTrash = "TRASH"
+)
+
+// BulkActionOpts configures `MessagesAPI.BulkAction()`.
+type BulkActionOpts struct {
+	UserID           string
+	Query            MessagesListQueryOpts
+	LabelIDs         []string
+	IncludeSpamTrash bool
+	MaxTotal         int                // maximum number of messages to act on, 0 for no limit
+	Action           string             // `BulkActionTrash` (default) or `BulkActionDelete`
+	DryRun           bool               // list matching messages without modifying them
+	PreviewCount     int                // number of matching messages to include in `BulkResult.Preview`
+	ChunkSize        int                // IDs per API call, defaults to and is capped at `BatchDeleteMaxIDs`
+	Progress         func(BulkProgress) // optional callback invoked after each stage step
+}
+
+func (opts *BulkActionOpts) inflate() error {
+	if opts.UserID = strings.TrimSpace(opts.UserID); opts.UserID == "" {
+		opts.UserID = UserIDMe
+	}
+	switch opts.Action = strings.ToLower(strings.TrimSpace(opts.Action)); opts.Action {
+	case "":
+		opts.Action = BulkActionTrash
+	case BulkActionTrash, BulkActionDelete:
+	default:
+		return fmt.Errorf("bulk action not supported (%s)", opts.Action)
+	}
+	if opts.ChunkSize <= 0 || opts.ChunkSize > BatchDeleteMaxIDs {
+		opts.ChunkSize = BatchDeleteMaxIDs
+	}
+	return nil
+}
+
+func (opts *BulkActionOpts) listOpts() MessagesListOpts {
+	return MessagesListOpts{
+		UserID:           opts.UserID,
+		Query:            opts.Query,
+		LabelIDs:         opts.LabelIDs,
+		IncludeSpamTrash: opts.IncludeSpamTrash,
+		MaxTotal:         opts.MaxTotal}
+}
+
+// BulkProgress reports the progress of a bulk action.
+type BulkProgress struct {
+	Stage     string // `BulkStageList`, `BulkStagePreview` or `BulkStageApply`
+	Matched   int    // messages matched so far
+	Processed int    // messages acted on so far
+}
+
+// BulkResult summarizes a bulk action.
+type BulkResult struct {
+	Query      string
+	Action     string
+	DryRun     bool
+	Matched    int
+	Processed  int
+	MessageIDs []string
+	Preview    []*ParsedMessage
+}
+
+// BulkAction moves to trash or permanently deletes all messages matching `opts.Query`.
+// With `DryRun`, matching messages are listed and optionally previewed but not modified.
+func (mapi *MessagesAPI) BulkAction(ctx context.Context, opts BulkActionOpts) (*BulkResult, error) {
+	if mapi.GmailService == nil {
+		return nil, ErrGmailServiceCannotBeNil
+	} else if err := opts.inflate(); err != nil {
+		return nil, err
+	}
+	res := &BulkResult{
+		Query:  opts.Query.Encode(),
+		Action: opts.Action,
+		DryRun: opts.DryRun}
+	progress := func(stage string) {
+		if opts.Progress != nil {
+			opts.Progress(BulkProgress{Stage: stage, Matched: res.Matched, Processed: res.Processed})
+		}
+	}
+
+	for msg, err := range mapi.ListAll(ctx, opts.listOpts()) {
+		if err != nil {
+			return res, err
+		}
+		res.MessageIDs = append(res.MessageIDs, msg.Id)
+		if res.Matched++; res.Matched%MessagesListDefaultPageSize == 0 {
+			progress(BulkStageList)
+		}
+	}
+	progress(BulkStageList)
+
+	if opts.PreviewCount > 0 && len(res.MessageIDs) > 0 {
+		var metas []*gmail.Message
+		for _, id := range res.MessageIDs[:min(opts.PreviewCount, len(res.MessageIDs))] {
+			metas = append(metas, &gmail.Message{Id: id})
+		}
+		inflated := mapi.InflateMessagesWithOpts(ctx, metas, InflateOpts{
+			UserID:          opts.UserID,
+			Format:          MessageFormatMetadata,
+			MetadataHeaders: []string{HeaderDate, "From", "Subject"}})
+		for _, msg := range inflated.Messages {
+			if pm, err := ParseMessage(msg); err == nil {
+				res.Preview = append(res.Preview, pm)
+			}
+		}
+		progress(BulkStagePreview)
+	}
+
+	if opts.DryRun {
+		return res, nil
+	}
+
+	for ids := range slices.Chunk(res.MessageIDs, opts.ChunkSize) {
+		var err error
+		switch opts.Action {
+		case BulkActionDelete:
+			err = mapi.GmailService.UsersService.Messages.BatchDelete(opts.UserID,
+				&gmail.BatchDeleteMessagesRequest{Ids: ids}).
+				Context(ctx).Do(mapi.GmailService.APICallOptions...)
+		default:
+			err = mapi.GmailService.UsersService.Messages.BatchModify(opts.UserID,
+				&gmail.BatchModifyMessagesRequest{Ids: ids, AddLabelIds: []string{LabelIDTrash}}).
+				Context(ctx).Do(mapi.GmailService.APICallOptions...)
+		}
+		if err != nil {
+			return res, err
+		}
+		res.Processed += len(ids)
+		progress(BulkStageApply)
+	}
+	return res, nil
+}
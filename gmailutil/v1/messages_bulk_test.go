@@ -0,0 +1,75 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+func TestBulkAction(t *testing.T) {
+	for _, tt := range []struct {
+		action      string
+		dryRun      bool
+		wantPath    string
+		wantBatches []int
+	}{
+		{BulkActionTrash, false, "batchModify", []int{1000, 1000, 500}},
+		{BulkActionDelete, false, "batchDelete", []int{1000, 1000, 500}},
+		{BulkActionDelete, true, "", nil},
+	} {
+		listCalls := 0
+		var batches []int
+		mux := http.NewServeMux()
+		mux.Handle("GET /gmail/v1/users/me/messages", pagedMessagesHandler(t, 2500, 500, &listCalls))
+		mux.HandleFunc("POST /gmail/v1/users/me/messages/{op}", func(w http.ResponseWriter, r *http.Request) {
+			if r.PathValue("op") != tt.wantPath {
+				t.Errorf("BulkAction(%s) path mismatch: want [%s] got [%s]", tt.action, tt.wantPath, r.PathValue("op"))
+			}
+			req := gmail.BatchModifyMessagesRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+				return
+			}
+			if tt.action == BulkActionTrash && (len(req.AddLabelIds) != 1 || req.AddLabelIds[0] != LabelIDTrash) {
+				t.Errorf("BulkAction(%s) label mismatch: got [%v]", tt.action, req.AddLabelIds)
+			}
+			batches = append(batches, len(req.Ids))
+			w.WriteHeader(http.StatusNoContent)
+		})
+		gs := newTestGmailService(t, mux)
+
+		var progress []BulkProgress
+		res, err := gs.MessagesAPI.BulkAction(context.Background(), BulkActionOpts{
+			Query:    MessagesListQueryOpts{From: "list@example.com"},
+			Action:   tt.action,
+			DryRun:   tt.dryRun,
+			Progress: func(p BulkProgress) { progress = append(progress, p) }})
+		if err != nil {
+			t.Fatalf("BulkAction(%s) error: [%v]", tt.action, err)
+		}
+		if res.Matched != 2500 || res.Query != "from:list@example.com" {
+			t.Errorf("BulkAction(%s) result mismatch: got matched [%d] query [%s]", tt.action, res.Matched, res.Query)
+		}
+		wantProcessed := 0
+		for _, n := range tt.wantBatches {
+			wantProcessed += n
+		}
+		if res.Processed != wantProcessed {
+			t.Errorf("BulkAction(%s,dryRun=%v) processed mismatch: want [%d] got [%d]", tt.action, tt.dryRun, wantProcessed, res.Processed)
+		}
+		if len(batches) != len(tt.wantBatches) {
+			t.Fatalf("BulkAction(%s,dryRun=%v) batch count mismatch: want [%v] got [%v]", tt.action, tt.dryRun, tt.wantBatches, batches)
+		}
+		for i := range batches {
+			if batches[i] != tt.wantBatches[i] {
+				t.Errorf("BulkAction(%s) batch size mismatch: want [%v] got [%v]", tt.action, tt.wantBatches, batches)
+			}
+		}
+		if last := progress[len(progress)-1]; last.Matched != 2500 || last.Processed != wantProcessed {
+			t.Errorf("BulkAction(%s) final progress mismatch: got [%+v]", tt.action, last)
+		}
+	}
+}
@@ -1,22 +1,43 @@
 package gmailutil
 
 import (
-	"fmt"
+	"context"
 	"strings"
 
 	gmail "google.golang.org/api/gmail/v1"
 )
 
 func (mapi *MessagesAPI) GetMessage(userID, messageID string) (*gmail.Message, error) {
+	return mapi.GetMessageWithOpts(context.Background(), userID, messageID, nil)
+}
+
+// GetMessageOpts specifies the response format for `users.messages.get`.
+type GetMessageOpts struct {
+	Format          string   // one of `MessageFormatMinimal`, `MessageFormatMetadata`, `MessageFormatFull` (default), `MessageFormatRaw`
+	MetadataHeaders []string // headers to include when `Format` is `MessageFormatMetadata`
+}
+
+// GetMessageWithOpts retrieves a message using the format specified in `opts`, which can be nil.
+func (mapi *MessagesAPI) GetMessageWithOpts(ctx context.Context, userID, messageID string, opts *GetMessageOpts) (*gmail.Message, error) {
 	if mapi.GmailService == nil {
 		return nil, ErrGmailServiceCannotBeNil
 	}
-	return mapi.GmailService.UsersService.Messages.Get(
+	call := mapi.GmailService.UsersService.Messages.Get(
 		strings.TrimSpace(userID),
-		strings.TrimSpace(messageID)).
-		Do(mapi.GmailService.APICallOptions...)
+		strings.TrimSpace(messageID))
+	if opts != nil {
+		if format := strings.TrimSpace(opts.Format); format != "" {
+			call.Format(format)
+		}
+		if len(opts.MetadataHeaders) > 0 {
+			call.MetadataHeaders(opts.MetadataHeaders...)
+		}
+	}
+	return call.Context(ctx).Do(mapi.GmailService.APICallOptions...)
 }
 
+// GetMessagesByCategory returns inflated messages for a category such as `CategoryPromotions`.
+// If `getAll` is false, only the first `MessagesListDefaultPageSize` messages are returned.
 func (mapi *MessagesAPI) GetMessagesByCategory(userID, categoryName string, getAll bool) ([]*gmail.Message, error) {
 	if mapi.GmailService == nil {
 		return nil, ErrGmailServiceCannotBeNil
@@ -25,14 +46,17 @@ func (mapi *MessagesAPI) GetMessagesByCategory(userID, categoryName string, getA
 		Category: categoryName,
 	}
 	opts := MessagesListOpts{
-		Query: qOpts,
+		UserID: userID,
+		Query:  qOpts,
+	}
+	if !getAll {
+		opts.MaxTotal = MessagesListDefaultPageSize
 	}
 
-	listRes, err := mapi.GetMessagesList(opts)
+	msgMetas, err := mapi.GetMessagesListAll(context.Background(), opts)
 	if err != nil {
-		fmt.Printf("ERR [%s]", err.Error())
 		return []*gmail.Message{}, err
 	}
 
-	return mapi.InflateMessages(userID, listRes.Messages)
+	return mapi.InflateMessages(userID, msgMetas)
 }
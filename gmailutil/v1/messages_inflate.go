@@ -0,0 +1,194 @@
+package gmailutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+const (
+	// DefaultInflateConcurrency is the number of concurrent workers used by `InflateMessagesWithOpts()`.
+	DefaultInflateConcurrency = 10
+	// BatchGetMaxSize is the maximum number of requests in a single Gmail HTTP batch request.
+	BatchGetMaxSize = 100
+)
+
+// InflateOpts configures `InflateMessagesWithOpts()`.
+type InflateOpts struct {
+	UserID          string
+	Format          string   // one of `MessageFormatMinimal`, `MessageFormatMetadata`, `MessageFormatFull` (default), `MessageFormatRaw`
+	MetadataHeaders []string // headers to include when `Format` is `MessageFormatMetadata`
+	Concurrency     int      // number of concurrent workers, defaults to `DefaultInflateConcurrency`
+	RequestsPerSec  float64  // quota limit for `users.messages.get` calls, 0 for no limit
+	UseBatch        bool     // fetch messages using the Gmail HTTP batch endpoint
+	BatchSize       int      // messages per batch request, defaults to and is capped at `BatchGetMaxSize`
+}
+
+func (opts *InflateOpts) inflate() {
+	if opts.UserID = strings.TrimSpace(opts.UserID); opts.UserID == "" {
+		opts.UserID = UserIDMe
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultInflateConcurrency
+	}
+	if opts.BatchSize <= 0 || opts.BatchSize > BatchGetMaxSize {
+		opts.BatchSize = BatchGetMaxSize
+	}
+}
+
+func (opts *InflateOpts) getMessageOpts() *GetMessageOpts {
+	return &GetMessageOpts{
+		Format:          opts.Format,
+		MetadataHeaders: opts.MetadataHeaders}
+}
+
+// InflateResult contains the messages retrieved by `InflateMessagesWithOpts()`, in input
+// order, and the errors for message IDs that could not be retrieved.
+type InflateResult struct {
+	Messages []*gmail.Message
+	Errors   map[string]error
+}
+
+// Err returns the per-ID errors joined into a single error, or nil if there are none.
+func (res *InflateResult) Err() error {
+	if len(res.Errors) == 0 {
+		return nil
+	}
+	var errs []error
+	for id, err := range res.Errors {
+		errs = append(errs, fmt.Errorf("message id (%s): %w", id, err))
+	}
+	return errors.Join(errs...)
+}
+
+// InflateMessages retrieves full messages for the `msgMetas` returned by `users.messages.list`.
+// Messages that cannot be retrieved are omitted from the result and reported in the returned error.
+func (mapi *MessagesAPI) InflateMessages(userID string, msgMetas []*gmail.Message) ([]*gmail.Message, error) {
+	if mapi.GmailService == nil {
+		return nil, ErrGmailServiceCannotBeNil
+	}
+	res := mapi.InflateMessagesWithOpts(context.Background(), msgMetas, InflateOpts{UserID: userID})
+	return res.Messages, res.Err()
+}
+
+// InflateMessagesWithOpts retrieves messages for `msgMetas` using a bounded worker pool with an
+// optional requests-per-second limit. Failures are recorded per message ID and do not stop the
+// retrieval of other messages.
+func (mapi *MessagesAPI) InflateMessagesWithOpts(ctx context.Context, msgMetas []*gmail.Message, opts InflateOpts) *InflateResult {
+	res := &InflateResult{
+		Messages: []*gmail.Message{},
+		Errors:   map[string]error{}}
+	if len(msgMetas) == 0 {
+		return res
+	}
+	opts.inflate()
+
+	ids := make([]string, len(msgMetas))
+	for i, msgMeta := range msgMetas {
+		if msgMeta != nil {
+			ids[i] = msgMeta.Id
+		}
+	}
+
+	if mapi.GmailService == nil {
+		for _, id := range ids {
+			res.Errors[id] = ErrGmailServiceCannotBeNil
+		}
+		return res
+	}
+
+	var limiter *rate.Limiter
+	if opts.RequestsPerSec > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.RequestsPerSec), int(math.Max(1, math.Ceil(opts.RequestsPerSec))))
+	}
+
+	jobSize := 1
+	if opts.UseBatch {
+		jobSize = opts.BatchSize
+	}
+	jobs := make(chan []string)
+	msgs := make([]*gmail.Message, len(ids))
+	idx := map[string][]int{}
+	for i, id := range ids {
+		idx[id] = append(idx[id], i)
+	}
+
+	var mu sync.Mutex
+	setResult := func(id string, msg *gmail.Message, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			res.Errors[id] = err
+			return
+		}
+		for _, i := range idx[id] {
+			msgs[i] = msg
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range min(opts.Concurrency, (len(ids)+jobSize-1)/jobSize) {
+		wg.Go(func() {
+			for job := range jobs {
+				if limiter != nil {
+					if err := waitN(ctx, limiter, len(job)); err != nil {
+						for _, id := range job {
+							setResult(id, nil, err)
+						}
+						continue
+					}
+				}
+				if opts.UseBatch {
+					batchMsgs, batchErrs := mapi.batchGetMessages(ctx, opts.UserID, job, opts.getMessageOpts())
+					for _, id := range job {
+						setResult(id, batchMsgs[id], batchErrs[id])
+					}
+				} else {
+					msg, err := mapi.GetMessageWithOpts(ctx, opts.UserID, job[0], opts.getMessageOpts())
+					setResult(job[0], msg, err)
+				}
+			}
+		})
+	}
+
+	seen := map[string]bool{}
+	var job []string
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if job = append(job, id); len(job) >= jobSize {
+			jobs <- job
+			job = nil
+		}
+	}
+	if len(job) > 0 {
+		jobs <- job
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, msg := range msgs {
+		if msg != nil {
+			res.Messages = append(res.Messages, msg)
+		}
+	}
+	return res
+}
+
+// waitN waits for `n` tokens one at a time so `n` can exceed the limiter burst.
+func waitN(ctx context.Context, limiter *rate.Limiter, n int) error {
+	for range n {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
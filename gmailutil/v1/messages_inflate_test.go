@@ -0,0 +1,177 @@
+package gmailutil
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	gmail "google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// inflateHandler serves `users.messages.get` and the batch endpoint. Message IDs
+// starting with "bad" return 404.
+func inflateHandler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/{userId}/messages/{id}", func(w http.ResponseWriter, r *http.Request) {
+		writeTestMessage(t, w, r.PathValue("id"), r.URL.Query().Get("format"))
+	})
+	mux.HandleFunc("POST /"+BatchPath, func(w http.ResponseWriter, r *http.Request) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		// Read all requests before writing since writing may close the request body.
+		type batchItem struct {
+			contentID string
+			req       *http.Request
+		}
+		var items []batchItem
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Error(err)
+				return
+			}
+			req, err := http.ReadRequest(bufio.NewReader(part))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			items = append(items, batchItem{contentID: strings.Trim(part.Header.Get("Content-ID"), "<>"), req: req})
+		}
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+		for _, item := range items {
+			pw, err := mw.CreatePart(map[string][]string{
+				"Content-Type": {"application/http"},
+				"Content-ID":   {"<response-" + item.contentID + ">"}})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			id := item.req.URL.Path[strings.LastIndex(item.req.URL.Path, "/")+1:]
+			rw := &testResponseWriter{header: http.Header{}}
+			writeTestMessage(t, rw, id, item.req.URL.Query().Get("format"))
+			if _, err := fmt.Fprintf(pw, "HTTP/1.1 %d %s\r\nContent-Type: application/json\r\n\r\n%s",
+				rw.status, http.StatusText(rw.status), rw.body.String()); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		if err := mw.Close(); err != nil {
+			t.Error(err)
+			return
+		}
+	})
+	return mux
+}
+
+func writeTestMessage(t *testing.T, w http.ResponseWriter, id, format string) {
+	if strings.HasPrefix(id, "bad") {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":{"code":404,"message":"Not Found"}}`)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(gmail.Message{Id: id, Snippet: format}); err != nil {
+		t.Error(err)
+	}
+}
+
+type testResponseWriter struct {
+	header http.Header
+	status int
+	body   strings.Builder
+}
+
+func (rw *testResponseWriter) Header() http.Header         { return rw.header }
+func (rw *testResponseWriter) Write(b []byte) (int, error) { return rw.body.Write(b) }
+func (rw *testResponseWriter) WriteHeader(status int)      { rw.status = status }
+
+func TestInflateMessagesWithOpts(t *testing.T) {
+	gs := newTestGmailService(t, inflateHandler(t))
+	var metas []*gmail.Message
+	var wantIDs []string
+	for i := range 250 {
+		id := fmt.Sprintf("msg%d", i)
+		if i%50 == 7 {
+			id = fmt.Sprintf("bad%d", i)
+		} else {
+			wantIDs = append(wantIDs, id)
+		}
+		metas = append(metas, &gmail.Message{Id: id})
+	}
+	for _, useBatch := range []bool{false, true} {
+		res := gs.MessagesAPI.InflateMessagesWithOpts(context.Background(), metas, InflateOpts{
+			Format:      MessageFormatMetadata,
+			Concurrency: 4,
+			UseBatch:    useBatch})
+		if len(res.Errors) != 5 {
+			t.Errorf("InflateMessagesWithOpts(useBatch=%v) error count mismatch: want [5] got [%d]", useBatch, len(res.Errors))
+		}
+		if len(res.Messages) != len(wantIDs) {
+			t.Fatalf("InflateMessagesWithOpts(useBatch=%v) message count mismatch: want [%d] got [%d]", useBatch, len(wantIDs), len(res.Messages))
+		}
+		for i, msg := range res.Messages {
+			if msg.Id != wantIDs[i] {
+				t.Errorf("InflateMessagesWithOpts(useBatch=%v) order mismatch: want [%s] got [%s]", useBatch, wantIDs[i], msg.Id)
+			}
+			if msg.Snippet != MessageFormatMetadata {
+				t.Errorf("InflateMessagesWithOpts(useBatch=%v) format mismatch: want [%s] got [%s]", useBatch, MessageFormatMetadata, msg.Snippet)
+			}
+		}
+		if res.Err() == nil {
+			t.Errorf("InflateMessagesWithOpts(useBatch=%v) expected joined error", useBatch)
+		}
+	}
+}
+
+func TestBatchGetMessagesCallOptions(t *testing.T) {
+	handler := inflateHandler(t)
+	var outerQuotaUser string
+	gs := newTestGmailService(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outerQuotaUser = r.URL.Query().Get("quotaUser")
+		handler.ServeHTTP(w, r)
+	}))
+	gs.APICallOptions = []googleapi.CallOption{googleapi.QuotaUser("quota-1")}
+	msgs, errs := gs.MessagesAPI.batchGetMessages(context.Background(), "me", []string{"msg1"}, nil)
+	if len(errs) != 0 || len(msgs) != 1 {
+		t.Fatalf("batchGetMessages() mismatch: messages (%d) errors (%v)", len(msgs), errs)
+	}
+	if outerQuotaUser != "quota-1" {
+		t.Errorf("batchGetMessages() batch quotaUser mismatch: want (quota-1), got (%s)", outerQuotaUser)
+	}
+
+	body, ct, err := batchGetMessagesRequestBody("me", []string{"msg1"},
+		&GetMessageOpts{Format: MessageFormatMetadata}, gs.APICallOptions...)
+	if err != nil {
+		t.Fatalf("batchGetMessagesRequestBody() error: [%v]", err)
+	}
+	_, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part, err := multipart.NewReader(body, params["boundary"]).NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.ReadRequest(bufio.NewReader(part))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qry := req.URL.Query(); qry.Get("quotaUser") != "quota-1" || qry.Get("format") != MessageFormatMetadata {
+		t.Errorf("batchGetMessagesRequestBody() query mismatch: want (format=metadata&quotaUser=quota-1), got (%s)", req.URL.RawQuery)
+	}
+}
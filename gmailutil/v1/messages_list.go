@@ -1,6 +1,8 @@
 package gmailutil
 
 import (
+	"context"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,6 +40,8 @@ const (
 
 Warning: All dates used in the search query are interpretted as midnight on that date in the PST timezone. To specify accurate dates for other timezones pass the value in seconds instead:
 
+See `MessagesListQueryOpts.DateEpoch`.
+
 */
 
 type MessagesListOpts struct {
@@ -49,6 +53,7 @@ type MessagesListOpts struct {
 	Query                MessagesListQueryOpts
 	Fields               []googleapi.Field
 	IfNoneMatchEntityTag string
+	MaxTotal             int // total cap across pages for `ListAll()`, 0 for no cap
 }
 
 func (opts *MessagesListOpts) Condense() {
@@ -64,16 +69,34 @@ func (opts *MessagesListOpts) Inflate() {
 	}
 }
 
+// MessagesListQueryOpts is a convenience front-end for building a Gmail search `Query`.
+// All non-empty fields are combined with AND. Use `Expr` for expressions that cannot be
+// represented by the flat fields, such as `OR` and negation.
 type MessagesListQueryOpts struct {
-	Category    string
-	In          string
-	From        string
-	RFC822msgid string
-	After       time.Time
-	Before      time.Time
-	OlderThan   string // #(mdy)
-	NewerThan   string // #(mdy)
-	Interval    timeutil.Interval
+	Category      string
+	In            string
+	From          string
+	RFC822msgid   string
+	After         time.Time
+	Before        time.Time
+	OlderThan     string            // #(mdy)
+	NewerThan     string            // #(mdy)
+	Interval      timeutil.Interval // with `After` and no `Before`, queries the day, week, month, quarter or year containing `After`
+	DateEpoch     bool              // encode `after:` and `before:` as epoch seconds for exact, timezone-aware windows
+	To            string
+	Cc            string
+	Bcc           string
+	Subject       string
+	Label         string
+	List          string
+	Filename      string
+	Larger        string // e.g. "10M"
+	Smaller       string // e.g. "100K"
+	HasAttachment bool
+	IsUnread      bool
+	IsStarred     bool
+	IsImportant   bool
+	Expr          Query
 }
 
 func (opts *MessagesListQueryOpts) TrimSpace() {
@@ -83,40 +106,115 @@ func (opts *MessagesListQueryOpts) TrimSpace() {
 	opts.RFC822msgid = strings.TrimSpace(opts.RFC822msgid)
 	opts.OlderThan = strings.TrimSpace(opts.OlderThan)
 	opts.NewerThan = strings.TrimSpace(opts.NewerThan)
+	opts.To = strings.TrimSpace(opts.To)
+	opts.Cc = strings.TrimSpace(opts.Cc)
+	opts.Bcc = strings.TrimSpace(opts.Bcc)
+	opts.Subject = strings.TrimSpace(opts.Subject)
+	opts.Label = strings.TrimSpace(opts.Label)
+	opts.List = strings.TrimSpace(opts.List)
+	opts.Filename = strings.TrimSpace(opts.Filename)
+	opts.Larger = strings.TrimSpace(opts.Larger)
+	opts.Smaller = strings.TrimSpace(opts.Smaller)
 }
 
-func (opts *MessagesListQueryOpts) Encode() string {
+// Query returns the options as a `QueryAnd` expression.
+func (opts *MessagesListQueryOpts) Query() QueryAnd {
 	opts.TrimSpace()
-	parts := []string{}
-
-	if len(opts.Category) > 0 {
-		parts = append(parts, "category:"+opts.Category)
+	q := QueryAnd{}
+	addTerm := func(op, val string) {
+		if val != "" {
+			q = append(q, NewQueryTerm(op, val))
+		}
 	}
-	if len(opts.From) > 0 {
-		parts = append(parts, "from:"+opts.From)
+	addTerm(OperatorCategory, opts.Category)
+	addTerm(OperatorFrom, opts.From)
+	addTerm(OperatorIn, opts.In)
+	addTerm(OperatorRFC822msgid, opts.RFC822msgid)
+	addTerm(OperatorOlderThan, opts.OlderThan)
+	addTerm(OperatorNewerThan, opts.NewerThan)
+	after, before := opts.DateRange()
+	if !timeutil.NewTimeMore(after, 0).IsZeroAny() {
+		addTerm(OperatorAfter, encodeQueryTime(after, opts.DateEpoch))
 	}
-	if len(opts.In) > 0 {
-		parts = append(parts, "in:"+opts.In)
+	if !timeutil.NewTimeMore(before, 0).IsZeroAny() {
+		addTerm(OperatorBefore, encodeQueryTime(before, opts.DateEpoch))
 	}
-	if len(opts.RFC822msgid) > 0 {
-		parts = append(parts, "rfc822msgid:"+opts.RFC822msgid)
+	addTerm(OperatorTo, opts.To)
+	addTerm(OperatorCc, opts.Cc)
+	addTerm(OperatorBcc, opts.Bcc)
+	addTerm(OperatorSubject, opts.Subject)
+	addTerm(OperatorLabel, opts.Label)
+	addTerm(OperatorList, opts.List)
+	addTerm(OperatorFilename, opts.Filename)
+	addTerm(OperatorLarger, opts.Larger)
+	addTerm(OperatorSmaller, opts.Smaller)
+	if opts.HasAttachment {
+		addTerm(OperatorHas, HasAttachment)
 	}
-	if len(opts.OlderThan) > 0 {
-		parts = append(parts, "older_than:"+opts.OlderThan)
+	if opts.IsUnread {
+		addTerm(OperatorIs, IsUnread)
 	}
-	if len(opts.NewerThan) > 0 {
-		parts = append(parts, "newer_than:"+opts.NewerThan)
+	if opts.IsStarred {
+		addTerm(OperatorIs, IsStarred)
 	}
-	if !timeutil.NewTimeMore(opts.After, 0).IsZeroAny() {
-		parts = append(parts, "after:"+opts.After.Format(GmailDateFormat))
+	if opts.IsImportant {
+		addTerm(OperatorIs, IsImportant)
 	}
-	if !timeutil.NewTimeMore(opts.Before, 0).IsZeroAny() {
-		parts = append(parts, "before:"+opts.Before.Format(GmailDateFormat))
+	if opts.Expr != nil {
+		q = append(q, opts.Expr)
+	}
+	return q
+}
+
+// DateRange returns the `after:` and `before:` times for the query. If `Before` is zero and
+// `Interval` is `timeutil.IntervalDay`, `IntervalWeek`, `IntervalMonth`, `IntervalQuarter` or
+// `IntervalYear`, the range is the interval containing `After`, in the location of `After`.
+// Weeks start on Sunday. Other intervals are ignored.
+func (opts *MessagesListQueryOpts) DateRange() (time.Time, time.Time) {
+	if timeutil.NewTimeMore(opts.After, 0).IsZeroAny() ||
+		!timeutil.NewTimeMore(opts.Before, 0).IsZeroAny() {
+		return opts.After, opts.Before
 	}
-	return strings.TrimSpace(strings.Join(parts, " "))
+	var years, months, days int
+	switch opts.Interval {
+	case timeutil.IntervalDay:
+		days = 1
+	case timeutil.IntervalWeek:
+		days = 7
+	case timeutil.IntervalMonth:
+		months = 1
+	case timeutil.IntervalQuarter:
+		months = 3
+	case timeutil.IntervalYear:
+		years = 1
+	default:
+		return opts.After, opts.Before
+	}
+	start, err := timeutil.NewTimeMore(opts.After, time.Sunday).IntervalStart(opts.Interval)
+	if err != nil {
+		return opts.After, opts.Before
+	}
+	return start, start.AddDate(years, months, days)
+}
+
+// encodeQueryTime formats `t` as epoch seconds or as a `GmailDateFormat` date in the
+// location of `t`, which Gmail interprets as midnight PST.
+func encodeQueryTime(t time.Time, epoch bool) string {
+	if epoch {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.Format(GmailDateFormat)
+}
+
+func (opts *MessagesListQueryOpts) Encode() string {
+	return strings.TrimSpace(opts.Query().Encode())
 }
 
 func (mapi *MessagesAPI) GetMessagesList(opts MessagesListOpts) (*gmail.ListMessagesResponse, error) {
+	return mapi.getMessagesList(context.Background(), opts)
+}
+
+func (mapi *MessagesAPI) getMessagesList(ctx context.Context, opts MessagesListOpts) (*gmail.ListMessagesResponse, error) {
 	if mapi.GmailService == nil {
 		return nil, ErrGmailServiceCannotBeNil
 	}
@@ -140,6 +238,7 @@ func (mapi *MessagesAPI) GetMessagesList(opts MessagesListOpts) (*gmail.ListMess
 	if len(opts.Fields) > 0 {
 		userMessagesListCall.Fields(opts.Fields...)
 	}
+	userMessagesListCall.Context(ctx)
 	if resp, err := userMessagesListCall.Do(mapi.GmailService.APICallOptions...); err != nil {
 		return resp, errorsutil.Wrap(err, "func GetMessagesList() call to userMessagesListCall.Do()")
 	} else {
@@ -147,30 +246,18 @@ func (mapi *MessagesAPI) GetMessagesList(opts MessagesListOpts) (*gmail.ListMess
 	}
 }
 
+// GetMessagesFrom returns all messages from `rfc822`, across all result pages, in a single
+// `*gmail.ListMessagesResponse`.
 func (mapi *MessagesAPI) GetMessagesFrom(rfc822 string) (*gmail.ListMessagesResponse, error) {
 	opts := MessagesListOpts{
 		Query: MessagesListQueryOpts{
 			From: rfc822},
 	}
-	if resp, err := mapi.GetMessagesList(opts); err != nil {
-		return resp, errorsutil.Wrap(err, "func GetMessagesFrom() call to mapi.GetMessagesList()")
+	if msgs, err := mapi.GetMessagesListAll(context.Background(), opts); err != nil {
+		return nil, errorsutil.Wrap(err, "func GetMessagesFrom() call to mapi.GetMessagesListAll()")
 	} else {
-		return resp, nil
-	}
-}
-
-func (mapi *MessagesAPI) InflateMessages(userID string, msgMetas []*gmail.Message) ([]*gmail.Message, error) {
-	if mapi.GmailService == nil {
-		return nil, ErrGmailServiceCannotBeNil
-	}
-
-	msgs := []*gmail.Message{}
-	for _, msgMeta := range msgMetas {
-		msg, err := mapi.GetMessage(userID, msgMeta.Id)
-		if err != nil {
-			return msgs, err
-		}
-		msgs = append(msgs, msg)
+		return &gmail.ListMessagesResponse{
+			Messages:           msgs,
+			ResultSizeEstimate: int64(len(msgs))}, nil
 	}
-	return msgs, nil
 }
@@ -0,0 +1,97 @@
+package gmailutil
+
+import (
+	"context"
+	"iter"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+// MessagesListDefaultPageSize is the Gmail API default for `maxResults` on `users.messages.list`.
+const MessagesListDefaultPageSize = 100
+
+// ListAll returns an iterator over all messages matching `opts`, following `NextPageToken`
+// until the result set is exhausted, `opts.MaxTotal` messages have been yielded, or `ctx`
+// is cancelled. Messages are yielded as returned by `users.messages.list` which includes
+// only `Id` and `ThreadId`. Iteration stops after the first error is yielded.
+func (mapi *MessagesAPI) ListAll(ctx context.Context, opts MessagesListOpts) iter.Seq2[*gmail.Message, error] {
+	return listAll(ctx, opts, func(opts MessagesListOpts) ([]*gmail.Message, string, error) {
+		if mapi.GmailService == nil {
+			return nil, "", ErrGmailServiceCannotBeNil
+		}
+		resp, err := mapi.getMessagesList(ctx, opts)
+		if err != nil {
+			return nil, "", errorsutil.Wrap(err, "func ListAll() call to mapi.getMessagesList()")
+		}
+		return resp.Messages, resp.NextPageToken, nil
+	})
+}
+
+// listAll returns an iterator over the items of a paged `users.*.list` call. `fetch` is
+// called for each page with `opts.PageToken` set, and `opts.MaxResults` reduced so that no
+// more than `opts.MaxTotal` items are requested. It returns the page items and the next
+// page token. Iteration stops after the first error is yielded.
+func listAll[T any](ctx context.Context, opts MessagesListOpts, fetch func(opts MessagesListOpts) ([]T, string, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		count := 0
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			if opts.MaxTotal > 0 {
+				pageSize := opts.MaxResults
+				if pageSize <= 0 {
+					pageSize = MessagesListDefaultPageSize
+				}
+				if remaining := opts.MaxTotal - count; remaining < pageSize {
+					opts.MaxResults = remaining
+				}
+			}
+			items, nextPageToken, err := fetch(opts)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+				count++
+				if opts.MaxTotal > 0 && count >= opts.MaxTotal {
+					return
+				}
+			}
+			if nextPageToken == "" {
+				return
+			}
+			opts.PageToken = nextPageToken
+		}
+	}
+}
+
+// GetMessagesListAll collects the results of `ListAll()` into a slice.
+func (mapi *MessagesAPI) GetMessagesListAll(ctx context.Context, opts MessagesListOpts) ([]*gmail.Message, error) {
+	var msgs []*gmail.Message
+	for msg, err := range mapi.ListAll(ctx, opts) {
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// GetMessageIDsAll returns the IDs of all messages matching `opts`.
+func (mapi *MessagesAPI) GetMessageIDsAll(ctx context.Context, opts MessagesListOpts) ([]string, error) {
+	var ids []string
+	for msg, err := range mapi.ListAll(ctx, opts) {
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, msg.Id)
+	}
+	return ids, nil
+}
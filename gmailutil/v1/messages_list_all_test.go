@@ -0,0 +1,107 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+// pagedMessagesHandler serves `total` messages in pages of `pageSize`, using the
+// page offset as the page token.
+func pagedMessagesHandler(t *testing.T, total, pageSize int, calls *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		offset := 0
+		if tok := r.URL.Query().Get("pageToken"); tok != "" {
+			if v, err := strconv.Atoi(tok); err != nil {
+				t.Errorf("invalid page token (%s)", tok)
+			} else {
+				offset = v
+			}
+		}
+		size := pageSize
+		if mr := r.URL.Query().Get("maxResults"); mr != "" {
+			if v, err := strconv.Atoi(mr); err == nil && v < size {
+				size = v
+			}
+		}
+		resp := gmail.ListMessagesResponse{}
+		for i := offset; i < total && i < offset+size; i++ {
+			resp.Messages = append(resp.Messages, &gmail.Message{Id: fmt.Sprintf("msg%d", i)})
+		}
+		if offset+size < total {
+			resp.NextPageToken = strconv.Itoa(offset + size)
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+var listAllTests = []struct {
+	total     int
+	pageSize  int
+	maxTotal  int
+	wantCount int
+	wantCalls int
+}{
+	{0, 10, 0, 0, 1},
+	{25, 10, 0, 25, 3},
+	{30, 10, 0, 30, 3},
+	{25, 10, 15, 15, 2},
+	{25, 10, 10, 10, 1},
+}
+
+func TestListAll(t *testing.T) {
+	for _, tt := range listAllTests {
+		calls := 0
+		gs := newTestGmailService(t, pagedMessagesHandler(t, tt.total, tt.pageSize, &calls))
+		msgs, err := gs.MessagesAPI.GetMessagesListAll(context.Background(), MessagesListOpts{MaxTotal: tt.maxTotal})
+		if err != nil {
+			t.Fatalf("MessagesAPI.GetMessagesListAll() error: [%v]", err)
+		}
+		if len(msgs) != tt.wantCount {
+			t.Errorf("MessagesAPI.GetMessagesListAll(total=%d,maxTotal=%d) count mismatch: want [%d] got [%d]",
+				tt.total, tt.maxTotal, tt.wantCount, len(msgs))
+		}
+		if calls != tt.wantCalls {
+			t.Errorf("MessagesAPI.GetMessagesListAll(total=%d,maxTotal=%d) call count mismatch: want [%d] got [%d]",
+				tt.total, tt.maxTotal, tt.wantCalls, calls)
+		}
+		for i, msg := range msgs {
+			if want := fmt.Sprintf("msg%d", i); msg.Id != want {
+				t.Errorf("MessagesAPI.GetMessagesListAll() id mismatch: want [%s] got [%s]", want, msg.Id)
+			}
+		}
+	}
+}
+
+func TestListAllContextCancel(t *testing.T) {
+	calls := 0
+	gs := newTestGmailService(t, pagedMessagesHandler(t, 100, 10, &calls))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	count := 0
+	var gotErr error
+	for _, err := range gs.MessagesAPI.ListAll(ctx, MessagesListOpts{}) {
+		if err != nil {
+			gotErr = err
+			break
+		}
+		count++
+		if count == 10 {
+			cancel()
+		}
+	}
+	if gotErr == nil {
+		t.Errorf("MessagesAPI.ListAll() expected context error after cancel")
+	}
+	if count != 10 {
+		t.Errorf("MessagesAPI.ListAll() count mismatch after cancel: want [10] got [%d]", count)
+	}
+}
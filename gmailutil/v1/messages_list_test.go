@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"testing"
 	"time"
+	_ "time/tzdata"
+
+	"github.com/grokify/mogo/time/timeutil"
 )
 
 var listQueryStringTests = []struct {
@@ -45,3 +48,56 @@ func TestGenerateMessageListQueryString(t *testing.T) {
 		}
 	}
 }
+
+var listQueryDateEpochTests = []struct {
+	location string
+	after    string // RFC 3339 local time without offset
+	before   string
+	interval timeutil.Interval
+	qString  string
+}{
+	// Explicit window in a non-US zone.
+	{"Asia/Kolkata", "2024-01-15T09:30:00", "2024-01-15T17:45:00", 0, "after:1705291200 before:1705320900"},
+	// Day containing the US DST start is 23 hours long.
+	{"America/Los_Angeles", "2024-03-10T12:00:00", "", timeutil.IntervalDay, "after:1710057600 before:1710140400"},
+	// Day containing the EU DST end is 25 hours long.
+	{"Europe/Berlin", "2024-10-27T08:00:00", "", timeutil.IntervalDay, "after:1729980000 before:1730070000"},
+	// Week (starting Sunday) ending at midnight before the US DST start.
+	{"America/New_York", "2024-03-06T15:00:00", "", timeutil.IntervalWeek, "after:1709442000 before:1710046800"},
+	{"UTC", "2024-02-14T00:00:00", "", timeutil.IntervalMonth, "after:1706745600 before:1709251200"},
+	// Quarter spanning the southern hemisphere DST start.
+	{"Australia/Sydney", "2024-11-15T00:00:00", "", timeutil.IntervalQuarter, "after:1727704800 before:1735650000"},
+	// Interval is ignored when Before is set.
+	{"UTC", "2024-02-01T00:00:00", "2024-02-02T00:00:00", timeutil.IntervalYear, "after:1706745600 before:1706832000"},
+}
+
+func TestMessageListQueryDateEpoch(t *testing.T) {
+	for _, tt := range listQueryDateEpochTests {
+		loc, err := time.LoadLocation(tt.location)
+		if err != nil {
+			t.Fatalf("time.LoadLocation(%q) error: [%v]", tt.location, err)
+		}
+		qOptions := MessagesListQueryOpts{DateEpoch: true, Interval: tt.interval}
+		if qOptions.After, err = time.ParseInLocation("2006-01-02T15:04:05", tt.after, loc); err != nil {
+			t.Fatal(err)
+		}
+		if tt.before != "" {
+			if qOptions.Before, err = time.ParseInLocation("2006-01-02T15:04:05", tt.before, loc); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if gotString := qOptions.Encode(); gotString != tt.qString {
+			t.Errorf("MessagesListQueryOpts.Encode(%s,%s,%s,%s) mismatch: want [%v] got [%v]",
+				tt.location, tt.after, tt.before, tt.interval, tt.qString, gotString)
+		}
+	}
+}
+
+func TestMessageListQueryIntervalDate(t *testing.T) {
+	qOptions := MessagesListQueryOpts{
+		After:    time.Date(2024, 2, 14, 10, 0, 0, 0, time.UTC),
+		Interval: timeutil.IntervalMonth}
+	if want, got := "after:2024/02/01 before:2024/03/01", qOptions.Encode(); got != want {
+		t.Errorf("MessagesListQueryOpts.Encode() mismatch: want [%v] got [%v]", want, got)
+	}
+}
@@ -0,0 +1,372 @@
+package gmailutil
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+
+	"github.com/grokify/mogo/mime/multipartutil"
+	"github.com/grokify/mogo/net/http/httputilmore"
+	"github.com/grokify/mogo/net/mailutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+// QuoteDateFormat is the date format used in reply attribution lines and forwarded
+// message headers.
+const QuoteDateFormat = "Mon, Jan 2, 2006 at 3:04 PM"
+
+// ReplyOpts configures `Reply()`, `ReplyAll()` and `Forward()`.
+type ReplyOpts struct {
+	BodyText     string             // new text, placed above the quoted original
+	BodyHTML     string             // new HTML, placed above the quoted original; if empty, only a text body is sent
+	To           mailutil.Addresses // forward recipients; for replies, replaces the computed recipients
+	Cc           mailutil.Addresses // added to the computed recipients
+	Bcc          mailutil.Addresses
+	OwnAddresses []string // addresses excluded from reply-all; defaults to the account address
+	NoQuote      bool     // do not quote the original body
+}
+
+// Reply replies to the sender of message `messageID`, or to its `Reply-To` address,
+// in the same thread.
+func (gs GmailService) Reply(ctx context.Context, userID, messageID string, opts ReplyOpts) (*gmail.Message, error) {
+	return gs.reply(ctx, userID, messageID, false, opts)
+}
+
+// ReplyAll replies to the sender and all `To` and `Cc` recipients of message `messageID`,
+// excluding `opts.OwnAddresses`, in the same thread.
+func (gs GmailService) ReplyAll(ctx context.Context, userID, messageID string, opts ReplyOpts) (*gmail.Message, error) {
+	return gs.reply(ctx, userID, messageID, true, opts)
+}
+
+func (gs GmailService) reply(ctx context.Context, userID, messageID string, all bool, opts ReplyOpts) (*gmail.Message, error) {
+	userID = labelUserID(userID)
+	orig, err := gs.getParsedMessage(ctx, userID, messageID, MessageFormatFull)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.To) == 0 && len(opts.OwnAddresses) == 0 {
+		if opts.OwnAddresses, err = gs.ownAddresses(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	msg, err := NewReplyMessage(orig, all, opts)
+	if err != nil {
+		return nil, err
+	}
+	return gs.SendInThread(ctx, userID, orig.ThreadID, msg)
+}
+
+// Forward forwards message `messageID`, including its attachments, to `opts.To` in the
+// same thread.
+func (gs GmailService) Forward(ctx context.Context, userID, messageID string, opts ReplyOpts) (*gmail.Message, error) {
+	userID = labelUserID(userID)
+	orig, err := gs.getParsedMessage(ctx, userID, messageID, MessageFormatRaw)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := NewForwardMessage(orig, opts)
+	if err != nil {
+		return nil, err
+	}
+	return gs.SendInThread(ctx, userID, orig.ThreadID, msg)
+}
+
+func (gs GmailService) getParsedMessage(ctx context.Context, userID, messageID, format string) (*ParsedMessage, error) {
+	if err := gs.validateConfig(); err != nil {
+		return nil, err
+	}
+	msg, err := gs.MessagesAPI.GetMessageWithOpts(ctx, userID, messageID, &GetMessageOpts{Format: format})
+	if err != nil {
+		return nil, err
+	}
+	return ParseMessage(msg)
+}
+
+// ownAddresses returns the account address from `users.getProfile`.
+func (gs GmailService) ownAddresses(ctx context.Context, userID string) ([]string, error) {
+	profile, err := gs.UsersService.GetProfile(userID).Context(ctx).Do(gs.APICallOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return []string{profile.EmailAddress}, nil
+}
+
+// NewReplyMessage builds a reply to `orig`. Recipients are the `Reply-To` or `From` of
+// `orig`, plus its `To` and `Cc` recipients if `all` is set, excluding `opts.OwnAddresses`.
+// When replying to a message sent from an own address, the reply goes to the original
+// recipients instead. The subject is prefixed with `Re: `, threading headers are set,
+// and the original body is quoted unless `opts.NoQuote` is set.
+func NewReplyMessage(orig *ParsedMessage, all bool, opts ReplyOpts) (mailutil.MessageWriter, error) {
+	msg := mailutil.MessageWriter{Header: textproto.MIMEHeader{}}
+	if orig == nil {
+		return msg, fmt.Errorf("original message cannot be nil")
+	}
+	if len(opts.To) > 0 {
+		msg.To = opts.To
+	} else {
+		own := newAddressSet(opts.OwnAddresses)
+		fromSelf := orig.From != nil && own.has(orig.From.Address)
+		switch {
+		case fromSelf:
+			msg.To = orig.To
+		case len(orig.ReplyTo) > 0:
+			msg.To = orig.ReplyTo
+		case orig.From != nil:
+			msg.To = mailutil.Addresses{*orig.From}
+		}
+		if all {
+			if !fromSelf {
+				msg.To = append(slices.Clone(msg.To), orig.To...)
+			}
+			msg.Cc = orig.Cc
+		}
+		msg.To = own.exclude(msg.To)
+		msg.Cc = own.exclude(msg.Cc)
+		if len(msg.To) == 0 {
+			msg.To, msg.Cc = msg.Cc, nil
+		}
+	}
+	msg.Cc = append(msg.Cc, opts.Cc...)
+	msg.Bcc = opts.Bcc
+	if len(msg.To) == 0 {
+		return msg, fmt.Errorf("no recipients for reply to message id (%s)", orig.ID)
+	}
+	SetReplyHeaders(&msg, orig)
+
+	text, htmlBody := opts.BodyText, opts.BodyHTML
+	if !opts.NoQuote {
+		attribution := replyAttribution(orig)
+		text = joinBody(text, attribution+"\n"+QuoteText(orig.BodyText))
+		if htmlBody != "" {
+			htmlBody += `<div class="gmail_quote"><div class="gmail_attr">` + html.EscapeString(attribution) +
+				`<br></div><blockquote class="gmail_quote" style="margin:0 0 0 .8ex;border-left:1px #ccc solid;padding-left:1ex">` +
+				origHTML(orig) + `</blockquote></div>`
+		}
+	}
+	ps, err := newBodyPartsSet(text, htmlBody, nil)
+	if err != nil {
+		return msg, err
+	}
+	msg.BodyPartsSet = ps
+	return msg, nil
+}
+
+// NewForwardMessage builds a forward of `orig` to `opts.To`. The subject is prefixed
+// with `Fwd: `, the original headers and body are included below the new body, and
+// attachments with data, such as those of a message retrieved with `MessageFormatRaw`,
+// are carried over. Threading headers are set so the forward stays in the thread.
+func NewForwardMessage(orig *ParsedMessage, opts ReplyOpts) (mailutil.MessageWriter, error) {
+	msg := mailutil.MessageWriter{
+		To:     opts.To,
+		Cc:     opts.Cc,
+		Bcc:    opts.Bcc,
+		Header: textproto.MIMEHeader{}}
+	if orig == nil {
+		return msg, fmt.Errorf("original message cannot be nil")
+	} else if len(msg.To) == 0 {
+		return msg, fmt.Errorf("forward recipients cannot be empty")
+	}
+	msg.Subject = ForwardSubject(orig.Subject)
+	SetReplyHeaders(&msg, orig)
+
+	var fields [][2]string
+	if orig.From != nil {
+		fields = append(fields, [2]string{mailutil.HeaderFrom, displayAddress(*orig.From)})
+	}
+	if !orig.Date.IsZero() {
+		fields = append(fields, [2]string{HeaderDate, orig.Date.Format(QuoteDateFormat)})
+	}
+	fields = append(fields, [2]string{mailutil.HeaderSubject, orig.Subject})
+	if len(orig.To) > 0 {
+		fields = append(fields, [2]string{mailutil.HeaderTo, addressesString(orig.To)})
+	}
+	if len(orig.Cc) > 0 {
+		fields = append(fields, [2]string{mailutil.HeaderCc, addressesString(orig.Cc)})
+	}
+
+	textHeader := []string{"---------- Forwarded message ---------"}
+	htmlHeader := []string{"---------- Forwarded message ---------"}
+	for _, f := range fields {
+		textHeader = append(textHeader, f[0]+": "+f[1])
+		htmlHeader = append(htmlHeader, f[0]+": "+html.EscapeString(f[1]))
+	}
+	text := joinBody(opts.BodyText, strings.Join(textHeader, "\n")+"\n\n"+trimBody(orig.BodyText))
+	htmlBody := opts.BodyHTML
+	if htmlBody != "" {
+		htmlBody += `<div class="gmail_quote"><div class="gmail_attr">` + strings.Join(htmlHeader, "<br>") +
+			`<br></div><br>` + origHTML(orig) + `</div>`
+	}
+
+	var parts multipartutil.Parts
+	for _, att := range orig.Attachments {
+		if len(att.Data) == 0 && att.Size > 0 {
+			return msg, fmt.Errorf("attachment data not loaded for part (%s); retrieve the message with MessageFormatRaw", att.PartID)
+		}
+		parts = append(parts, forwardAttachmentPart(att))
+	}
+	ps, err := newBodyPartsSet(text, htmlBody, parts)
+	if err != nil {
+		return msg, err
+	}
+	msg.BodyPartsSet = ps
+	return msg, nil
+}
+
+// ForwardSubject returns `subject` with a `Fwd: ` prefix, unless it already has a
+// `Fwd:` or `Fw:` prefix.
+func ForwardSubject(subject string) string {
+	subject = strings.TrimSpace(subject)
+	lower := strings.ToLower(subject)
+	if strings.HasPrefix(lower, "fwd:") || strings.HasPrefix(lower, "fw:") {
+		return subject
+	}
+	return "Fwd: " + subject
+}
+
+// QuoteText prefixes each line of `text` with `> `.
+func QuoteText(text string) string {
+	lines := strings.Split(trimBody(text), "\n")
+	for i, line := range lines {
+		if line == "" || strings.HasPrefix(line, ">") {
+			lines[i] = ">" + line
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func replyAttribution(orig *ParsedMessage) string {
+	from := "unknown sender"
+	if orig.From != nil {
+		from = displayAddress(*orig.From)
+	}
+	if orig.Date.IsZero() {
+		return from + " wrote:"
+	}
+	return "On " + orig.Date.Format(QuoteDateFormat) + " " + from + " wrote:"
+}
+
+// origHTML returns the HTML body of `orig`, or its text body converted to HTML.
+func origHTML(orig *ParsedMessage) string {
+	if orig.BodyHTML != "" {
+		return orig.BodyHTML
+	}
+	return strings.ReplaceAll(html.EscapeString(trimBody(orig.BodyText)), "\n", "<br>")
+}
+
+// trimBody converts CRLF line endings to LF and removes trailing newlines.
+func trimBody(body string) string {
+	return strings.TrimRight(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+}
+
+func joinBody(body, quoted string) string {
+	if body = strings.TrimRight(body, "\r\n"); body == "" {
+		return quoted + "\n"
+	}
+	return body + "\n\n" + quoted + "\n"
+}
+
+// newBodyPartsSet returns a `multipart/mixed` set with a text body, or text and HTML
+// alternatives, followed by `parts`. Unlike `multipartutil.NewPartsSetMail()`, an empty
+// HTML body is omitted and both bodies declare UTF-8.
+func newBodyPartsSet(text, htmlBody string, parts multipartutil.Parts) (multipartutil.PartsSet, error) {
+	body := multipartutil.Part{
+		Type:        multipartutil.PartTypeRaw,
+		ContentType: httputilmore.ContentTypeTextPlainUtf8,
+		BodyDataRaw: []byte(text)}
+	if htmlBody != "" {
+		alt := multipartutil.NewPartsSet(httputilmore.ContentTypeMultipartAlternative)
+		alt.Parts = append(alt.Parts, body, multipartutil.Part{
+			Type:        multipartutil.PartTypeRaw,
+			ContentType: httputilmore.ContentTypeTextHTMLUtf8,
+			BodyDataRaw: []byte(htmlBody)})
+		var err error
+		if body, err = alt.Part(); err != nil {
+			return multipartutil.PartsSet{}, err
+		}
+	}
+	ps := multipartutil.NewPartsSet(httputilmore.ContentTypeMultipartMixed)
+	ps.Parts = append(multipartutil.Parts{body}, parts...)
+	return ps, nil
+}
+
+func forwardAttachmentPart(att ParsedAttachment) multipartutil.Part {
+	disposition := httputilmore.DispositionTypeAttachment
+	if att.Inline {
+		disposition = httputilmore.DispositionTypeInline
+	}
+	header := textproto.MIMEHeader{}
+	dispParams := map[string]string{}
+	if att.Filename != "" {
+		dispParams["filename"] = att.Filename
+	}
+	header.Set(httputilmore.HeaderContentDisposition, mime.FormatMediaType(disposition, dispParams))
+	if att.ContentID != "" {
+		header.Set(httputilmore.HeaderContentID, "<"+att.ContentID+">")
+	}
+	mimeType := att.MIMEType
+	if att.Filename != "" {
+		mimeType = mime.FormatMediaType(att.MIMEType, map[string]string{"name": att.Filename})
+	}
+	return multipartutil.Part{
+		Type:             multipartutil.PartTypeRaw,
+		ContentType:      mimeType,
+		HeaderRaw:        header,
+		BodyDataRaw:      att.Data,
+		BodyEncodeBase64: true}
+}
+
+// displayAddress formats an address for display in a message body. Unlike
+// `mail.Address.String()`, the name is not MIME encoded.
+func displayAddress(addr mail.Address) string {
+	if addr.Name == "" {
+		return addr.Address
+	}
+	return addr.Name + " <" + addr.Address + ">"
+}
+
+func addressesString(addrs mailutil.Addresses) string {
+	var s []string
+	for _, a := range addrs {
+		s = append(s, displayAddress(a))
+	}
+	return strings.Join(s, ", ")
+}
+
+// addressSet matches email addresses case-insensitively.
+type addressSet map[string]struct{}
+
+func newAddressSet(addrs []string) addressSet {
+	set := addressSet{}
+	for _, a := range addrs {
+		if a = strings.ToLower(strings.TrimSpace(a)); a != "" {
+			set[a] = struct{}{}
+		}
+	}
+	return set
+}
+
+func (set addressSet) has(addr string) bool {
+	_, ok := set[strings.ToLower(strings.TrimSpace(addr))]
+	return ok
+}
+
+// exclude returns `addrs` which are not in the set and not repeated, adding each
+// returned address to the set.
+func (set addressSet) exclude(addrs mailutil.Addresses) mailutil.Addresses {
+	var out mailutil.Addresses
+	for _, a := range addrs {
+		if a.Address == "" || set.has(a.Address) {
+			continue
+		}
+		out = append(out, a)
+		set[strings.ToLower(strings.TrimSpace(a.Address))] = struct{}{}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/grokify/mogo/net/mailutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+var rxBoundary = regexp.MustCompile(`boundary=([0-9a-f]+)`)
+
+// normalizeRFC822 makes generated message bytes comparable by sorting the top-level
+// header lines, which are written in map order, and renaming random MIME boundaries.
+func normalizeRFC822(raw []byte) string {
+	s := string(raw)
+	for i, m := range rxBoundary.FindAllStringSubmatch(s, -1) {
+		s = strings.ReplaceAll(s, m[1], "BOUNDARY"+strconv.Itoa(i+1))
+	}
+	header, body, _ := strings.Cut(s, "\n\n")
+	lines := strings.Split(header, "\n")
+	slices.Sort(lines)
+	return strings.Join(lines, "\n") + "\n\n" + body
+}
+
+// newTestReplyService serves `testdata/message_multipart.eml` as message `msg-3` and
+// captures sent messages.
+func newTestReplyService(t *testing.T, sent *gmail.Message) *GmailService {
+	t.Helper()
+	eml, err := os.ReadFile(filepath.Join("testdata", "message_multipart.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/me/messages/msg-3", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&gmail.Message{
+			Id:       "msg-3",
+			ThreadId: "thread-3",
+			Raw:      base64.URLEncoding.EncodeToString(eml)})
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/profile", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&gmail.Profile{EmailAddress: "Bob@example.com"})
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/messages/send", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(sent); err != nil {
+			t.Error(err)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&gmail.Message{Id: "msg-4", ThreadId: sent.ThreadId})
+	})
+	return newTestGmailService(t, mux)
+}
+
+func TestReplyForward(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		golden string
+		send   func(gs *GmailService) (*gmail.Message, error)
+	}{
+		{"Reply", "reply.eml", func(gs *GmailService) (*gmail.Message, error) {
+			return gs.Reply(context.Background(), "", "msg-3", ReplyOpts{BodyText: "Danke!"})
+		}},
+		{"ReplyAll", "reply_all.eml", func(gs *GmailService) (*gmail.Message, error) {
+			return gs.ReplyAll(context.Background(), "", "msg-3", ReplyOpts{
+				BodyText: "Thanks, looks good.",
+				BodyHTML: "<p>Thanks, looks <b>good</b>.</p>"})
+		}},
+		{"Forward", "forward.eml", func(gs *GmailService) (*gmail.Message, error) {
+			return gs.Forward(context.Background(), "", "msg-3", ReplyOpts{
+				BodyText: "FYI",
+				To:       mailutil.Addresses{{Name: "Dave", Address: "dave@example.com"}}})
+		}},
+	} {
+		sent := &gmail.Message{}
+		gs := newTestReplyService(t, sent)
+		if _, err := tt.send(gs); err != nil {
+			t.Fatalf("%s() error: [%v]", tt.name, err)
+		}
+		if sent.ThreadId != "thread-3" {
+			t.Errorf("%s() thread id mismatch: want [thread-3] got [%s]", tt.name, sent.ThreadId)
+		}
+		raw, err := base64.URLEncoding.DecodeString(sent.Raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile(filepath.Join("testdata", tt.golden))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := normalizeRFC822(raw); got != string(want) {
+			t.Errorf("%s() RFC 822 mismatch:\nwant:\n%s\ngot:\n%s", tt.name, want, got)
+		}
+	}
+}
+
+func TestNewReplyMessageRecipients(t *testing.T) {
+	orig := &ParsedMessage{
+		From:    &mailutil.Addresses{{Address: "me@example.com"}}[0],
+		To:      mailutil.Addresses{{Address: "a@example.com"}, {Address: "ME@example.com"}},
+		Cc:      mailutil.Addresses{{Address: "a@example.com"}, {Address: "b@example.com"}},
+		Subject: "Re: Plans"}
+	for _, tt := range []struct {
+		all    bool
+		wantTo string
+		wantCc string
+	}{
+		{false, "a@example.com", ""},
+		{true, "a@example.com", "b@example.com"},
+	} {
+		msg, err := NewReplyMessage(orig, tt.all, ReplyOpts{OwnAddresses: []string{"me@example.com"}, NoQuote: true})
+		if err != nil {
+			t.Fatalf("NewReplyMessage() error: [%v]", err)
+		}
+		if got := strings.Join(msg.To.Strings(true, false, false), ","); got != tt.wantTo {
+			t.Errorf("NewReplyMessage(all=%v) To mismatch: want [%s] got [%s]", tt.all, tt.wantTo, got)
+		}
+		if got := strings.Join(msg.Cc.Strings(true, false, false), ","); got != tt.wantCc {
+			t.Errorf("NewReplyMessage(all=%v) Cc mismatch: want [%s] got [%s]", tt.all, tt.wantCc, got)
+		}
+		if msg.Subject != "Re: Plans" {
+			t.Errorf("NewReplyMessage() Subject mismatch: want [Re: Plans] got [%s]", msg.Subject)
+		}
+	}
+}
+
+func TestReplyForwardSubject(t *testing.T) {
+	for _, tt := range []struct {
+		subject string
+		wantRe  string
+		wantFwd string
+	}{
+		{"Hello", "Re: Hello", "Fwd: Hello"},
+		{"re: Hello", "re: Hello", "Fwd: re: Hello"},
+		{"Fwd: Hello", "Re: Fwd: Hello", "Fwd: Hello"},
+		{"FW: Hello", "Re: FW: Hello", "FW: Hello"},
+		{"Reminder", "Re: Reminder", "Fwd: Reminder"},
+	} {
+		if got := ReplySubject(tt.subject); got != tt.wantRe {
+			t.Errorf("ReplySubject(%q) mismatch: want [%s] got [%s]", tt.subject, tt.wantRe, got)
+		}
+		if got := ForwardSubject(tt.subject); got != tt.wantFwd {
+			t.Errorf("ForwardSubject(%q) mismatch: want [%s] got [%s]", tt.subject, tt.wantFwd, got)
+		}
+	}
+}
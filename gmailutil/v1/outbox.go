@@ -0,0 +1,318 @@
+package gmailutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	"github.com/grokify/mogo/net/mailutil"
+	"google.golang.org/api/googleapi"
+)
+
+var (
+	ErrOutboxStoreCannotBeNil = errors.New("outbox store cannot be nil")
+	// ErrOutboxMayHaveBeenSent marks a failed item whose send request may have reached
+	// Gmail, such as after a timeout. It is not retried since sending is not idempotent.
+	ErrOutboxMayHaveBeenSent = errors.New("message may have been sent")
+)
+
+// Outbox item statuses for `OutboxItem.Status`.
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSending = "sending" // saved before each send attempt
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed"
+)
+
+const (
+	// DefaultOutboxMaxAttempts is how many times `Outbox` tries to send an item before
+	// marking it failed.
+	DefaultOutboxMaxAttempts = 5
+	// DefaultOutboxRetryDelay is how long `Outbox` waits before the first retry. The
+	// delay doubles after each failed attempt up to `OutboxMaxRetryDelay`.
+	DefaultOutboxRetryDelay = time.Minute
+	// OutboxMaxRetryDelay is the longest `Outbox` waits between attempts.
+	OutboxMaxRetryDelay = time.Hour
+	// DefaultOutboxPollInterval is how often `Outbox.Run()` checks for due items.
+	DefaultOutboxPollInterval = 30 * time.Second
+)
+
+// OutboxItem is a message queued to be sent at `SendAt`. The message is stored encoded,
+// so attachments are captured when it is queued.
+type OutboxItem struct {
+	ID            string    `json:"id"`
+	From          string    `json:"from"` // user ID or `me`
+	ThreadID      string    `json:"threadId,omitempty"`
+	To            string    `json:"to,omitempty"` // for display
+	Subject       string    `json:"subject,omitempty"`
+	Raw           []byte    `json:"raw"` // RFC 5322 message
+	Status        string    `json:"status"`
+	SendAt        time.Time `json:"sendAt"`
+	Attempts      int       `json:"attempts,omitempty"`
+	NextAttemptAt time.Time `json:"nextAttemptAt,omitzero"`
+	LastError     string    `json:"lastError,omitempty"`
+	MessageID     string    `json:"messageId,omitempty"` // Gmail message ID once sent
+	CreatedAt     time.Time `json:"createdAt"`
+	SentAt        time.Time `json:"sentAt,omitzero"`
+}
+
+// NewOutboxItem encodes `msg` as a pending item to be sent from `from` at `sendAt`, or
+// as soon as possible if `sendAt` is zero.
+func NewOutboxItem(from string, msg mailutil.MessageWriter, sendAt time.Time) (OutboxItem, error) {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return OutboxItem{}, err
+	}
+	now := time.Now().UTC()
+	if sendAt.IsZero() {
+		sendAt = now
+	}
+	return OutboxItem{
+		ID:        newOutboxItemID(now),
+		From:      labelUserID(from),
+		To:        strings.Join(msg.To.Strings(true, false, false), ","),
+		Subject:   msg.Subject,
+		Raw:       raw,
+		Status:    OutboxStatusPending,
+		SendAt:    sendAt.UTC(),
+		CreatedAt: now}, nil
+}
+
+// newOutboxItemID returns an ID which sorts by creation time.
+func newOutboxItemID(now time.Time) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return now.Format("20060102T150405.000000") + "-" + hex.EncodeToString(b)
+}
+
+// IsDue reports if the item is pending and can be sent at `now`.
+func (item OutboxItem) IsDue(now time.Time) bool {
+	return item.Status == OutboxStatusPending && !now.Before(item.SendAt) && !now.Before(item.NextAttemptAt)
+}
+
+// Outbox queues messages in an `OutboxStore` and sends them when they are due. Sends
+// which fail with a 429 or 5xx response, or before the request was written, are retried
+// with exponential backoff; other errors fail the item. Run one sender per store.
+type Outbox struct {
+	GmailService *GmailService
+	Store        OutboxStore
+	MaxAttempts  int           // defaults to `DefaultOutboxMaxAttempts`
+	RetryDelay   time.Duration // defaults to `DefaultOutboxRetryDelay`
+	PollInterval time.Duration // defaults to `DefaultOutboxPollInterval`
+	// OnSend, if set, is called after every send attempt with the updated item.
+	OnSend func(item OutboxItem, err error)
+}
+
+// NewOutbox returns an `Outbox` which sends with `gs` and stores items in `store`. `gs`
+// is only needed to send, not to queue.
+func NewOutbox(gs *GmailService, store OutboxStore) *Outbox {
+	return &Outbox{GmailService: gs, Store: store}
+}
+
+// Enqueue stores `msg` to be sent from `from` at `sendAt`, or as soon as possible if
+// `sendAt` is zero.
+func (ob *Outbox) Enqueue(ctx context.Context, from string, msg mailutil.MessageWriter, sendAt time.Time) (OutboxItem, error) {
+	if ob.Store == nil {
+		return OutboxItem{}, ErrOutboxStoreCannotBeNil
+	}
+	item, err := NewOutboxItem(from, msg, sendAt)
+	if err != nil {
+		return item, err
+	} else if err := ob.Store.Save(ctx, item); err != nil {
+		return item, errorsutil.Wrap(err, "func Outbox.Enqueue() call to Store.Save()")
+	}
+	return item, nil
+}
+
+// OutboxResult summarizes a pass over the outbox by `Outbox.SendDue()`.
+type OutboxResult struct {
+	Sent    int // items sent
+	Retry   int // items which failed and will be retried
+	Failed  int // items which failed permanently
+	Pending int // items still pending after the pass, including retries
+}
+
+// SendDue sends the pending items which are due. Send failures are recorded on the items
+// rather than returned; an error is returned if the store fails or `ctx` is cancelled.
+func (ob *Outbox) SendDue(ctx context.Context) (OutboxResult, error) {
+	var res OutboxResult
+	if ob.GmailService == nil {
+		return res, ErrGmailServiceCannotBeNil
+	} else if err := ob.GmailService.validateConfig(); err != nil {
+		return res, err
+	} else if ob.Store == nil {
+		return res, ErrOutboxStoreCannotBeNil
+	}
+	items, err := ob.Store.List(ctx)
+	if err != nil {
+		return res, errorsutil.Wrap(err, "func Outbox.SendDue() call to Store.List()")
+	}
+	for _, item := range items {
+		var errSend error
+		if item.Status == OutboxStatusSending {
+			// A previous run stopped during this attempt, which may have been sent.
+			item, errSend = interruptedOutboxItem(item)
+			if err := ob.Store.Save(ctx, item); err != nil {
+				return res, errorsutil.Wrap(err, "func Outbox.SendDue() call to Store.Save()")
+			}
+			res.Failed++
+			if ob.OnSend != nil {
+				ob.OnSend(item, errSend)
+			}
+			continue
+		} else if item.Status != OutboxStatusPending {
+			continue
+		} else if !item.IsDue(time.Now()) {
+			res.Pending++
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		item.Attempts++
+		item.Status = OutboxStatusSending
+		if err := ob.Store.Save(ctx, item); err != nil {
+			return res, errorsutil.Wrap(err, "func Outbox.SendDue() call to Store.Save()")
+		}
+		item, errSend = ob.send(ctx, item)
+		// save the outcome even if `ctx` was cancelled during the attempt
+		if err := ob.Store.Save(context.WithoutCancel(ctx), item); err != nil {
+			return res, errorsutil.Wrap(err, "func Outbox.SendDue() call to Store.Save()")
+		} else if errSend != nil && ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		switch item.Status {
+		case OutboxStatusSent:
+			res.Sent++
+		case OutboxStatusFailed:
+			res.Failed++
+		default:
+			res.Retry++
+			res.Pending++
+		}
+		if ob.OnSend != nil {
+			ob.OnSend(item, errSend)
+		}
+	}
+	return res, nil
+}
+
+// send makes one attempt to send `item`, already saved as `OutboxStatusSending`, and
+// returns it updated with the outcome.
+func (ob *Outbox) send(ctx context.Context, item OutboxItem) (OutboxItem, error) {
+	msg, err := ob.GmailService.SendRaw(ctx, item.From, item.ThreadID, item.Raw, ob.GmailService.APICallOptions...)
+	if err == nil {
+		item.Status = OutboxStatusSent
+		item.MessageID = msg.Id
+		item.SentAt = time.Now().UTC()
+		item.NextAttemptAt = time.Time{}
+		item.LastError = ""
+		return item, nil
+	}
+	if !isRetryableSendError(err) && !isGoogleAPIError(err) {
+		err = fmt.Errorf("%w; check the Sent folder before resending: %w", ErrOutboxMayHaveBeenSent, err)
+	}
+	item.LastError = err.Error()
+	maxAttempts := ob.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultOutboxMaxAttempts
+	}
+	if !isRetryableSendError(err) || item.Attempts >= maxAttempts {
+		item.Status = OutboxStatusFailed
+		item.NextAttemptAt = time.Time{}
+		return item, err
+	}
+	delay := ob.RetryDelay
+	if delay <= 0 {
+		delay = DefaultOutboxRetryDelay
+	}
+	item.Status = OutboxStatusPending
+	item.NextAttemptAt = time.Now().UTC().Add(outboxRetryDelay(err, delay, item.Attempts))
+	return item, err
+}
+
+// interruptedOutboxItem fails an item left as `OutboxStatusSending` by a run which
+// stopped before saving the outcome of the attempt.
+func interruptedOutboxItem(item OutboxItem) (OutboxItem, error) {
+	err := fmt.Errorf("%w; check the Sent folder before resending: send attempt (%d) was interrupted",
+		ErrOutboxMayHaveBeenSent, item.Attempts)
+	item.Status = OutboxStatusFailed
+	item.NextAttemptAt = time.Time{}
+	item.LastError = err.Error()
+	return item, err
+}
+
+// Run sends due items every `PollInterval` until `ctx` is cancelled, and then returns
+// nil. Store errors are returned immediately.
+func (ob *Outbox) Run(ctx context.Context) error {
+	interval := ob.PollInterval
+	if interval <= 0 {
+		interval = DefaultOutboxPollInterval
+	}
+	for {
+		if _, err := ob.SendDue(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryableSendError reports if a send which failed with `err` may succeed later and
+// was not delivered: a 429 or 5xx response, a 403 rate limit, or a connection error
+// before the request was written. Other transport errors, such as timeouts, are not
+// retried since Gmail may have accepted the message.
+func isRetryableSendError(err error) bool {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		if gerr.Code == http.StatusForbidden {
+			for _, item := range gerr.Errors {
+				if item.Reason == "userRateLimitExceeded" || item.Reason == "rateLimitExceeded" {
+					return true
+				}
+			}
+			return false
+		}
+		return gerr.Code == http.StatusTooManyRequests || gerr.Code >= http.StatusInternalServerError
+	}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	return errors.As(err, &dnsErr) || (errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+func isGoogleAPIError(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr)
+}
+
+// outboxRetryDelay returns the delay before the next attempt: `Retry-After` if the
+// response has it, or `delay` doubled for each attempt after the first, capped at
+// `OutboxMaxRetryDelay`.
+func outboxRetryDelay(err error, delay time.Duration, attempts int) time.Duration {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && gerr.Header != nil {
+		if secs, errConv := strconv.Atoi(strings.TrimSpace(gerr.Header.Get("Retry-After"))); errConv == nil && secs > 0 {
+			return min(time.Duration(secs)*time.Second, OutboxMaxRetryDelay)
+		}
+	}
+	for i := 1; i < attempts && delay < OutboxMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, OutboxMaxRetryDelay)
+}
@@ -0,0 +1,135 @@
+package gmailutil
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrOutboxItemNotFound  = errors.New("outbox item not found")
+	ErrOutboxItemIDInvalid = errors.New("outbox item id is invalid")
+)
+
+// OutboxStore persists `OutboxItem`s for `Outbox`. Implementations must be safe for
+// concurrent use.
+type OutboxStore interface {
+	// Save creates or replaces the item with `item.ID`.
+	Save(ctx context.Context, item OutboxItem) error
+	// Load returns the item with `id`, or `ErrOutboxItemNotFound`.
+	Load(ctx context.Context, id string) (OutboxItem, error)
+	// List returns all items ordered by `SendAt` and then `ID`.
+	List(ctx context.Context) ([]OutboxItem, error)
+	// Delete removes the item with `id`, or returns `ErrOutboxItemNotFound`.
+	Delete(ctx context.Context, id string) error
+}
+
+// FileOutboxStore stores each outbox item as a JSON file named `<id>.json` in a
+// directory. Writes replace files atomically, so a queue survives restarts and crashes.
+type FileOutboxStore struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// NewFileOutboxStore returns a store backed by the directory `dir`, which is created on
+// the first save.
+func NewFileOutboxStore(dir string) *FileOutboxStore {
+	return &FileOutboxStore{Dir: dir}
+}
+
+// Save implements `OutboxStore`.
+func (fs *FileOutboxStore) Save(ctx context.Context, item OutboxItem) error {
+	path, err := fs.path(item.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := os.MkdirAll(fs.Dir, 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Load implements `OutboxStore`.
+func (fs *FileOutboxStore) Load(ctx context.Context, id string) (OutboxItem, error) {
+	path, err := fs.path(id)
+	if err != nil {
+		return OutboxItem{}, err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return readOutboxItem(path)
+}
+
+// List implements `OutboxStore`.
+func (fs *FileOutboxStore) List(ctx context.Context) ([]OutboxItem, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	entries, err := os.ReadDir(fs.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var items []OutboxItem
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		item, err := readOutboxItem(filepath.Join(fs.Dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b OutboxItem) int {
+		return cmp.Or(a.SendAt.Compare(b.SendAt), strings.Compare(a.ID, b.ID))
+	})
+	return items, nil
+}
+
+// Delete implements `OutboxStore`.
+func (fs *FileOutboxStore) Delete(ctx context.Context, id string) error {
+	path, err := fs.path(id)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrOutboxItemNotFound, id)
+	} else {
+		return err
+	}
+}
+
+// path returns the file for `id`, which cannot contain path separators.
+func (fs *FileOutboxStore) path(id string) (string, error) {
+	if id = strings.TrimSpace(id); id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("%w: %q", ErrOutboxItemIDInvalid, id)
+	}
+	return filepath.Join(fs.Dir, id+".json"), nil
+}
+
+func readOutboxItem(path string) (OutboxItem, error) {
+	var item OutboxItem
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return item, fmt.Errorf("%w: %s", ErrOutboxItemNotFound, strings.TrimSuffix(filepath.Base(path), ".json"))
+	} else if err != nil {
+		return item, err
+	}
+	return item, json.Unmarshal(data, &item)
+}
@@ -0,0 +1,235 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grokify/mogo/mime/multipartutil"
+	"github.com/grokify/mogo/net/mailutil"
+	gmail "google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+func newTestOutboxMessage(subject string) mailutil.MessageWriter {
+	return mailutil.MessageWriter{
+		To:           mailutil.Addresses{{Address: "user@example.com"}},
+		Subject:      subject,
+		BodyPartsSet: multipartutil.NewPartsSetAlternative([]byte("Hello"), nil)}
+}
+
+func TestOutboxSendDue(t *testing.T) {
+	var sends atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/messages/send", func(w http.ResponseWriter, r *http.Request) {
+		var msg gmail.Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decode send request error: [%v]", err)
+			return
+		}
+		raw, err := base64.URLEncoding.DecodeString(msg.Raw)
+		if err != nil {
+			t.Errorf("decode raw message error: [%v]", err)
+			return
+		}
+		switch n := sends.Add(1); {
+		case strings.Contains(string(raw), "Subject: Rejected"):
+			http.Error(w, `{"error":{"code":400,"message":"Invalid To header"}}`, http.StatusBadRequest)
+		case n == 1:
+			http.Error(w, `{"error":{"code":503,"message":"Backend Error"}}`, http.StatusServiceUnavailable)
+		default:
+			_ = json.NewEncoder(w).Encode(&gmail.Message{Id: "sent-1"})
+		}
+	})
+	gs := newTestGmailService(t, mux)
+	store := NewFileOutboxStore(t.TempDir())
+	ctx := context.Background()
+
+	ob := NewOutbox(gs, store)
+	ob.RetryDelay = time.Millisecond
+	if _, err := ob.Enqueue(ctx, "", newTestOutboxMessage("Now"), time.Time{}); err != nil {
+		t.Fatalf("Outbox.Enqueue() error: [%v]", err)
+	}
+	later, err := ob.Enqueue(ctx, "me", newTestOutboxMessage("Later"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Outbox.Enqueue() error: [%v]", err)
+	}
+
+	// The first send fails with a 503 and is retried.
+	res, err := ob.SendDue(ctx)
+	if err != nil {
+		t.Fatalf("Outbox.SendDue() error: [%v]", err)
+	} else if res != (OutboxResult{Retry: 1, Pending: 2}) {
+		t.Errorf("Outbox.SendDue() first pass mismatch: got [%+v]", res)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if res, err = ob.SendDue(ctx); err != nil {
+		t.Fatalf("Outbox.SendDue() error: [%v]", err)
+	} else if res != (OutboxResult{Sent: 1, Pending: 1}) {
+		t.Errorf("Outbox.SendDue() second pass mismatch: got [%+v]", res)
+	}
+
+	items, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("FileOutboxStore.List() error: [%v]", err)
+	} else if len(items) != 2 {
+		t.Fatalf("FileOutboxStore.List() mismatch: want (2) items, got (%d)", len(items))
+	}
+	if sent := items[0]; sent.Status != OutboxStatusSent || sent.MessageID != "sent-1" || sent.Attempts != 2 || sent.LastError != "" {
+		t.Errorf("sent item mismatch: got [%+v]", sent)
+	}
+	if items[1].ID != later.ID || items[1].Status != OutboxStatusPending {
+		t.Errorf("later item mismatch: got [%+v]", items[1])
+	}
+
+	// A 400 is not retried.
+	if _, err := ob.Enqueue(ctx, "me", newTestOutboxMessage("Rejected"), time.Time{}); err != nil {
+		t.Fatalf("Outbox.Enqueue() error: [%v]", err)
+	}
+	var failed OutboxItem
+	ob.OnSend = func(item OutboxItem, err error) { failed = item }
+	if res, err = ob.SendDue(ctx); err != nil {
+		t.Fatalf("Outbox.SendDue() error: [%v]", err)
+	} else if res != (OutboxResult{Failed: 1, Pending: 1}) || failed.Status != OutboxStatusFailed || failed.Attempts != 1 {
+		t.Errorf("Outbox.SendDue() rejected mismatch: got [%+v] item [%+v]", res, failed)
+	}
+
+	if err := store.Delete(ctx, later.ID); err != nil {
+		t.Errorf("FileOutboxStore.Delete() error: [%v]", err)
+	}
+	if _, err := store.Load(ctx, later.ID); !errors.Is(err, ErrOutboxItemNotFound) {
+		t.Errorf("FileOutboxStore.Load() deleted: want [%v], got [%v]", ErrOutboxItemNotFound, err)
+	}
+	if _, err := store.Load(ctx, "../secret"); !errors.Is(err, ErrOutboxItemIDInvalid) {
+		t.Errorf("FileOutboxStore.Load() traversal: want [%v], got [%v]", ErrOutboxItemIDInvalid, err)
+	}
+}
+
+func TestOutboxRetryDelay(t *testing.T) {
+	tests := []struct {
+		err      error
+		attempts int
+		want     time.Duration
+	}{
+		{&googleapi.Error{Code: 503}, 1, time.Minute},
+		{&googleapi.Error{Code: 503}, 3, 4 * time.Minute},
+		{&googleapi.Error{Code: 429}, 20, OutboxMaxRetryDelay},
+		{&googleapi.Error{Code: 429, Header: http.Header{"Retry-After": []string{"30"}}}, 3, 30 * time.Second},
+		{errors.New("connection reset"), 2, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := outboxRetryDelay(tt.err, time.Minute, tt.attempts); got != tt.want {
+			t.Errorf("outboxRetryDelay(%v, %d) mismatch: want (%v), got (%v)", tt.err, tt.attempts, tt.want, got)
+		}
+	}
+	for code, want := range map[int]bool{400: false, 403: false, 429: true, 500: true, 503: true} {
+		if got := isRetryableSendError(&googleapi.Error{Code: code}); got != want {
+			t.Errorf("isRetryableSendError(%d) mismatch: want (%v), got (%v)", code, want, got)
+		}
+	}
+
+	rateLimit := &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}}}
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{rateLimit, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{&url.Error{Op: "Post", Err: &net.DNSError{Err: "no such host"}}, true},
+		{&url.Error{Op: "Post", Err: &net.OpError{Op: "read", Err: errors.New("connection reset")}}, false},
+		{context.DeadlineExceeded, false},
+	} {
+		if got := isRetryableSendError(tt.err); got != tt.want {
+			t.Errorf("isRetryableSendError(%v) mismatch: want (%v), got (%v)", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestOutboxSendMayHaveBeenSent(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/messages/send", func(w http.ResponseWriter, r *http.Request) {
+		// The request is read but the connection drops before a response.
+		_, _ = io.Copy(io.Discard, r.Body)
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("hijack error: [%v]", err)
+			return
+		}
+		_ = conn.Close()
+	})
+	ctx := context.Background()
+	ob := NewOutbox(newTestGmailService(t, mux), NewFileOutboxStore(t.TempDir()))
+	if _, err := ob.Enqueue(ctx, "me", newTestOutboxMessage("Dropped"), time.Time{}); err != nil {
+		t.Fatalf("Outbox.Enqueue() error: [%v]", err)
+	}
+	var failed OutboxItem
+	var errSend error
+	ob.OnSend = func(item OutboxItem, err error) { failed, errSend = item, err }
+	if res, err := ob.SendDue(ctx); err != nil {
+		t.Fatalf("Outbox.SendDue() error: [%v]", err)
+	} else if res != (OutboxResult{Failed: 1}) || failed.Status != OutboxStatusFailed || failed.Attempts != 1 {
+		t.Errorf("Outbox.SendDue() dropped mismatch: got [%+v] item [%+v]", res, failed)
+	}
+	if !errors.Is(errSend, ErrOutboxMayHaveBeenSent) || !strings.Contains(failed.LastError, "may have been sent") {
+		t.Errorf("Outbox.SendDue() dropped error mismatch: got [%v]", errSend)
+	}
+}
+
+func TestOutboxSendDueInterrupted(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileOutboxStore(t.TempDir())
+	var inFlight OutboxItem
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/messages/send", func(w http.ResponseWriter, r *http.Request) {
+		items, err := store.List(r.Context())
+		if err != nil || len(items) != 1 {
+			t.Errorf("FileOutboxStore.List() during send mismatch: got [%d] items error [%v]", len(items), err)
+			return
+		}
+		inFlight = items[0]
+		_ = json.NewEncoder(w).Encode(&gmail.Message{Id: "sent-1"})
+	})
+	ob := NewOutbox(newTestGmailService(t, mux), store)
+
+	// The item is saved as sending with its attempt counted before the request.
+	item, err := ob.Enqueue(ctx, "me", newTestOutboxMessage("Now"), time.Time{})
+	if err != nil {
+		t.Fatalf("Outbox.Enqueue() error: [%v]", err)
+	} else if _, err := ob.SendDue(ctx); err != nil {
+		t.Fatalf("Outbox.SendDue() error: [%v]", err)
+	} else if inFlight.Status != OutboxStatusSending || inFlight.Attempts != 1 {
+		t.Errorf("in-flight item mismatch: got [%+v]", inFlight)
+	}
+
+	// An item left as sending by a crashed run is failed rather than sent again.
+	item.Status, item.Attempts = OutboxStatusSending, 1
+	if err := store.Save(ctx, item); err != nil {
+		t.Fatalf("FileOutboxStore.Save() error: [%v]", err)
+	}
+	inFlight = OutboxItem{}
+	var errSend error
+	ob.OnSend = func(_ OutboxItem, err error) { errSend = err }
+	if res, err := ob.SendDue(ctx); err != nil {
+		t.Fatalf("Outbox.SendDue() error: [%v]", err)
+	} else if res != (OutboxResult{Failed: 1}) || inFlight.ID != "" {
+		t.Errorf("Outbox.SendDue() interrupted mismatch: got [%+v] sent [%v]", res, inFlight.ID != "")
+	}
+	if !errors.Is(errSend, ErrOutboxMayHaveBeenSent) {
+		t.Errorf("Outbox.SendDue() interrupted error: want [%v], got [%v]", ErrOutboxMayHaveBeenSent, errSend)
+	}
+	got, err := store.Load(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("FileOutboxStore.Load() error: [%v]", err)
+	} else if got.Status != OutboxStatusFailed || got.Attempts != 1 || !strings.Contains(got.LastError, "may have been sent") {
+		t.Errorf("interrupted item mismatch: got [%+v]", got)
+	}
+}
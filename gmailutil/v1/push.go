@@ -0,0 +1,288 @@
+package gmailutil
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	gmail "google.golang.org/api/gmail/v1"
+	"google.golang.org/api/idtoken"
+)
+
+var (
+	ErrPushCallbackCannotBeNil     = errors.New("push callback cannot be nil")
+	ErrPushRequestUnauthorized     = errors.New("push request unauthorized")
+	ErrPushSubscriptionMismatch    = errors.New("push subscription does not match")
+	ErrPushNotificationMissingData = errors.New("push notification missing emailAddress or historyId")
+)
+
+// PushMaxBodySize is the largest Pub/Sub push request body accepted by `PushHandler`.
+const PushMaxBodySize = 1 << 20
+
+// PushEnvelope is the body of a Pub/Sub push delivery.
+type PushEnvelope struct {
+	Message      PushMessage `json:"message"`
+	Subscription string      `json:"subscription"`
+}
+
+// PushMessage is the Pub/Sub message in a `PushEnvelope`. `Data` is base64 encoded.
+type PushMessage struct {
+	Data        string            `json:"data"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	MessageID   string            `json:"messageId"`
+	PublishTime time.Time         `json:"publishTime"`
+}
+
+// PushNotification is the Gmail change notification carried by a Pub/Sub push, along
+// with the Pub/Sub delivery metadata.
+type PushNotification struct {
+	EmailAddress string
+	HistoryID    uint64 // mailbox history ID after the change
+	MessageID    string // Pub/Sub message ID
+	PublishTime  time.Time
+	Subscription string
+}
+
+// ParsePushNotification decodes a Pub/Sub push body and its base64 encoded Gmail
+// payload, `{"emailAddress": "user@example.com", "historyId": 1234}`.
+func ParsePushNotification(body []byte) (*PushNotification, error) {
+	var env PushEnvelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, errorsutil.Wrap(err, "decode push envelope")
+	}
+	data, err := base64.StdEncoding.DecodeString(env.Message.Data)
+	if err != nil {
+		if data, err = base64.URLEncoding.DecodeString(env.Message.Data); err != nil {
+			return nil, errorsutil.Wrap(err, "decode push message data")
+		}
+	}
+	var payload struct {
+		EmailAddress string      `json:"emailAddress"`
+		HistoryID    json.Number `json:"historyId"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, errorsutil.Wrap(err, "decode push message data")
+	}
+	n := &PushNotification{
+		EmailAddress: strings.TrimSpace(payload.EmailAddress),
+		MessageID:    env.Message.MessageID,
+		PublishTime:  env.Message.PublishTime,
+		Subscription: env.Subscription}
+	if payload.HistoryID != "" {
+		if n.HistoryID, err = strconv.ParseUint(payload.HistoryID.String(), 10, 64); err != nil {
+			return nil, errorsutil.Wrap(err, "decode push message historyId")
+		}
+	}
+	if n.EmailAddress == "" || n.HistoryID == 0 {
+		return nil, ErrPushNotificationMissingData
+	}
+	return n, nil
+}
+
+// PushVerifier authenticates a Pub/Sub push request, returning an error if it should be
+// rejected.
+type PushVerifier func(r *http.Request) error
+
+// VerifyPushToken returns a `PushVerifier` which requires the `token` query parameter of
+// the push endpoint URL to equal `token`.
+func VerifyPushToken(token string) PushVerifier {
+	return func(r *http.Request) error {
+		if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) != 1 {
+			return ErrPushRequestUnauthorized
+		}
+		return nil
+	}
+}
+
+// VerifyPushOIDC returns a `PushVerifier` which validates the Google-signed OIDC token
+// that Pub/Sub sends in the `Authorization` header of authenticated push subscriptions.
+// `audience` is the audience configured on the subscription, and `serviceAccountEmail`,
+// if not empty, must match the token's verified `email` claim.
+func VerifyPushOIDC(audience, serviceAccountEmail string) PushVerifier {
+	return func(r *http.Request) error {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			return ErrPushRequestUnauthorized
+		}
+		payload, err := idtoken.Validate(r.Context(), strings.TrimSpace(token), audience)
+		if err != nil {
+			return errors.Join(ErrPushRequestUnauthorized, err)
+		}
+		if serviceAccountEmail != "" {
+			email, _ := payload.Claims["email"].(string)
+			verified, _ := payload.Claims["email_verified"].(bool)
+			if !verified || !strings.EqualFold(email, serviceAccountEmail) {
+				return ErrPushRequestUnauthorized
+			}
+		}
+		return nil
+	}
+}
+
+// PushCallback receives the messages added to a mailbox since the last notification.
+type PushCallback func(ctx context.Context, n *PushNotification, msgs []*gmail.Message) error
+
+// PushHandler is an `http.Handler` for Pub/Sub push deliveries of Gmail notifications.
+// For each notification, it reads the history since the last seen history ID in `Store`,
+// retrieves the added messages and passes them to `Callback`. The cursor is advanced only
+// after `Callback` succeeds; otherwise a 5xx response makes Pub/Sub redeliver the
+// notification, so delivery is at least once.
+//
+// Without a stored cursor, the notification's history ID is stored and no messages are
+// reported, since the notification does not say which changes preceded it; use a
+// `Watcher` with the same `Store` and user ID to seed the cursor when the watch starts. If the stored
+// history ID has expired, the cursor is reset to the current history ID.
+type PushHandler struct {
+	GmailService *GmailService
+	Store        SyncStateStore
+	Callback     PushCallback
+	UserID       string       // mailbox to read and cursor key; defaults to `UserIDMe` as for `Watcher`
+	Subscription string       // if set, the full subscription name deliveries must come from
+	Verify       PushVerifier // required; requests failing verification are rejected with 401
+	LabelID      string       // restricts reported messages to those added with a label, e.g. `INBOX`
+	Format       string       // message format passed to `users.messages.get`, default `MessageFormatFull`
+	// InsecureSkipVerify accepts requests without `Verify`, such as for local testing.
+	// Otherwise all requests are rejected with 401 until `Verify` is set.
+	InsecureSkipVerify bool
+	mu                 sync.Mutex
+}
+
+// NewPushHandler returns a `PushHandler` for the authenticated user. Set
+// `Verify` before serving requests.
+func NewPushHandler(gs *GmailService, store SyncStateStore, callback PushCallback) *PushHandler {
+	return &PushHandler{GmailService: gs, Store: store, Callback: callback}
+}
+
+// ServeHTTP implements `http.Handler`. It responds with 204 when the notification has been
+// handled, 400 for malformed bodies, 401 or 403 for unauthorized requests and 500 when
+// handling fails and the delivery should be retried.
+func (h *PushHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Verify != nil {
+		if err := h.Verify(r); err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	} else if !h.InsecureSkipVerify {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, PushMaxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := ParsePushNotification(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.Subscription != "" && n.Subscription != h.Subscription {
+		http.Error(w, ErrPushSubscriptionMismatch.Error(), http.StatusForbidden)
+		return
+	}
+	if err := h.Handle(r.Context(), n); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Handle processes a notification: it retrieves the messages added since the stored
+// history ID, calls `Callback` if there are any, and then saves the new cursor.
+// Notifications are handled one at a time.
+func (h *PushHandler) Handle(ctx context.Context, n *PushNotification) error {
+	if h.GmailService == nil {
+		return ErrGmailServiceCannotBeNil
+	} else if h.Store == nil {
+		return ErrSyncStateStoreCannotBeNil
+	} else if h.Callback == nil {
+		return ErrPushCallbackCannotBeNil
+	} else if n == nil {
+		return ErrPushNotificationMissingData
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	userID := labelUserID(h.UserID)
+	last, err := h.Store.Load(ctx, userID)
+	if err != nil {
+		return errorsutil.Wrap(err, "func PushHandler.Handle() call to Store.Load()")
+	} else if last == 0 {
+		return h.Store.Save(ctx, userID, n.HistoryID)
+	} else if last >= n.HistoryID {
+		return nil
+	}
+
+	pending := &pendingSyncStateStore{SyncStateStore: h.Store}
+	syncer := Syncer{
+		GmailService: h.GmailService,
+		Store:        pending,
+		UserID:       userID,
+		LabelID:      h.LabelID,
+		HistoryTypes: []string{HistoryTypeMessageAdded}}
+	var metas []*gmail.Message
+	seen := map[string]bool{}
+	for ev, err := range syncer.Sync(ctx) {
+		if err != nil {
+			return err
+		} else if ev.Type == SyncEventReset {
+			return h.Store.Save(ctx, userID, ev.HistoryID)
+		} else if ev.Type == SyncEventMessageAdded && !seen[ev.MessageID] {
+			seen[ev.MessageID] = true
+			metas = append(metas, &gmail.Message{Id: ev.MessageID, ThreadId: ev.ThreadID})
+		}
+	}
+
+	format := h.Format
+	if strings.TrimSpace(format) == "" {
+		format = MessageFormatFull
+	}
+	res := h.GmailService.MessagesAPI.InflateMessagesWithOpts(ctx, metas, InflateOpts{UserID: userID, Format: format})
+	for id, err := range res.Errors {
+		// messages deleted since they were added are skipped
+		if !isNotFound(err) {
+			return fmt.Errorf("message id (%s): %w", id, err)
+		}
+	}
+	var msgs []*gmail.Message
+	for _, msg := range res.Messages {
+		if msg != nil {
+			msgs = append(msgs, msg)
+		}
+	}
+	if len(msgs) > 0 {
+		if err := h.Callback(ctx, n, msgs); err != nil {
+			return errorsutil.Wrap(err, "func PushHandler.Handle() call to Callback()")
+		}
+	}
+	if pending.historyID > 0 {
+		return h.Store.Save(ctx, userID, pending.historyID)
+	}
+	return nil
+}
+
+// pendingSyncStateStore holds the cursor saved by a `Syncer` until it is committed.
+type pendingSyncStateStore struct {
+	SyncStateStore
+	historyID uint64
+}
+
+func (ps *pendingSyncStateStore) Save(ctx context.Context, userID string, historyID uint64) error {
+	ps.historyID = historyID
+	return nil
+}
@@ -0,0 +1,204 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+// testPushBody is a Pub/Sub push delivery for `{"emailAddress":"user@example.com","historyId":"120"}`.
+const testPushBody = `{
+  "message": {
+    "data": "eyJlbWFpbEFkZHJlc3MiOiJ1c2VyQGV4YW1wbGUuY29tIiwiaGlzdG9yeUlkIjoiMTIwIn0=",
+    "messageId": "2070443601311540",
+    "publishTime": "2026-02-26T19:13:55.749Z"
+  },
+  "subscription": "projects/myproject/subscriptions/gmail-push"
+}`
+
+// newTestPushService serves history since 100 adding messages m3 and m4, where m4 has
+// since been deleted. Any other start history ID returns 404. A watch starts at 100.
+func newTestPushService(t *testing.T) *GmailService {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/{userID}/history", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("userID") != UserIDMe {
+			t.Errorf("history userID mismatch: got (%s)", r.PathValue("userID"))
+		}
+		if got := r.URL.Query().Get("historyTypes"); got != HistoryTypeMessageAdded {
+			t.Errorf("history historyTypes mismatch: got (%s)", got)
+		}
+		if r.URL.Query().Get("startHistoryId") != "100" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"message":"Requested entity was not found."}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&gmail.ListHistoryResponse{HistoryId: 120, History: []*gmail.History{{
+			Id: 110,
+			MessagesAdded: []*gmail.HistoryMessageAdded{
+				{Message: &gmail.Message{Id: "m3", ThreadId: "t3"}},
+				{Message: &gmail.Message{Id: "m4", ThreadId: "t4"}}}}}})
+	})
+	mux.HandleFunc("GET /gmail/v1/users/{userID}/messages/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "m3" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"message":"Requested entity was not found."}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&gmail.Message{Id: "m3", ThreadId: "t3", Snippet: "hello"})
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/watch", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&gmail.WatchResponse{HistoryId: 100})
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/stop", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /gmail/v1/users/{userID}/profile", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&gmail.Profile{EmailAddress: "user@example.com", HistoryId: 500})
+	})
+	return newTestGmailService(t, mux)
+}
+
+func TestParsePushNotification(t *testing.T) {
+	n, err := ParsePushNotification([]byte(testPushBody))
+	if err != nil {
+		t.Fatalf("ParsePushNotification() error: [%v]", err)
+	}
+	if n.EmailAddress != "user@example.com" || n.HistoryID != 120 || n.MessageID != "2070443601311540" ||
+		n.Subscription != "projects/myproject/subscriptions/gmail-push" || n.PublishTime.IsZero() {
+		t.Errorf("ParsePushNotification() mismatch: got [%+v]", n)
+	}
+
+	numeric := strings.Replace(testPushBody,
+		"eyJlbWFpbEFkZHJlc3MiOiJ1c2VyQGV4YW1wbGUuY29tIiwiaGlzdG9yeUlkIjoiMTIwIn0=",
+		"eyJlbWFpbEFkZHJlc3MiOiJ1c2VyQGV4YW1wbGUuY29tIiwiaGlzdG9yeUlkIjo5MH0=", 1)
+	if n, err := ParsePushNotification([]byte(numeric)); err != nil || n.HistoryID != 90 {
+		t.Errorf("ParsePushNotification() numeric historyId mismatch: got [%+v] error [%v]", n, err)
+	}
+	for _, body := range []string{`not json`, `{"message":{"data":"!!"}}`, `{"message":{"data":"e30="}}`} {
+		if _, err := ParsePushNotification([]byte(body)); err == nil {
+			t.Errorf("ParsePushNotification(%s) want error, got nil", body)
+		}
+	}
+}
+
+func TestPushHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		cursor      uint64
+		callbackErr error
+		wantStatus  int
+		wantMsgs    string
+		wantCursor  uint64
+	}{
+		{"new messages", http.MethodPost, "/push?token=secret", testPushBody, 100, nil, http.StatusNoContent, "m3", 120},
+		{"no cursor seeds", http.MethodPost, "/push?token=secret", testPushBody, 0, nil, http.StatusNoContent, "", 120},
+		{"already seen", http.MethodPost, "/push?token=secret", testPushBody, 130, nil, http.StatusNoContent, "", 130},
+		{"expired cursor resets", http.MethodPost, "/push?token=secret", testPushBody, 50, nil, http.StatusNoContent, "", 500},
+		{"callback error retries", http.MethodPost, "/push?token=secret", testPushBody, 100, errors.New("downstream"), http.StatusInternalServerError, "m3", 100},
+		{"bad token", http.MethodPost, "/push?token=wrong", testPushBody, 100, nil, http.StatusUnauthorized, "", 100},
+		{"bad method", http.MethodGet, "/push?token=secret", "", 100, nil, http.StatusMethodNotAllowed, "", 100},
+		{"bad body", http.MethodPost, "/push?token=secret", `{"message":{}}`, 100, nil, http.StatusBadRequest, "", 100},
+		{"other subscription", http.MethodPost, "/push?token=secret",
+			strings.Replace(testPushBody, "gmail-push", "other", 1), 100, nil, http.StatusForbidden, "", 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewFileSyncStateStore(filepath.Join(t.TempDir(), "sync.json"))
+			if tt.cursor > 0 {
+				if err := store.Save(ctx, UserIDMe, tt.cursor); err != nil {
+					t.Fatalf("store.Save() error: [%v]", err)
+				}
+			}
+			var got []string
+			h := NewPushHandler(newTestPushService(t), store,
+				func(ctx context.Context, n *PushNotification, msgs []*gmail.Message) error {
+					if n.EmailAddress != "user@example.com" {
+						t.Errorf("callback notification mismatch: got [%+v]", n)
+					}
+					for _, msg := range msgs {
+						got = append(got, msg.Id)
+					}
+					return tt.callbackErr
+				})
+			h.Verify = VerifyPushToken("secret")
+			h.Subscription = "projects/myproject/subscriptions/gmail-push"
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status mismatch: want (%d), got (%d) body (%s)", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if s := strings.Join(got, ","); s != tt.wantMsgs {
+				t.Errorf("callback messages mismatch: want (%s), got (%s)", tt.wantMsgs, s)
+			}
+			if cur, err := store.Load(ctx, UserIDMe); err != nil || cur != tt.wantCursor {
+				t.Errorf("cursor mismatch: want (%d), got (%d) error [%v]", tt.wantCursor, cur, err)
+			}
+		})
+	}
+}
+
+func TestPushHandlerRequiresVerify(t *testing.T) {
+	for _, insecure := range []bool{false, true} {
+		ctx := context.Background()
+		store := NewFileSyncStateStore(filepath.Join(t.TempDir(), "sync.json"))
+		h := NewPushHandler(newTestPushService(t), store,
+			func(ctx context.Context, n *PushNotification, msgs []*gmail.Message) error { return nil })
+		h.InsecureSkipVerify = insecure
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/push", strings.NewReader(testPushBody)))
+		want, wantCursor := http.StatusUnauthorized, uint64(0)
+		if insecure {
+			want, wantCursor = http.StatusNoContent, 120
+		}
+		if rec.Code != want {
+			t.Errorf("InsecureSkipVerify (%t) status mismatch: want (%d), got (%d)", insecure, want, rec.Code)
+		}
+		if cur, err := store.Load(ctx, UserIDMe); err != nil || cur != wantCursor {
+			t.Errorf("InsecureSkipVerify (%t) cursor mismatch: want (%d), got (%d) error [%v]", insecure, wantCursor, cur, err)
+		}
+	}
+}
+
+func TestPushHandlerWatcherSeed(t *testing.T) {
+	gs := newTestPushService(t)
+	store := NewFileSyncStateStore(filepath.Join(t.TempDir(), "sync.json"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewWatcher(gs, "projects/myproject/topics/gmail")
+	w.Store = store
+	w.OnWatch = func(resp *gmail.WatchResponse, err error) { cancel() }
+	if err := w.Run(ctx); err != nil {
+		t.Fatalf("Watcher.Run() error: [%v]", err)
+	}
+
+	var got []string
+	h := NewPushHandler(gs, store, func(ctx context.Context, n *PushNotification, msgs []*gmail.Message) error {
+		for _, msg := range msgs {
+			got = append(got, msg.Id)
+		}
+		return nil
+	})
+	h.Verify = VerifyPushToken("secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/push?token=secret", strings.NewReader(testPushBody)))
+	if rec.Code != http.StatusNoContent || strings.Join(got, ",") != "m3" {
+		t.Errorf("first push after watch mismatch: want (204) (m3), got (%d) (%s)", rec.Code, strings.Join(got, ","))
+	}
+	if cur, err := store.Load(context.Background(), UserIDMe); err != nil || cur != 120 {
+		t.Errorf("cursor mismatch: want (120), got (%d) error [%v]", cur, err)
+	}
+}
@@ -0,0 +1,173 @@
+package gmailutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Gmail search operators. See https://support.google.com/mail/answer/7190
+const (
+	OperatorAfter       = "after"
+	OperatorBcc         = "bcc"
+	OperatorBefore      = "before"
+	OperatorCategory    = "category"
+	OperatorCc          = "cc"
+	OperatorDeliveredTo = "deliveredto"
+	OperatorFilename    = "filename"
+	OperatorFrom        = "from"
+	OperatorHas         = "has"
+	OperatorIn          = "in"
+	OperatorIs          = "is"
+	OperatorLabel       = "label"
+	OperatorLarger      = "larger"
+	OperatorList        = "list"
+	OperatorNewer       = "newer"
+	OperatorNewerThan   = "newer_than"
+	OperatorOlder       = "older"
+	OperatorOlderThan   = "older_than"
+	OperatorRFC822msgid = "rfc822msgid"
+	OperatorSize        = "size"
+	OperatorSmaller     = "smaller"
+	OperatorSubject     = "subject"
+	OperatorTo          = "to"
+
+	HasAttachment = "attachment"
+	IsImportant   = "important"
+	IsRead        = "read"
+	IsStarred     = "starred"
+	IsUnread      = "unread"
+
+	queryKeywordOr  = "OR"
+	queryKeywordAnd = "AND"
+)
+
+// Query is a node in a Gmail search query expression.
+type Query interface {
+	// Encode returns the Gmail search string for the node.
+	Encode() string
+}
+
+// QueryTerm is a single search term such as `from:alice@example.com`, free text,
+// or an exact phrase. An empty `Operator` represents free text.
+type QueryTerm struct {
+	Operator string
+	Value    string
+	Exact    bool // always quote `Value`, e.g. for an exact phrase match
+}
+
+// NewQueryTerm returns a `QueryTerm` for `operator:value`.
+func NewQueryTerm(operator, value string) QueryTerm {
+	return QueryTerm{Operator: operator, Value: value}
+}
+
+// NewQueryPhrase returns a `QueryTerm` matching the exact phrase `value`.
+func NewQueryPhrase(value string) QueryTerm {
+	return QueryTerm{Value: value, Exact: true}
+}
+
+func (t QueryTerm) Encode() string {
+	val := strings.TrimSpace(t.Value)
+	if val == "" {
+		return ""
+	}
+	if t.Exact || queryValueNeedsQuotes(val) {
+		val = `"` + strings.ReplaceAll(val, `"`, "") + `"`
+	}
+	if op := strings.TrimSpace(t.Operator); op != "" {
+		return op + ":" + val
+	}
+	return val
+}
+
+// queryValueNeedsQuotes reports if a value must be quoted to be treated as a single
+// term. Values wrapped in parentheses, such as `(a OR b)`, are left as groups.
+func queryValueNeedsQuotes(val string) bool {
+	if strings.HasPrefix(val, "(") && strings.HasSuffix(val, ")") {
+		return false
+	} else if val == queryKeywordOr || val == queryKeywordAnd {
+		return true
+	}
+	return strings.ContainsFunc(val, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"(){}`, r)
+	}) || strings.HasPrefix(val, "-")
+}
+
+// QueryAnd matches messages matching all of its nodes.
+type QueryAnd []Query
+
+// And returns a `QueryAnd` for `nodes`.
+func And(nodes ...Query) QueryAnd { return QueryAnd(nodes) }
+
+func (q QueryAnd) Encode() string {
+	var parts []string
+	for _, node := range q {
+		if s := encodeQueryChild(node, queryIsOr); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// QueryOr matches messages matching any of its nodes.
+type QueryOr []Query
+
+// Or returns a `QueryOr` for `nodes`.
+func Or(nodes ...Query) QueryOr { return QueryOr(nodes) }
+
+func (q QueryOr) Encode() string {
+	var parts []string
+	for _, node := range q {
+		if s := encodeQueryChild(node, queryIsAnd); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " "+queryKeywordOr+" ")
+}
+
+// QueryNot excludes messages matching its node.
+type QueryNot struct {
+	Query Query
+}
+
+// Not returns a `QueryNot` for `node`.
+func Not(node Query) QueryNot { return QueryNot{Query: node} }
+
+func (q QueryNot) Encode() string {
+	if s := encodeQueryChild(q.Query, func(n Query) bool { return queryIsAnd(n) || queryIsOr(n) }); s != "" {
+		return "-" + s
+	}
+	return ""
+}
+
+// encodeQueryChild encodes `node`, wrapping it in parentheses if `needsGroup` reports
+// true and `node` has more than one non-empty child.
+func encodeQueryChild(node Query, needsGroup func(Query) bool) string {
+	if node == nil {
+		return ""
+	}
+	s := node.Encode()
+	if s != "" && needsGroup(node) {
+		s = "(" + s + ")"
+	}
+	return s
+}
+
+func queryIsAnd(node Query) bool {
+	n, ok := node.(QueryAnd)
+	return ok && len(queryNonEmpty(n)) > 1
+}
+
+func queryIsOr(node Query) bool {
+	n, ok := node.(QueryOr)
+	return ok && len(queryNonEmpty(n)) > 1
+}
+
+func queryNonEmpty(nodes []Query) []Query {
+	var out []Query
+	for _, node := range nodes {
+		if node != nil && node.Encode() != "" {
+			out = append(out, node)
+		}
+	}
+	return out
+}
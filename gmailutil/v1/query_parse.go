@@ -0,0 +1,231 @@
+package gmailutil
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParseQuery parses a Gmail search string into a `Query` expression. It supports
+// `operator:value` terms, quoted phrases, `-` negation, `OR`, `AND`, `()` grouping
+// and `{}` OR-grouping. As in Gmail, `OR` binds more tightly than the implicit `AND`
+// between adjacent terms.
+func ParseQuery(s string) (Query, error) {
+	p := &queryParser{input: []rune(s)}
+	q, err := p.parseAnd(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, fmt.Errorf("gmail query: unexpected (%c) at position (%d)", p.peek(), p.pos)
+	}
+	return q, nil
+}
+
+type queryParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *queryParser) eof() bool  { return p.pos >= len(p.input) }
+func (p *queryParser) peek() rune { return p.input[p.pos] }
+
+func (p *queryParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+// parseAnd parses adjacent nodes until EOF or the closing rune `end`.
+func (p *queryParser) parseAnd(end rune) (Query, error) {
+	var nodes QueryAnd
+	for {
+		p.skipSpace()
+		if p.eof() || (end != 0 && p.peek() == end) {
+			break
+		}
+		node, err := p.parseOr(end)
+		if err != nil {
+			return nil, err
+		} else if node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *queryParser) parseOr(end rune) (Query, error) {
+	first, err := p.parseUnary(end)
+	if err != nil {
+		return nil, err
+	}
+	nodes := QueryOr{first}
+	for {
+		save := p.pos
+		p.skipSpace()
+		if !p.acceptKeyword(queryKeywordOr) {
+			p.pos = save
+			break
+		}
+		p.skipSpace()
+		next, err := p.parseUnary(end)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, next)
+	}
+	if len(nodes) == 1 {
+		return first, nil
+	}
+	return nodes, nil
+}
+
+// acceptKeyword consumes `kw` if it is the next whole word.
+func (p *queryParser) acceptKeyword(kw string) bool {
+	r := []rune(kw)
+	if p.pos+len(r) > len(p.input) || string(p.input[p.pos:p.pos+len(r)]) != kw {
+		return false
+	}
+	if next := p.pos + len(r); next < len(p.input) && !unicode.IsSpace(p.input[next]) {
+		return false
+	}
+	p.pos += len(r)
+	return true
+}
+
+func (p *queryParser) parseUnary(end rune) (Query, error) {
+	p.skipSpace()
+	if p.eof() || p.peek() == end {
+		return nil, fmt.Errorf("gmail query: expected term at position (%d)", p.pos)
+	}
+	if p.acceptKeyword(queryKeywordAnd) {
+		return p.parseUnary(end)
+	}
+	if p.peek() == '-' && p.pos+1 < len(p.input) && !unicode.IsSpace(p.input[p.pos+1]) {
+		p.pos++
+		node, err := p.parseUnary(end)
+		if err != nil {
+			return nil, err
+		}
+		return Not(node), nil
+	}
+	switch p.peek() {
+	case '(':
+		p.pos++
+		node, err := p.parseAnd(')')
+		if err != nil {
+			return nil, err
+		} else if p.eof() {
+			return nil, fmt.Errorf("gmail query: missing closing (%c)", ')')
+		}
+		p.pos++
+		return node, nil
+	case '{':
+		p.pos++
+		var nodes QueryOr
+		for {
+			p.skipSpace()
+			if p.eof() {
+				return nil, fmt.Errorf("gmail query: missing closing (%c)", '}')
+			} else if p.peek() == '}' {
+				p.pos++
+				break
+			} else if p.acceptKeyword(queryKeywordOr) {
+				// Terms in braces are already ORed; an explicit OR is redundant.
+				continue
+			}
+			node, err := p.parseUnary('}')
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		}
+		if len(nodes) == 1 {
+			return nodes[0], nil
+		}
+		return nodes, nil
+	case '"':
+		val, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		return NewQueryPhrase(val), nil
+	}
+	return p.parseTerm()
+}
+
+func (p *queryParser) parseQuoted() (string, error) {
+	start := p.pos
+	p.pos++
+	for !p.eof() && p.peek() != '"' {
+		p.pos++
+	}
+	if p.eof() {
+		return "", fmt.Errorf("gmail query: unterminated quote at position (%d)", start)
+	}
+	val := string(p.input[start+1 : p.pos])
+	p.pos++
+	return val, nil
+}
+
+func (p *queryParser) parseTerm() (Query, error) {
+	word := p.readWord()
+	op, val, ok := strings.Cut(word, ":")
+	if !ok || op == "" || !isQueryOperatorName(op) {
+		if word == "" {
+			return nil, fmt.Errorf("gmail query: unexpected (%c) at position (%d)", p.peek(), p.pos)
+		}
+		return QueryTerm{Value: word}, nil
+	}
+	if val != "" || p.eof() {
+		return NewQueryTerm(op, val), nil
+	}
+	switch p.peek() {
+	case '"':
+		v, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		return QueryTerm{Operator: op, Value: v, Exact: true}, nil
+	case '(':
+		start := p.pos
+		depth := 0
+		for ; !p.eof(); p.pos++ {
+			if p.peek() == '(' {
+				depth++
+			} else if p.peek() == ')' {
+				if depth--; depth == 0 {
+					p.pos++
+					return NewQueryTerm(op, string(p.input[start:p.pos])), nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("gmail query: missing closing (%c)", ')')
+	}
+	return NewQueryTerm(op, val), nil
+}
+
+func (p *queryParser) readWord() string {
+	start := p.pos
+	for !p.eof() {
+		r := p.peek()
+		if unicode.IsSpace(r) || strings.ContainsRune(`"(){}`, r) {
+			break
+		}
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+func isQueryOperatorName(s string) bool {
+	for _, r := range s {
+		if !(unicode.IsLower(r) || r == '_' || unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package gmailutil
+
+import (
+	"testing"
+)
+
+var queryEncodeTests = []struct {
+	query Query
+	want  string
+}{
+	{NewQueryTerm(OperatorFrom, "foo@example.com"), "from:foo@example.com"},
+	{NewQueryTerm(OperatorSubject, "quarterly report"), `subject:"quarterly report"`},
+	{NewQueryPhrase("hello world"), `"hello world"`},
+	{NewQueryPhrase("hello"), `"hello"`},
+	{And(NewQueryTerm(OperatorIs, IsUnread), NewQueryTerm(OperatorHas, HasAttachment)), "is:unread has:attachment"},
+	{Or(NewQueryTerm(OperatorFrom, "a@example.com"), NewQueryTerm(OperatorFrom, "b@example.com")), "from:a@example.com OR from:b@example.com"},
+	{And(NewQueryTerm(OperatorLabel, "work"), Or(NewQueryTerm(OperatorFrom, "a"), NewQueryTerm(OperatorFrom, "b"))), "label:work (from:a OR from:b)"},
+	{Or(And(NewQueryTerm(OperatorFrom, "a"), NewQueryTerm(OperatorIs, IsStarred)), NewQueryTerm(OperatorTo, "b")), "(from:a is:starred) OR to:b"},
+	{Not(NewQueryTerm(OperatorIn, "inbox")), "-in:inbox"},
+	{Not(And(NewQueryTerm(OperatorFrom, "a"), NewQueryTerm(OperatorTo, "b"))), "-(from:a to:b)"},
+	{And(NewQueryTerm(OperatorFrom, ""), nil, Or()), ""},
+	{And(Or(NewQueryTerm(OperatorFrom, "a")), NewQueryTerm(OperatorTo, "b")), "from:a to:b"},
+	{NewQueryTerm(OperatorFrom, "(a OR b)"), "from:(a OR b)"},
+	{QueryTerm{Value: "OR"}, `"OR"`},
+}
+
+func TestQueryEncode(t *testing.T) {
+	for _, tt := range queryEncodeTests {
+		if got := tt.query.Encode(); got != tt.want {
+			t.Errorf("Query.Encode() mismatch: want [%s] got [%s]", tt.want, got)
+		}
+	}
+}
+
+var queryParseTests = []struct {
+	input string
+	want  string
+}{
+	{"from:foo@example.com", "from:foo@example.com"},
+	{"from:a to:b", "from:a to:b"},
+	{"from:a OR from:b", "from:a OR from:b"},
+	{"label:work from:a OR from:b", "label:work (from:a OR from:b)"},
+	{"{from:a from:b} is:unread", "(from:a OR from:b) is:unread"},
+	{"{from:a OR from:b}", "from:a OR from:b"},
+	{"(from:a is:starred) OR to:b", "(from:a is:starred) OR to:b"},
+	{`subject:"quarterly report" -in:spam`, `subject:"quarterly report" -in:spam`},
+	{`"exact phrase"`, `"exact phrase"`},
+	{"-(from:a to:b)", "-(from:a to:b)"},
+	{"from:a AND to:b", "from:a to:b"},
+	{"subject:(dinner movie)", "subject:(dinner movie)"},
+	{"after:2016/01/02 before:2019/11/12", "after:2016/01/02 before:2019/11/12"},
+	{"rfc822msgid:<abc@example.com>", "rfc822msgid:<abc@example.com>"},
+	{"larger:10M has:attachment filename:pdf", "larger:10M has:attachment filename:pdf"},
+	{"  ", ""},
+}
+
+func TestParseQuery(t *testing.T) {
+	for _, tt := range queryParseTests {
+		q, err := ParseQuery(tt.input)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) error: [%v]", tt.input, err)
+			continue
+		}
+		if got := q.Encode(); got != tt.want {
+			t.Errorf("ParseQuery(%q).Encode() mismatch: want [%s] got [%s]", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, input := range []string{`"unterminated`, "(from:a", "{from:a", "from:a)", "from:a OR"} {
+		if _, err := ParseQuery(input); err == nil {
+			t.Errorf("ParseQuery(%q) expected error", input)
+		}
+	}
+}
+
+func TestMessagesListQueryOptsEncode(t *testing.T) {
+	opts := MessagesListQueryOpts{
+		From:          "a@example.com",
+		Subject:       "status update",
+		HasAttachment: true,
+		IsUnread:      true,
+		Larger:        "5M",
+		Expr:          Or(NewQueryTerm(OperatorLabel, "x"), NewQueryTerm(OperatorLabel, "y")),
+	}
+	want := `from:a@example.com subject:"status update" larger:5M has:attachment is:unread (label:x OR label:y)`
+	if got := opts.Encode(); got != want {
+		t.Errorf("MessagesListQueryOpts.Encode() mismatch: want [%s] got [%s]", want, got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/grokify/mogo/mime/multipartutil"
 	"github.com/grokify/mogo/net/mailutil"
@@ -38,6 +39,11 @@ type GmailService struct {
 	Service        *gmail.Service
 	UsersService   *gmail.UsersService
 	MessagesAPI    MessagesAPI
+	LabelsAPI      LabelsAPI
+	ThreadsAPI     ThreadsAPI
+	DraftsAPI      DraftsAPI
+	SettingsAPI    SettingsAPI
+	DraftOnly      bool // create drafts instead of sending from `Send()` and the helpers built on it
 }
 
 func NewGmailService(ctx context.Context, client *http.Client) (*GmailService, error) {
@@ -51,6 +57,10 @@ func NewGmailService(ctx context.Context, client *http.Client) (*GmailService, e
 	}
 	gs.UsersService = gmail.NewUsersService(gs.Service)
 	gs.MessagesAPI = MessagesAPI{GmailService: gs}
+	gs.LabelsAPI = newLabelsAPI(gs)
+	gs.ThreadsAPI = ThreadsAPI{GmailService: gs}
+	gs.DraftsAPI = DraftsAPI{GmailService: gs}
+	gs.SettingsAPI = SettingsAPI{GmailService: gs}
 	return gs, nil
 }
 
@@ -76,27 +86,71 @@ func (gs GmailService) validateConfig() error {
 
 // Send is a helper for https://pkg.go.dev/google.golang.org/api/gmail/v1#UsersMessagesService.Send
 func (gs GmailService) Send(ctx context.Context, from string, msg mailutil.MessageWriter, opts ...googleapi.CallOption) (*gmail.Message, error) {
+	return gs.SendInThread(ctx, from, "", msg, opts...)
+}
+
+// SendInThread sends `msg` in the existing thread `threadID`, or in a new thread if `threadID`
+// is empty. Gmail only adds the message to the thread if the `Subject` matches and the
+// `In-Reply-To` or `References` headers refer to a message in it; see `SetReplyHeaders()`.
+// If `gs.DraftOnly` is set, a draft is created instead and its message is returned.
+func (gs GmailService) SendInThread(ctx context.Context, from, threadID string, msg mailutil.MessageWriter, opts ...googleapi.CallOption) (*gmail.Message, error) {
 	if err := gs.validateConfig(); err != nil {
 		return nil, err
 	}
+	gmsg, err := newRawMessage(threadID, msg)
+	if err != nil {
+		return nil, err
+	}
+	return gs.sendMessage(ctx, from, gmsg, opts...)
+}
+
+// SendRaw sends the RFC 5322 message `raw`, such as the output of
+// `mailutil.MessageWriter.Bytes()`, in the thread `threadID` or in a new thread if
+// `threadID` is empty. If `gs.DraftOnly` is set, a draft is created instead and its
+// message is returned.
+func (gs GmailService) SendRaw(ctx context.Context, from, threadID string, raw []byte, opts ...googleapi.CallOption) (*gmail.Message, error) {
+	if err := gs.validateConfig(); err != nil {
+		return nil, err
+	}
+	return gs.sendMessage(ctx, from, newRawMessageBytes(threadID, raw), opts...)
+}
+
+// sendMessage sends an encoded message, or creates a draft with `DraftsAPI` if
+// `gs.DraftOnly` is set and returns its message.
+func (gs GmailService) sendMessage(ctx context.Context, from string, gmsg *gmail.Message, opts ...googleapi.CallOption) (*gmail.Message, error) {
+	if gs.DraftOnly {
+		draft, err := gs.DraftsAPI.createMessage(ctx, from, gmsg, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return draft.Message, nil
+	}
+	return gs.UsersService.Messages.Send(labelUserID(from), gmsg).Context(ctx).Do(opts...)
+}
+
+// newRawMessage encodes `msg` as a `gmail.Message` for sending or for a draft.
+func newRawMessage(threadID string, msg mailutil.MessageWriter) (*gmail.Message, error) {
 	msgBytes, err := msg.Bytes()
 	if err != nil {
 		return nil, err
 	}
-	gmsg := &gmail.Message{
-		Raw: base64.URLEncoding.EncodeToString(msgBytes)}
-	call := gs.UsersService.Messages.Send(from, gmsg)
-	call = call.Context(ctx)
-	return call.Do(opts...)
+	return newRawMessageBytes(threadID, msgBytes), nil
+}
+
+func newRawMessageBytes(threadID string, raw []byte) *gmail.Message {
+	return &gmail.Message{
+		Raw:      base64.URLEncoding.EncodeToString(raw),
+		ThreadId: strings.TrimSpace(threadID)}
 }
 
 // SendSimpleOpts contains options for SendSimple.
 type SendSimpleOpts struct {
-	To       string // Recipient email address
-	Subject  string // Email subject
-	BodyText string // Plain text body
-	BodyHTML string // HTML body (optional, if provided creates multipart/alternative)
-	ReplyTo  string // Reply-To header (optional)
+	To           string // Recipient email address
+	Subject      string // Email subject
+	BodyText     string // Plain text body
+	BodyHTML     string // HTML body (optional, if provided creates multipart/alternative)
+	BodyMarkdown string // Markdown body (optional, if provided replaces BodyText and BodyHTML)
+	ReplyTo      string // Reply-To header (optional)
 }
 
 // SendSimple sends an email with minimal configuration.
@@ -111,12 +165,16 @@ func (gs GmailService) SendSimple(ctx context.Context, from string, opts SendSim
 		Subject: opts.Subject,
 	}
 
-	// Build body parts using NewPartsSetMail which handles text-only or text+HTML
-	partsSet, err := multipartutil.NewPartsSetMail([]byte(opts.BodyText), []byte(opts.BodyHTML), nil)
-	if err != nil {
-		return nil, err
+	if strings.TrimSpace(opts.BodyMarkdown) != "" {
+		msg.BodyPartsSet = NewMarkdownPartsSet(opts.BodyMarkdown)
+	} else {
+		// Build body parts using NewPartsSetMail which handles text-only or text+HTML
+		partsSet, err := multipartutil.NewPartsSetMail([]byte(opts.BodyText), []byte(opts.BodyHTML), nil)
+		if err != nil {
+			return nil, err
+		}
+		msg.BodyPartsSet = partsSet
 	}
-	msg.BodyPartsSet = partsSet
 
 	// Add Reply-To header if specified
 	if opts.ReplyTo != "" {
@@ -0,0 +1,36 @@
+package gmailutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gmail "google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// newTestGmailService returns a `GmailService` backed by `handler`.
+func newTestGmailService(t *testing.T, handler http.Handler) *GmailService {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	svc, err := gmail.NewService(context.Background(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("gmail.NewService() error: [%v]", err)
+	}
+	gs := &GmailService{
+		httpClient:     srv.Client(),
+		APICallOptions: []googleapi.CallOption{},
+		Service:        svc,
+		UsersService:   svc.Users}
+	gs.MessagesAPI = MessagesAPI{GmailService: gs}
+	gs.LabelsAPI = newLabelsAPI(gs)
+	gs.ThreadsAPI = ThreadsAPI{GmailService: gs}
+	gs.DraftsAPI = DraftsAPI{GmailService: gs}
+	gs.SettingsAPI = SettingsAPI{GmailService: gs}
+	return gs
+}
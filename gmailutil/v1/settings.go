@@ -0,0 +1,110 @@
+package gmailutil
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+// SettingsAPI manages mailbox settings such as filters, forwarding addresses, vacation
+// auto-replies and signatures.
+// Changing settings requires `GmailSettingsBasicScope`; forwarding addresses also
+// require `GmailSettingsSharingScope`, and creating labels `GmailLabelsScope`.
+type SettingsAPI struct {
+	GmailService *GmailService
+}
+
+func (sapi *SettingsAPI) validate() error {
+	if sapi.GmailService == nil || sapi.GmailService.UsersService == nil || sapi.GmailService.UsersService.Settings == nil {
+		return ErrGmailServiceCannotBeNil
+	}
+	return nil
+}
+
+// ListFilters returns the message filters for `userID`.
+func (sapi *SettingsAPI) ListFilters(ctx context.Context, userID string) ([]*gmail.Filter, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	}
+	resp, err := sapi.GmailService.UsersService.Settings.Filters.List(labelUserID(userID)).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+	if err != nil {
+		return nil, errorsutil.Wrap(err, "func SettingsAPI.ListFilters() call to Filters.List().Do()")
+	}
+	return resp.Filter, nil
+}
+
+// CreateFilter creates a message filter. Filters cannot be updated; to change a filter,
+// create a new one and delete the old one.
+func (sapi *SettingsAPI) CreateFilter(ctx context.Context, userID string, filter *gmail.Filter) (*gmail.Filter, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	}
+	return sapi.GmailService.UsersService.Settings.Filters.Create(labelUserID(userID), filter).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+}
+
+// DeleteFilter deletes a message filter.
+func (sapi *SettingsAPI) DeleteFilter(ctx context.Context, userID, filterID string) error {
+	if err := sapi.validate(); err != nil {
+		return err
+	}
+	return sapi.GmailService.UsersService.Settings.Filters.Delete(labelUserID(userID), strings.TrimSpace(filterID)).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+}
+
+// ListForwardingAddresses returns the forwarding addresses for `userID`, including
+// addresses pending verification.
+func (sapi *SettingsAPI) ListForwardingAddresses(ctx context.Context, userID string) ([]*gmail.ForwardingAddress, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	}
+	resp, err := sapi.GmailService.UsersService.Settings.ForwardingAddresses.List(labelUserID(userID)).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+	if err != nil {
+		return nil, errorsutil.Wrap(err, "func SettingsAPI.ListForwardingAddresses() call to ForwardingAddresses.List().Do()")
+	}
+	return resp.ForwardingAddresses, nil
+}
+
+// CreateForwardingAddress adds a forwarding address. Unless the address belongs to the
+// same domain, Gmail sends a verification message and the address has the `pending`
+// status until it is confirmed.
+func (sapi *SettingsAPI) CreateForwardingAddress(ctx context.Context, userID, email string) (*gmail.ForwardingAddress, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	}
+	return sapi.GmailService.UsersService.Settings.ForwardingAddresses.Create(labelUserID(userID),
+		&gmail.ForwardingAddress{ForwardingEmail: strings.TrimSpace(email)}).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+}
+
+// DeleteForwardingAddress removes a forwarding address and any verification state.
+func (sapi *SettingsAPI) DeleteForwardingAddress(ctx context.Context, userID, email string) error {
+	if err := sapi.validate(); err != nil {
+		return err
+	}
+	return sapi.GmailService.UsersService.Settings.ForwardingAddresses.Delete(labelUserID(userID), strings.TrimSpace(email)).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+}
+
+// GetAutoForwarding returns the auto-forwarding setting for `userID`.
+func (sapi *SettingsAPI) GetAutoForwarding(ctx context.Context, userID string) (*gmail.AutoForwarding, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	}
+	return sapi.GmailService.UsersService.Settings.GetAutoForwarding(labelUserID(userID)).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+}
+
+// UpdateAutoForwarding updates the auto-forwarding setting. `EmailAddress` must be a
+// verified forwarding address.
+func (sapi *SettingsAPI) UpdateAutoForwarding(ctx context.Context, userID string, af *gmail.AutoForwarding) (*gmail.AutoForwarding, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	}
+	return sapi.GmailService.UsersService.Settings.UpdateAutoForwarding(labelUserID(userID), af).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+}
@@ -0,0 +1,420 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	gmail "google.golang.org/api/gmail/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// System label IDs used by filter actions.
+const (
+	LabelIDInbox     = "INBOX"
+	LabelIDUnread    = "UNREAD"
+	LabelIDStarred   = "STARRED"
+	LabelIDImportant = "IMPORTANT"
+	LabelIDSpam      = "SPAM"
+)
+
+// FiltersConfig is a declarative description of message filters and forwarding
+// addresses, read from YAML or JSON with `ReadFiltersConfigFile()`.
+type FiltersConfig struct {
+	ForwardingAddresses []string     `json:"forwardingAddresses,omitempty" yaml:"forwardingAddresses,omitempty"`
+	Filters             []FilterSpec `json:"filters" yaml:"filters"`
+}
+
+// FilterSpec describes one filter. `Name` is for humans only and is not stored by Gmail.
+type FilterSpec struct {
+	Name     string             `json:"name,omitempty" yaml:"name,omitempty"`
+	Criteria FilterCriteriaSpec `json:"criteria" yaml:"criteria"`
+	Action   FilterActionSpec   `json:"action" yaml:"action"`
+}
+
+// FilterCriteriaSpec selects messages using the same fields as `MessagesListQueryOpts`.
+// `Query` is an additional Gmail search query.
+type FilterCriteriaSpec struct {
+	From          string `json:"from,omitempty" yaml:"from,omitempty"`
+	To            string `json:"to,omitempty" yaml:"to,omitempty"`
+	Cc            string `json:"cc,omitempty" yaml:"cc,omitempty"`
+	Bcc           string `json:"bcc,omitempty" yaml:"bcc,omitempty"`
+	Subject       string `json:"subject,omitempty" yaml:"subject,omitempty"`
+	List          string `json:"list,omitempty" yaml:"list,omitempty"`
+	Filename      string `json:"filename,omitempty" yaml:"filename,omitempty"`
+	Category      string `json:"category,omitempty" yaml:"category,omitempty"`
+	Larger        string `json:"larger,omitempty" yaml:"larger,omitempty"`
+	Smaller       string `json:"smaller,omitempty" yaml:"smaller,omitempty"`
+	HasAttachment bool   `json:"hasAttachment,omitempty" yaml:"hasAttachment,omitempty"`
+	Query         string `json:"query,omitempty" yaml:"query,omitempty"`
+	NegatedQuery  string `json:"negatedQuery,omitempty" yaml:"negatedQuery,omitempty"` // messages matching this are excluded
+	ExcludeChats  bool   `json:"excludeChats,omitempty" yaml:"excludeChats,omitempty"`
+}
+
+// FilterActionSpec describes what a filter does, with labels referenced by name.
+type FilterActionSpec struct {
+	AddLabels      []string `json:"addLabels,omitempty" yaml:"addLabels,omitempty"` // created on apply if missing
+	RemoveLabels   []string `json:"removeLabels,omitempty" yaml:"removeLabels,omitempty"`
+	Archive        bool     `json:"archive,omitempty" yaml:"archive,omitempty"`   // remove `INBOX`
+	MarkRead       bool     `json:"markRead,omitempty" yaml:"markRead,omitempty"` // remove `UNREAD`
+	Star           bool     `json:"star,omitempty" yaml:"star,omitempty"`
+	Important      bool     `json:"important,omitempty" yaml:"important,omitempty"`
+	NeverImportant bool     `json:"neverImportant,omitempty" yaml:"neverImportant,omitempty"`
+	NeverSpam      bool     `json:"neverSpam,omitempty" yaml:"neverSpam,omitempty"`
+	Trash          bool     `json:"trash,omitempty" yaml:"trash,omitempty"`
+	Category       string   `json:"category,omitempty" yaml:"category,omitempty"` // e.g. `promotions`
+	Forward        string   `json:"forward,omitempty" yaml:"forward,omitempty"`   // a verified forwarding address
+}
+
+// ReadFiltersConfigFile reads a `FiltersConfig` from a YAML or JSON file.
+func ReadFiltersConfigFile(path string) (*FiltersConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFiltersConfig(data)
+}
+
+// ParseFiltersConfig parses a `FiltersConfig` from YAML or JSON, which is valid YAML.
+// Unknown fields are rejected so that typos do not silently widen a filter.
+func ParseFiltersConfig(data []byte) (*FiltersConfig, error) {
+	cfg := &FiltersConfig{}
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return nil, errorsutil.Wrap(err, "parse filters config")
+	}
+	for i, f := range cfg.Filters {
+		if _, err := f.Criteria.QueryOpts(); err != nil {
+			return nil, fmt.Errorf("filter (%d) criteria: %w", i, err)
+		} else if f.Criteria.FilterCriteria() == nil {
+			return nil, fmt.Errorf("filter (%d) has no criteria", i)
+		} else if len(f.Action.labelChanges()) == 0 && strings.TrimSpace(f.Action.Forward) == "" {
+			return nil, fmt.Errorf("filter (%d) has no action", i)
+		}
+	}
+	return cfg, nil
+}
+
+// QueryOpts returns the criteria as `MessagesListQueryOpts`, with `Query` parsed into
+// `Expr`, for example to preview matching messages with `MessagesAPI.ListAll()`.
+func (c FilterCriteriaSpec) QueryOpts() (MessagesListQueryOpts, error) {
+	opts := MessagesListQueryOpts{
+		From:          c.From,
+		To:            c.To,
+		Cc:            c.Cc,
+		Bcc:           c.Bcc,
+		Subject:       c.Subject,
+		List:          c.List,
+		Filename:      c.Filename,
+		Category:      c.Category,
+		Larger:        c.Larger,
+		Smaller:       c.Smaller,
+		HasAttachment: c.HasAttachment}
+	if q := strings.TrimSpace(c.Query); q != "" {
+		expr, err := ParseQuery(q)
+		if err != nil {
+			return opts, err
+		}
+		opts.Expr = expr
+	}
+	return opts, nil
+}
+
+// FilterCriteria returns the criteria for `users.settings.filters.create`, or nil if
+// the criteria are empty.
+func (c FilterCriteriaSpec) FilterCriteria() *gmail.FilterCriteria {
+	opts, err := c.QueryOpts()
+	if err != nil {
+		return nil
+	}
+	fc := NewFilterCriteria(opts)
+	fc.NegatedQuery = strings.TrimSpace(c.NegatedQuery)
+	fc.ExcludeChats = c.ExcludeChats
+	if fc.From == "" && fc.To == "" && fc.Subject == "" && fc.Query == "" && fc.NegatedQuery == "" && !fc.HasAttachment {
+		return nil
+	}
+	return fc
+}
+
+// NewFilterCriteria converts `MessagesListQueryOpts` to filter criteria. `From`, `To`,
+// `Subject` and `HasAttachment` map to the corresponding criteria fields, and all other
+// options are encoded in the criteria `Query`.
+func NewFilterCriteria(opts MessagesListQueryOpts) *gmail.FilterCriteria {
+	opts.TrimSpace()
+	fc := &gmail.FilterCriteria{
+		From:          opts.From,
+		To:            opts.To,
+		Subject:       opts.Subject,
+		HasAttachment: opts.HasAttachment}
+	opts.From, opts.To, opts.Subject, opts.HasAttachment = "", "", "", false
+	fc.Query = opts.Encode()
+	return fc
+}
+
+// labelChanges returns the label names added and removed by the action, keyed by name
+// with true for added.
+func (a FilterActionSpec) labelChanges() map[string]bool {
+	changes := map[string]bool{}
+	for _, name := range a.AddLabels {
+		if name = NormalizeLabelPath(name); name != "" {
+			changes[name] = true
+		}
+	}
+	for _, name := range a.RemoveLabels {
+		if name = NormalizeLabelPath(name); name != "" {
+			changes[name] = false
+		}
+	}
+	set := func(cond bool, name string, add bool) {
+		if cond {
+			changes[name] = add
+		}
+	}
+	set(a.Archive, LabelIDInbox, false)
+	set(a.MarkRead, LabelIDUnread, false)
+	set(a.Star, LabelIDStarred, true)
+	set(a.Important, LabelIDImportant, true)
+	set(a.NeverImportant, LabelIDImportant, false)
+	set(a.NeverSpam, LabelIDSpam, false)
+	set(a.Trash, LabelIDTrash, true)
+	if c := strings.TrimSpace(a.Category); c != "" {
+		changes["CATEGORY_"+strings.ToUpper(c)] = true
+	}
+	return changes
+}
+
+// FilterState is a filter with labels referenced by name, used to compare filters in
+// a config with those on the server.
+type FilterState struct {
+	ID           string `json:"-"` // server filter ID, empty for filters to create
+	Name         string `json:"-"`
+	Criteria     gmail.FilterCriteria
+	AddLabels    []string
+	RemoveLabels []string
+	Forward      string
+}
+
+func (fs FilterState) key() string {
+	b, _ := json.Marshal(fs)
+	return strings.ToLower(string(b))
+}
+
+// String describes the filter, e.g. `from:(a@example.com) => +Label -INBOX`.
+func (fs FilterState) String() string {
+	var crit []string
+	add := func(k, v string) {
+		if v != "" {
+			crit = append(crit, k+":("+v+")")
+		}
+	}
+	add("from", fs.Criteria.From)
+	add("to", fs.Criteria.To)
+	add("subject", fs.Criteria.Subject)
+	if fs.Criteria.HasAttachment {
+		crit = append(crit, "has:attachment")
+	}
+	add("query", fs.Criteria.Query)
+	add("-query", fs.Criteria.NegatedQuery)
+	var acts []string
+	for _, l := range fs.AddLabels {
+		acts = append(acts, "+"+l)
+	}
+	for _, l := range fs.RemoveLabels {
+		acts = append(acts, "-"+l)
+	}
+	if fs.Forward != "" {
+		acts = append(acts, "forward:"+fs.Forward)
+	}
+	return strings.Join(crit, " ") + " => " + strings.Join(acts, " ")
+}
+
+// FiltersDiff is the difference between a `FiltersConfig` and the server. Gmail filters
+// cannot be updated, so a changed filter is a create and a delete.
+type FiltersDiff struct {
+	Create                    []FilterState
+	Delete                    []FilterState // server filters not in the config, if pruning
+	Unchanged                 []FilterState
+	Unmanaged                 []FilterState // server filters not in the config, if not pruning
+	CreateLabels              []string      // label names used by new filters which do not exist
+	CreateForwardingAddresses []string
+}
+
+// IsEmpty reports if applying the diff would change nothing.
+func (d *FiltersDiff) IsEmpty() bool {
+	return len(d.Create) == 0 && len(d.Delete) == 0 && len(d.CreateLabels) == 0 && len(d.CreateForwardingAddresses) == 0
+}
+
+// DiffFilters compares `cfg` with the filters and forwarding addresses of `userID`.
+// If `prune` is true, server filters not in `cfg` are deleted by `ApplyFilters()`;
+// otherwise they are reported as unmanaged. Forwarding addresses are only added.
+func (sapi *SettingsAPI) DiffFilters(ctx context.Context, userID string, cfg *FiltersConfig, prune bool) (*FiltersDiff, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	} else if cfg == nil {
+		return nil, fmt.Errorf("filters config cannot be nil")
+	}
+	userID = labelUserID(userID)
+	lapi := &sapi.GmailService.LabelsAPI
+	diff := &FiltersDiff{}
+
+	current, err := sapi.FilterStates(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	have := map[string]FilterState{}
+	var haveOrder []string
+	for _, fs := range current {
+		if _, ok := have[fs.key()]; !ok {
+			haveOrder = append(haveOrder, fs.key())
+		}
+		have[fs.key()] = fs
+	}
+
+	want := map[string]bool{}
+	missingLabels := map[string]bool{}
+	for _, spec := range cfg.Filters {
+		fs := FilterState{Name: spec.Name, Forward: strings.TrimSpace(spec.Action.Forward)}
+		if fc := spec.Criteria.FilterCriteria(); fc != nil {
+			fs.Criteria = *fc
+		}
+		for name, add := range spec.Action.labelChanges() {
+			label, err := lapi.GetByName(ctx, userID, name)
+			if err != nil {
+				return nil, err
+			} else if label != nil {
+				name = label.Name
+			} else if add {
+				missingLabels[name] = true
+			} else {
+				return nil, fmt.Errorf("filter (%s) removes label that does not exist (%s)", fs.String(), name)
+			}
+			if add {
+				fs.AddLabels = append(fs.AddLabels, name)
+			} else {
+				fs.RemoveLabels = append(fs.RemoveLabels, name)
+			}
+		}
+		fs.normalize()
+		if want[fs.key()] {
+			continue
+		}
+		want[fs.key()] = true
+		if cur, ok := have[fs.key()]; ok {
+			fs.ID = cur.ID
+			diff.Unchanged = append(diff.Unchanged, fs)
+		} else {
+			diff.Create = append(diff.Create, fs)
+		}
+	}
+	for _, k := range haveOrder {
+		if !want[k] {
+			if prune {
+				diff.Delete = append(diff.Delete, have[k])
+			} else {
+				diff.Unmanaged = append(diff.Unmanaged, have[k])
+			}
+		}
+	}
+	for name := range missingLabels {
+		diff.CreateLabels = append(diff.CreateLabels, name)
+	}
+	slices.Sort(diff.CreateLabels)
+
+	if len(cfg.ForwardingAddresses) > 0 {
+		addrs, err := sapi.ListForwardingAddresses(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, email := range cfg.ForwardingAddresses {
+			email = strings.TrimSpace(email)
+			if email != "" && !slices.ContainsFunc(addrs, func(fa *gmail.ForwardingAddress) bool {
+				return fa != nil && strings.EqualFold(fa.ForwardingEmail, email)
+			}) {
+				diff.CreateForwardingAddresses = append(diff.CreateForwardingAddresses, email)
+			}
+		}
+	}
+	return diff, nil
+}
+
+// FilterStates returns the filters of `userID` with label IDs resolved to names.
+func (sapi *SettingsAPI) FilterStates(ctx context.Context, userID string) ([]FilterState, error) {
+	filters, err := sapi.ListFilters(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	lapi := &sapi.GmailService.LabelsAPI
+	var states []FilterState
+	for _, f := range filters {
+		if f == nil {
+			continue
+		}
+		fs := FilterState{ID: f.Id}
+		if f.Criteria != nil {
+			fs.Criteria = *f.Criteria
+		}
+		if f.Action != nil {
+			if fs.AddLabels, err = lapi.LabelNames(ctx, userID, f.Action.AddLabelIds); err != nil {
+				return nil, err
+			} else if fs.RemoveLabels, err = lapi.LabelNames(ctx, userID, f.Action.RemoveLabelIds); err != nil {
+				return nil, err
+			}
+			fs.Forward = f.Action.Forward
+		}
+		fs.normalize()
+		states = append(states, fs)
+	}
+	return states, nil
+}
+
+func (fs *FilterState) normalize() {
+	slices.Sort(fs.AddLabels)
+	slices.Sort(fs.RemoveLabels)
+	fs.AddLabels = slices.Compact(fs.AddLabels)
+	fs.RemoveLabels = slices.Compact(fs.RemoveLabels)
+}
+
+// ApplyFilters applies a diff from `DiffFilters()`: it creates forwarding addresses and
+// missing labels, creates new filters and then deletes pruned filters.
+func (sapi *SettingsAPI) ApplyFilters(ctx context.Context, userID string, diff *FiltersDiff) error {
+	if err := sapi.validate(); err != nil {
+		return err
+	} else if diff == nil {
+		return nil
+	}
+	userID = labelUserID(userID)
+	lapi := &sapi.GmailService.LabelsAPI
+	for _, email := range diff.CreateForwardingAddresses {
+		if _, err := sapi.CreateForwardingAddress(ctx, userID, email); err != nil {
+			return errorsutil.Wrapf(err, "create forwarding address (%s)", email)
+		}
+	}
+	for _, name := range diff.CreateLabels {
+		if _, err := lapi.CreatePath(ctx, userID, name); err != nil {
+			return errorsutil.Wrapf(err, "create label (%s)", name)
+		}
+	}
+	for _, fs := range diff.Create {
+		criteria := fs.Criteria
+		f := &gmail.Filter{Criteria: &criteria, Action: &gmail.FilterAction{Forward: fs.Forward}}
+		var err error
+		if f.Action.AddLabelIds, err = lapi.LabelIDs(ctx, userID, fs.AddLabels); err != nil {
+			return err
+		} else if f.Action.RemoveLabelIds, err = lapi.LabelIDs(ctx, userID, fs.RemoveLabels); err != nil {
+			return err
+		} else if _, err := sapi.CreateFilter(ctx, userID, f); err != nil {
+			return errorsutil.Wrapf(err, "create filter (%s)", fs.String())
+		}
+	}
+	for _, fs := range diff.Delete {
+		if err := sapi.DeleteFilter(ctx, userID, fs.ID); err != nil {
+			return errorsutil.Wrapf(err, "delete filter (%s)", fs.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+const testFiltersConfig = `
+forwardingAddresses:
+  - archive@example.com
+  - new@example.com
+filters:
+  - name: newsletters
+    criteria:
+      from: news@example.com
+    action:
+      addLabels: [newsletters]
+      archive: true
+  - name: receipts
+    criteria:
+      subject: receipt
+      hasAttachment: true
+      larger: 1M
+    action:
+      addLabels: [Receipts/2026]
+      markRead: true
+`
+
+// testSettingsServer serves labels, filters and forwarding addresses from memory.
+type testSettingsServer struct {
+	mu      sync.Mutex
+	labels  []*gmail.Label
+	filters []*gmail.Filter
+	deleted []string
+	fwd     []string
+}
+
+func newTestSettingsService(t *testing.T, srv *testSettingsServer) *GmailService {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/me/labels", func(w http.ResponseWriter, r *http.Request) {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(&gmail.ListLabelsResponse{Labels: srv.labels})
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/labels", func(w http.ResponseWriter, r *http.Request) {
+		var label gmail.Label
+		if err := json.NewDecoder(r.Body).Decode(&label); err != nil {
+			t.Errorf("decode label error: [%v]", err)
+			return
+		}
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		label.Id = "Label_" + label.Name
+		srv.labels = append(srv.labels, &label)
+		_ = json.NewEncoder(w).Encode(&label)
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/settings/filters", func(w http.ResponseWriter, r *http.Request) {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(&gmail.ListFiltersResponse{Filter: srv.filters})
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/settings/filters", func(w http.ResponseWriter, r *http.Request) {
+		var f gmail.Filter
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			t.Errorf("decode filter error: [%v]", err)
+			return
+		}
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		f.Id = "new-filter"
+		srv.filters = append(srv.filters, &f)
+		_ = json.NewEncoder(w).Encode(&f)
+	})
+	mux.HandleFunc("DELETE /gmail/v1/users/me/settings/filters/{id}", func(w http.ResponseWriter, r *http.Request) {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		srv.deleted = append(srv.deleted, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/settings/forwardingAddresses", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&gmail.ListForwardingAddressesResponse{ForwardingAddresses: []*gmail.ForwardingAddress{
+			{ForwardingEmail: "Archive@example.com", VerificationStatus: "accepted"}}})
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/settings/forwardingAddresses", func(w http.ResponseWriter, r *http.Request) {
+		var fa gmail.ForwardingAddress
+		if err := json.NewDecoder(r.Body).Decode(&fa); err != nil {
+			t.Errorf("decode forwarding address error: [%v]", err)
+			return
+		}
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		srv.fwd = append(srv.fwd, fa.ForwardingEmail)
+		fa.VerificationStatus = "pending"
+		_ = json.NewEncoder(w).Encode(&fa)
+	})
+	return newTestGmailService(t, mux)
+}
+
+func formatFilterStates(states []FilterState) string {
+	var s []string
+	for _, fs := range states {
+		s = append(s, fs.String())
+	}
+	return strings.Join(s, "; ")
+}
+
+func TestFiltersDiffApply(t *testing.T) {
+	cfg, err := ParseFiltersConfig([]byte(testFiltersConfig))
+	if err != nil {
+		t.Fatalf("ParseFiltersConfig() error: [%v]", err)
+	}
+	srv := &testSettingsServer{
+		labels: []*gmail.Label{
+			{Id: "INBOX", Name: "INBOX"}, {Id: "UNREAD", Name: "UNREAD"},
+			{Id: "TRASH", Name: "TRASH"}, {Id: "Label_1", Name: "Newsletters"}},
+		filters: []*gmail.Filter{{
+			Id:       "f1",
+			Criteria: &gmail.FilterCriteria{From: "news@example.com"},
+			Action:   &gmail.FilterAction{AddLabelIds: []string{"Label_1"}, RemoveLabelIds: []string{"INBOX"}},
+		}, {
+			Id:       "f2",
+			Criteria: &gmail.FilterCriteria{Subject: "old"},
+			Action:   &gmail.FilterAction{AddLabelIds: []string{"TRASH"}},
+		}}}
+	gs := newTestSettingsService(t, srv)
+	ctx := context.Background()
+
+	diff, err := gs.SettingsAPI.DiffFilters(ctx, "", cfg, false)
+	if err != nil {
+		t.Fatalf("SettingsAPI.DiffFilters() error: [%v]", err)
+	}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"create", formatFilterStates(diff.Create), "subject:(receipt) has:attachment query:(larger:1M) => +Receipts/2026 -UNREAD"},
+		{"unchanged", formatFilterStates(diff.Unchanged), "from:(news@example.com) => +Newsletters -INBOX"},
+		{"unmanaged", formatFilterStates(diff.Unmanaged), "subject:(old) => +TRASH"},
+		{"delete", formatFilterStates(diff.Delete), ""},
+		{"labels", strings.Join(diff.CreateLabels, ","), "Receipts/2026"},
+		{"forwarding", strings.Join(diff.CreateForwardingAddresses, ","), "new@example.com"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("SettingsAPI.DiffFilters() %s mismatch: want (%s), got (%s)", tt.name, tt.want, tt.got)
+		}
+	}
+
+	if diff, err = gs.SettingsAPI.DiffFilters(ctx, "", cfg, true); err != nil {
+		t.Fatalf("SettingsAPI.DiffFilters() prune error: [%v]", err)
+	} else if err := gs.SettingsAPI.ApplyFilters(ctx, "", diff); err != nil {
+		t.Fatalf("SettingsAPI.ApplyFilters() error: [%v]", err)
+	}
+	if strings.Join(srv.deleted, ",") != "f2" || strings.Join(srv.fwd, ",") != "new@example.com" {
+		t.Errorf("SettingsAPI.ApplyFilters() mismatch: deleted (%v) forwarding (%v)", srv.deleted, srv.fwd)
+	}
+	created := srv.filters[len(srv.filters)-1]
+	if created.Id != "new-filter" || created.Criteria.Query != "larger:1M" ||
+		strings.Join(created.Action.AddLabelIds, ",") != "Label_Receipts/2026" ||
+		strings.Join(created.Action.RemoveLabelIds, ",") != "UNREAD" {
+		t.Errorf("SettingsAPI.ApplyFilters() created filter mismatch: got criteria [%+v] action [%+v]", created.Criteria, created.Action)
+	}
+
+	// the fake server does not remove deleted filters, so drop f2 before diffing again
+	srv.filters = srv.filters[:1]
+	srv.filters = append(srv.filters, created)
+	if diff, err = gs.SettingsAPI.DiffFilters(ctx, "", cfg, true); err != nil {
+		t.Fatalf("SettingsAPI.DiffFilters() after apply error: [%v]", err)
+	} else if len(diff.Create) != 0 || len(diff.Delete) != 0 || len(diff.CreateLabels) != 0 {
+		t.Errorf("SettingsAPI.DiffFilters() after apply want no changes, got [%+v]", diff)
+	}
+}
+
+func TestParseFiltersConfigErrors(t *testing.T) {
+	tests := []string{
+		`filters: [{criteria: {form: a@example.com}, action: {archive: true}}]`,
+		`filters: [{criteria: {}, action: {archive: true}}]`,
+		`filters: [{criteria: {from: a@example.com}, action: {}}]`,
+		`filters: [{criteria: {query: "(unclosed"}, action: {archive: true}}]`,
+	}
+	for _, tt := range tests {
+		if _, err := ParseFiltersConfig([]byte(tt)); err == nil {
+			t.Errorf("ParseFiltersConfig(%s) want error, got nil", tt)
+		}
+	}
+
+	cfg, err := ParseFiltersConfig([]byte(`{"filters": [{"criteria": {"from": "a@example.com", "query": "list:dev"}, "action": {"star": true}}]}`))
+	if err != nil {
+		t.Fatalf("ParseFiltersConfig(json) error: [%v]", err)
+	}
+	fc := cfg.Filters[0].Criteria.FilterCriteria()
+	if fc.From != "a@example.com" || fc.Query != "list:dev" {
+		t.Errorf("FilterCriteria() mismatch: got [%+v]", fc)
+	}
+}
@@ -0,0 +1,66 @@
+package gmailutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+var ErrSendAsPrimaryNotFound = errors.New("primary send-as alias not found")
+
+// ListSendAs returns the send-as aliases for `userID`, including the primary address.
+func (sapi *SettingsAPI) ListSendAs(ctx context.Context, userID string) ([]*gmail.SendAs, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	}
+	resp, err := sapi.GmailService.UsersService.Settings.SendAs.List(labelUserID(userID)).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+	if err != nil {
+		return nil, errorsutil.Wrap(err, "func SettingsAPI.ListSendAs() call to SendAs.List().Do()")
+	}
+	return resp.SendAs, nil
+}
+
+// PrimarySendAs returns the primary send-as alias for `userID`, which is the user's
+// own address.
+func (sapi *SettingsAPI) PrimarySendAs(ctx context.Context, userID string) (*gmail.SendAs, error) {
+	aliases, err := sapi.ListSendAs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sa := range aliases {
+		if sa != nil && sa.IsPrimary {
+			return sa, nil
+		}
+	}
+	return nil, ErrSendAsPrimaryNotFound
+}
+
+// UpdateSignature sets the HTML signature of the send-as alias `sendAsEmail`, or of the
+// primary address if `sendAsEmail` is empty. An empty `signatureHTML` clears the
+// signature.
+func (sapi *SettingsAPI) UpdateSignature(ctx context.Context, userID, sendAsEmail, signatureHTML string) (*gmail.SendAs, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	}
+	sendAsEmail = strings.TrimSpace(sendAsEmail)
+	if sendAsEmail == "" {
+		primary, err := sapi.PrimarySendAs(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		sendAsEmail = primary.SendAsEmail
+	}
+	resp, err := sapi.GmailService.UsersService.Settings.SendAs.Patch(labelUserID(userID), sendAsEmail,
+		&gmail.SendAs{
+			Signature:       strings.TrimSpace(signatureHTML),
+			ForceSendFields: []string{"Signature"}}).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+	if err != nil {
+		return nil, errorsutil.Wrap(err, "func SettingsAPI.UpdateSignature() call to SendAs.Patch().Do()")
+	}
+	return resp, nil
+}
@@ -0,0 +1,96 @@
+package gmailutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+var (
+	ErrVacationBodyCannotBeEmpty = errors.New("vacation response body cannot be empty")
+	ErrVacationEndBeforeStart    = errors.New("vacation end time must be after start time")
+)
+
+// VacationOpts describes a vacation auto-reply. If `Markdown` is set, it is rendered
+// as the plain text and HTML bodies.
+type VacationOpts struct {
+	Subject      string
+	BodyText     string
+	BodyHTML     string
+	Markdown     string
+	Start        time.Time // optional, replies are sent from this time
+	End          time.Time // optional, replies stop at this time
+	ContactsOnly bool      // only reply to senders in the user's contacts
+	DomainOnly   bool      // only reply to senders in the user's domain, Workspace only
+}
+
+// VacationSettings returns enabled vacation settings for `opts`.
+func (opts VacationOpts) VacationSettings() (*gmail.VacationSettings, error) {
+	vs := &gmail.VacationSettings{
+		EnableAutoReply:       true,
+		ResponseSubject:       strings.TrimSpace(opts.Subject),
+		ResponseBodyPlainText: strings.TrimSpace(opts.BodyText),
+		ResponseBodyHtml:      strings.TrimSpace(opts.BodyHTML),
+		RestrictToContacts:    opts.ContactsOnly,
+		RestrictToDomain:      opts.DomainOnly,
+		ForceSendFields:       []string{"RestrictToContacts", "RestrictToDomain"}}
+	if md := strings.TrimSpace(opts.Markdown); md != "" {
+		vs.ResponseBodyPlainText = strings.TrimSpace(MarkdownToText(md))
+		vs.ResponseBodyHtml = MarkdownToHTML(md)
+	}
+	if vs.ResponseBodyPlainText == "" && vs.ResponseBodyHtml == "" {
+		return nil, ErrVacationBodyCannotBeEmpty
+	}
+	if !opts.Start.IsZero() {
+		vs.StartTime = opts.Start.UnixMilli()
+	}
+	if !opts.End.IsZero() {
+		if !opts.Start.IsZero() && !opts.End.After(opts.Start) {
+			return nil, ErrVacationEndBeforeStart
+		}
+		vs.EndTime = opts.End.UnixMilli()
+	}
+	return vs, nil
+}
+
+// GetVacation returns the vacation auto-reply settings for `userID`.
+func (sapi *SettingsAPI) GetVacation(ctx context.Context, userID string) (*gmail.VacationSettings, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	}
+	return sapi.GmailService.UsersService.Settings.GetVacation(labelUserID(userID)).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+}
+
+// UpdateVacation replaces the vacation auto-reply settings for `userID`.
+func (sapi *SettingsAPI) UpdateVacation(ctx context.Context, userID string, vs *gmail.VacationSettings) (*gmail.VacationSettings, error) {
+	if err := sapi.validate(); err != nil {
+		return nil, err
+	}
+	resp, err := sapi.GmailService.UsersService.Settings.UpdateVacation(labelUserID(userID), vs).
+		Context(ctx).Do(sapi.GmailService.APICallOptions...)
+	if err != nil {
+		return nil, errorsutil.Wrap(err, "func SettingsAPI.UpdateVacation() call to Settings.UpdateVacation().Do()")
+	}
+	return resp, nil
+}
+
+// SetVacation enables a vacation auto-reply for `userID`.
+func (sapi *SettingsAPI) SetVacation(ctx context.Context, userID string, opts VacationOpts) (*gmail.VacationSettings, error) {
+	vs, err := opts.VacationSettings()
+	if err != nil {
+		return nil, err
+	}
+	return sapi.UpdateVacation(ctx, userID, vs)
+}
+
+// ClearVacation disables the vacation auto-reply for `userID`.
+func (sapi *SettingsAPI) ClearVacation(ctx context.Context, userID string) (*gmail.VacationSettings, error) {
+	return sapi.UpdateVacation(ctx, userID, &gmail.VacationSettings{
+		EnableAutoReply: false,
+		ForceSendFields: []string{"EnableAutoReply"}})
+}
@@ -0,0 +1,88 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+func TestVacationOptsSettings(t *testing.T) {
+	start := time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC)
+	end := start.Add(14 * 24 * time.Hour)
+	vs, err := VacationOpts{
+		Subject:      "Out of office",
+		Markdown:     "# Away\n\nBack in January.",
+		Start:        start,
+		End:          end,
+		ContactsOnly: true}.VacationSettings()
+	if err != nil {
+		t.Fatalf("VacationOpts.VacationSettings() error: [%v]", err)
+	}
+	if !vs.EnableAutoReply || !vs.RestrictToContacts || vs.RestrictToDomain ||
+		vs.StartTime != start.UnixMilli() || vs.EndTime != end.UnixMilli() ||
+		vs.ResponseBodyPlainText != "Away\n====\n\nBack in January." ||
+		!strings.Contains(vs.ResponseBodyHtml, ">Away</h1>") {
+		t.Errorf("VacationOpts.VacationSettings() mismatch: got [%+v]", vs)
+	}
+
+	tests := []struct {
+		opts VacationOpts
+		err  error
+	}{
+		{VacationOpts{Subject: "Away"}, ErrVacationBodyCannotBeEmpty},
+		{VacationOpts{BodyText: "Away", Start: end, End: start}, ErrVacationEndBeforeStart},
+	}
+	for _, tt := range tests {
+		if _, err := tt.opts.VacationSettings(); !errors.Is(err, tt.err) {
+			t.Errorf("VacationOpts.VacationSettings() want error (%v), got (%v)", tt.err, err)
+		}
+	}
+}
+
+func TestSettingsVacationSignature(t *testing.T) {
+	var bodies []string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body error: [%v]", err)
+			return
+		}
+		bodies = append(bodies, r.Method+" "+r.URL.Path+" "+strings.TrimSpace(string(b)))
+		_, _ = w.Write(b)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /gmail/v1/users/{userID}/settings/vacation", record)
+	mux.HandleFunc("PATCH /gmail/v1/users/{userID}/settings/sendAs/{email}", record)
+	mux.HandleFunc("GET /gmail/v1/users/{userID}/settings/sendAs", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&gmail.ListSendAsResponse{SendAs: []*gmail.SendAs{
+			{SendAsEmail: "oncall@example.com"},
+			{SendAsEmail: r.PathValue("userID"), IsPrimary: true}}})
+	})
+	gs := newTestGmailService(t, mux)
+	ctx := context.Background()
+
+	if _, err := gs.SettingsAPI.ClearVacation(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("SettingsAPI.ClearVacation() error: [%v]", err)
+	}
+	if _, err := gs.SettingsAPI.UpdateSignature(ctx, "alice@example.com", "", "<b>Alice</b>"); err != nil {
+		t.Fatalf("SettingsAPI.UpdateSignature() error: [%v]", err)
+	}
+	if _, err := gs.SettingsAPI.UpdateSignature(ctx, "", "oncall@example.com", ""); err != nil {
+		t.Fatalf("SettingsAPI.UpdateSignature() clear error: [%v]", err)
+	}
+	want := []string{
+		`PUT /gmail/v1/users/alice@example.com/settings/vacation {"enableAutoReply":false}`,
+		`PATCH /gmail/v1/users/alice@example.com/settings/sendAs/alice@example.com {"signature":"\u003cb\u003eAlice\u003c/b\u003e"}`,
+		`PATCH /gmail/v1/users/me/settings/sendAs/oncall@example.com {"signature":""}`,
+	}
+	if strings.Join(bodies, "\n") != strings.Join(want, "\n") {
+		t.Errorf("SettingsAPI requests mismatch: want\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(bodies, "\n"))
+	}
+}
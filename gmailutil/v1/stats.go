@@ -0,0 +1,272 @@
+package gmailutil
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grokify/gocharts/v2/data/table"
+	"github.com/grokify/mogo/net/mailutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+// Dimensions aggregated by `MessagesAPI.Stats()`.
+const (
+	StatsBySender = "sender"
+	StatsByDomain = "domain"
+	StatsByLabel  = "label"
+	StatsByWeek   = "week"
+)
+
+// StatsDimensions lists the dimensions of `MailboxStats` in report order.
+var StatsDimensions = []string{StatsBySender, StatsByDomain, StatsByLabel, StatsByWeek}
+
+// StatsOpts configures `MessagesAPI.Stats()`.
+type StatsOpts struct {
+	UserID           string
+	Query            MessagesListQueryOpts
+	LabelIDs         []string
+	IncludeSpamTrash bool
+	MaxTotal         int // maximum number of messages to scan, 0 for no limit
+	Concurrency      int // workers retrieving metadata, defaults to `DefaultInflateConcurrency`
+	RequestsPerSec   float64
+	UseBatch         bool
+	Progress         func(scanned int) // optional callback invoked after each chunk
+}
+
+// StatsBucket is the message count and total size for one key of a dimension.
+type StatsBucket struct {
+	Key   string
+	Count int
+	Bytes int64 // sum of `SizeEstimate`
+}
+
+// MailboxStats aggregates message counts and sizes by sender address, sender domain,
+// label and ISO week.
+type MailboxStats struct {
+	Messages   int
+	Bytes      int64
+	Dimensions map[string]map[string]*StatsBucket // buckets by `StatsBy*` dimension and key
+	LabelNames map[string]string                  // label names by ID, used for `StatsByLabel` keys in tables
+	Errors     map[string]error                   // retrieval errors by message ID
+}
+
+// NewMailboxStats returns an empty `MailboxStats`.
+func NewMailboxStats() *MailboxStats {
+	ms := &MailboxStats{
+		Dimensions: map[string]map[string]*StatsBucket{},
+		LabelNames: map[string]string{},
+		Errors:     map[string]error{}}
+	for _, dim := range StatsDimensions {
+		ms.Dimensions[dim] = map[string]*StatsBucket{}
+	}
+	return ms
+}
+
+// Stats scans the messages matching `opts` in metadata format, which includes the
+// `From` header, labels, size estimate and internal date but not message bodies, and
+// aggregates them. Label IDs are resolved to names for reporting. Messages which cannot
+// be retrieved are recorded in `MailboxStats.Errors` and not counted.
+func (mapi *MessagesAPI) Stats(ctx context.Context, opts StatsOpts) (*MailboxStats, error) {
+	if mapi.GmailService == nil {
+		return nil, ErrGmailServiceCannotBeNil
+	}
+	userID := labelUserID(opts.UserID)
+	ms := NewMailboxStats()
+	listOpts := MessagesListOpts{
+		UserID:           userID,
+		Query:            opts.Query,
+		LabelIDs:         opts.LabelIDs,
+		IncludeSpamTrash: opts.IncludeSpamTrash,
+		MaxTotal:         opts.MaxTotal}
+	inflateOpts := InflateOpts{
+		UserID:          userID,
+		Format:          MessageFormatMetadata,
+		MetadataHeaders: []string{mailutil.HeaderFrom},
+		Concurrency:     opts.Concurrency,
+		RequestsPerSec:  opts.RequestsPerSec,
+		UseBatch:        opts.UseBatch}
+
+	var chunk []*gmail.Message
+	flush := func() {
+		res := mapi.InflateMessagesWithOpts(ctx, chunk, inflateOpts)
+		for _, msg := range res.Messages {
+			if msg != nil {
+				ms.Add(msg)
+			}
+		}
+		for id, err := range res.Errors {
+			ms.Errors[id] = err
+		}
+		chunk = chunk[:0]
+		if opts.Progress != nil {
+			opts.Progress(ms.Messages + len(ms.Errors))
+		}
+	}
+	for msg, err := range mapi.ListAll(ctx, listOpts) {
+		if err != nil {
+			return ms, err
+		}
+		if chunk = append(chunk, msg); len(chunk) >= BatchGetMaxSize {
+			flush()
+		}
+	}
+	if len(chunk) > 0 {
+		flush()
+	}
+	if err := ctx.Err(); err != nil {
+		return ms, err
+	}
+
+	var labelIDs []string
+	for id := range ms.Dimensions[StatsByLabel] {
+		labelIDs = append(labelIDs, id)
+	}
+	if len(labelIDs) > 0 {
+		names, err := mapi.GmailService.LabelsAPI.LabelNames(ctx, userID, labelIDs)
+		if err != nil {
+			return ms, err
+		}
+		for i, id := range labelIDs {
+			ms.LabelNames[id] = names[i]
+		}
+	}
+	return ms, nil
+}
+
+// Add counts a message retrieved in metadata or full format.
+func (ms *MailboxStats) Add(msg *gmail.Message) {
+	if msg == nil {
+		return
+	}
+	ms.Messages++
+	ms.Bytes += msg.SizeEstimate
+	add := func(dim, key string) {
+		b, ok := ms.Dimensions[dim][key]
+		if !ok {
+			b = &StatsBucket{Key: key}
+			ms.Dimensions[dim][key] = b
+		}
+		b.Count++
+		b.Bytes += msg.SizeEstimate
+	}
+
+	sender := ""
+	if msg.Payload != nil {
+		sender = statsSender(messagePartHeader(msg.Payload).Get(mailutil.HeaderFrom))
+	}
+	domain := ""
+	if i := strings.LastIndex(sender, "@"); i >= 0 {
+		domain = sender[i+1:]
+	}
+	add(StatsBySender, sender)
+	add(StatsByDomain, domain)
+	for _, id := range msg.LabelIds {
+		add(StatsByLabel, id)
+	}
+	if msg.InternalDate > 0 {
+		year, week := time.UnixMilli(msg.InternalDate).UTC().ISOWeek()
+		add(StatsByWeek, fmt.Sprintf("%04d-W%02d", year, week))
+	}
+}
+
+// statsSender returns the lowercased address of a `From` header value, or the trimmed
+// value if it cannot be parsed.
+func statsSender(from string) string {
+	ap := mail.AddressParser{WordDecoder: newWordDecoder()}
+	if addr, err := ap.Parse(from); err == nil {
+		return strings.ToLower(addr.Address)
+	}
+	return strings.ToLower(strings.TrimSpace(from))
+}
+
+// Buckets returns the buckets of a dimension sorted by count and then size, descending,
+// except for `StatsByWeek` which is sorted chronologically. If `limit` is greater than 0,
+// at most `limit` buckets are returned; weeks are not limited.
+func (ms *MailboxStats) Buckets(dimension string, limit int) []StatsBucket {
+	var buckets []StatsBucket
+	for _, b := range ms.Dimensions[dimension] {
+		buckets = append(buckets, *b)
+	}
+	if dimension == StatsByWeek {
+		slices.SortFunc(buckets, func(a, b StatsBucket) int { return cmp.Compare(a.Key, b.Key) })
+	} else {
+		slices.SortFunc(buckets, func(a, b StatsBucket) int {
+			return cmp.Or(
+				cmp.Compare(b.Count, a.Count),
+				cmp.Compare(b.Bytes, a.Bytes),
+				cmp.Compare(a.Key, b.Key))
+		})
+	}
+	if limit > 0 && len(buckets) > limit && dimension != StatsByWeek {
+		buckets = buckets[:limit]
+	}
+	return buckets
+}
+
+// Table returns a dimension as a table with `Messages`, `Bytes` and `% Messages` columns,
+// which can be written with `WriteCSV()`, `WriteXLSX()` or `Markdown()`.
+func (ms *MailboxStats) Table(dimension string, limit int) (*table.Table, error) {
+	if _, ok := ms.Dimensions[dimension]; !ok {
+		return nil, fmt.Errorf("stats dimension not supported (%s)", dimension)
+	}
+	tbl := table.NewTable(statsDimensionTitle(dimension))
+	tbl.Columns = []string{statsDimensionTitle(dimension), "Messages", "Bytes", "% Messages"}
+	tbl.FormatMap = map[int]string{1: table.FormatInt, 2: table.FormatInt, 3: table.FormatFloat}
+	for _, b := range ms.Buckets(dimension, limit) {
+		key := b.Key
+		if dimension == StatsByLabel {
+			if name, ok := ms.LabelNames[key]; ok {
+				key = name
+			}
+		}
+		share := 0.0
+		if ms.Messages > 0 {
+			share = 100 * float64(b.Count) / float64(ms.Messages)
+		}
+		tbl.Rows = append(tbl.Rows, []string{
+			key,
+			strconv.Itoa(b.Count),
+			strconv.FormatInt(b.Bytes, 10),
+			strconv.FormatFloat(share, 'f', 1, 64)})
+	}
+	return &tbl, nil
+}
+
+// TableSet returns a table for each of `dimensions`, or all `StatsDimensions` if empty,
+// for writing as sheets of an XLSX file.
+func (ms *MailboxStats) TableSet(dimensions []string, limit int) (*table.TableSet, error) {
+	if len(dimensions) == 0 {
+		dimensions = StatsDimensions
+	}
+	ts := table.NewTableSet("Mailbox Stats")
+	for _, dim := range dimensions {
+		tbl, err := ms.Table(dim, limit)
+		if err != nil {
+			return nil, err
+		} else if err := ts.Add(tbl); err != nil {
+			return nil, err
+		}
+	}
+	return ts, nil
+}
+
+func statsDimensionTitle(dimension string) string {
+	switch dimension {
+	case StatsBySender:
+		return "Sender"
+	case StatsByDomain:
+		return "Domain"
+	case StatsByLabel:
+		return "Label"
+	case StatsByWeek:
+		return "Week"
+	default:
+		return dimension
+	}
+}
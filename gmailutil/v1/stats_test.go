@@ -0,0 +1,85 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+func TestMessagesStats(t *testing.T) {
+	week7 := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC).UnixMilli()
+	week8 := time.Date(2026, 2, 17, 12, 0, 0, 0, time.UTC).UnixMilli()
+	msgs := map[string]*gmail.Message{
+		"m1": {Id: "m1", SizeEstimate: 1000, InternalDate: week7, LabelIds: []string{"INBOX", "Label_1"},
+			Payload: &gmail.MessagePart{Headers: []*gmail.MessagePartHeader{{Name: "From", Value: "News <News@Shop.example.com>"}}}},
+		"m2": {Id: "m2", SizeEstimate: 3000, InternalDate: week7, LabelIds: []string{"INBOX"},
+			Payload: &gmail.MessagePart{Headers: []*gmail.MessagePartHeader{{Name: "From", Value: "news@shop.example.com"}}}},
+		"m3": {Id: "m3", SizeEstimate: 500, InternalDate: week8, LabelIds: []string{"INBOX", "Label_1"},
+			Payload: &gmail.MessagePart{Headers: []*gmail.MessagePartHeader{{Name: "From", Value: "=?UTF-8?Q?Bj=C3=B6rn?= <bjorn@example.org>"}}}},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/me/messages", func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("q"); q != "older_than:1y" {
+			t.Errorf("stats list query mismatch: got [%s]", q)
+		}
+		_ = json.NewEncoder(w).Encode(&gmail.ListMessagesResponse{Messages: []*gmail.Message{{Id: "m1"}, {Id: "m2"}, {Id: "m3"}}})
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/messages/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != MessageFormatMetadata {
+			t.Errorf("stats get format mismatch: got [%s]", r.URL.Query().Get("format"))
+		}
+		_ = json.NewEncoder(w).Encode(msgs[r.PathValue("id")])
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/labels", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&gmail.ListLabelsResponse{Labels: []*gmail.Label{
+			{Id: "INBOX", Name: "INBOX"}, {Id: "Label_1", Name: "Newsletters"}}})
+	})
+	gs := newTestGmailService(t, mux)
+
+	ms, err := gs.MessagesAPI.Stats(context.Background(), StatsOpts{
+		Query: MessagesListQueryOpts{OlderThan: "1y"}})
+	if err != nil {
+		t.Fatalf("Stats() error: [%v]", err)
+	}
+	if ms.Messages != 3 || ms.Bytes != 4500 {
+		t.Errorf("Stats() totals mismatch: got messages [%d] bytes [%d]", ms.Messages, ms.Bytes)
+	}
+
+	tests := []struct {
+		dimension string
+		limit     int
+		want      string
+	}{
+		{StatsBySender, 0, "news@shop.example.com:2:4000:66.7;bjorn@example.org:1:500:33.3"},
+		{StatsByDomain, 1, "shop.example.com:2:4000:66.7"},
+		{StatsByLabel, 0, "INBOX:3:4500:100.0;Newsletters:2:1500:66.7"},
+		{StatsByWeek, 0, "2026-W07:2:4000:66.7;2026-W08:1:500:33.3"},
+		{StatsByWeek, 1, "2026-W07:2:4000:66.7;2026-W08:1:500:33.3"},
+	}
+	for _, tt := range tests {
+		tbl, err := ms.Table(tt.dimension, tt.limit)
+		if err != nil {
+			t.Fatalf("MailboxStats.Table(%s) error: [%v]", tt.dimension, err)
+		}
+		var rows []string
+		for _, row := range tbl.Rows {
+			rows = append(rows, strings.Join(row, ":"))
+		}
+		if got := strings.Join(rows, ";"); got != tt.want {
+			t.Errorf("MailboxStats.Table(%s, %d) mismatch: want [%s] got [%s]", tt.dimension, tt.limit, tt.want, got)
+		}
+	}
+
+	ts, err := ms.TableSet(nil, 10)
+	if err != nil || len(ts.Order) != len(StatsDimensions) {
+		t.Errorf("MailboxStats.TableSet() mismatch: got [%v] error [%v]", ts, err)
+	}
+	if _, err := ms.Table("month", 0); err == nil {
+		t.Error("MailboxStats.Table(month) want error, got nil")
+	}
+}
@@ -0,0 +1,236 @@
+package gmailutil
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"strings"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	gmail "google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+var ErrSyncStateStoreCannotBeNil = errors.New("sync state store cannot be nil")
+
+// SyncEventType identifies the kind of change in a `SyncEvent`.
+type SyncEventType string
+
+const (
+	// SyncEventReset is emitted before a full resync, when there is no stored cursor or
+	// the stored history ID has expired. The `SyncEventMessageAdded` events which follow
+	// list every message in the mailbox; consumers should treat them as the full set.
+	SyncEventReset          SyncEventType = "reset"
+	SyncEventMessageAdded   SyncEventType = "messageAdded"
+	SyncEventMessageDeleted SyncEventType = "messageDeleted"
+	SyncEventLabelsAdded    SyncEventType = "labelsAdded"
+	SyncEventLabelsRemoved  SyncEventType = "labelsRemoved"
+)
+
+// History types for `Syncer.HistoryTypes`.
+const (
+	HistoryTypeMessageAdded   = "messageAdded"
+	HistoryTypeMessageDeleted = "messageDeleted"
+	HistoryTypeLabelAdded     = "labelAdded"
+	HistoryTypeLabelRemoved   = "labelRemoved"
+)
+
+// SyncEvent is a single mailbox change.
+type SyncEvent struct {
+	Type      SyncEventType
+	HistoryID uint64 // history record ID, or the starting history ID for a reset
+	MessageID string // empty for `SyncEventReset`
+	ThreadID  string
+	LabelIDs  []string // labels added or removed, or the message labels for other events
+}
+
+// Syncer incrementally synchronizes a mailbox using `users.history.list`. The history ID
+// cursor is read from and written to `Store`.
+type Syncer struct {
+	GmailService *GmailService
+	Store        SyncStateStore
+	UserID       string
+	ListOpts     MessagesListOpts // restricts full resyncs, e.g. by `LabelIDs` or `Query`
+	LabelID      string           // restricts history to changes involving a label
+	HistoryTypes []string         // restricts history to `HistoryType*` values; empty for all
+}
+
+// NewSyncer returns a `Syncer` for the authenticated user.
+func NewSyncer(gs *GmailService, store SyncStateStore) *Syncer {
+	return &Syncer{GmailService: gs, Store: store, UserID: UserIDMe}
+}
+
+// Sync returns an iterator over changes since the stored history ID. Without a stored
+// cursor, or if Gmail reports the history ID as expired, a `SyncEventReset` is followed
+// by a `SyncEventMessageAdded` event for every message matching `ListOpts`.
+//
+// The cursor is saved only after all events have been yielded, so changes are delivered
+// at least once: if iteration stops early or fails, the same events are yielded again by
+// the next `Sync()`.
+func (s *Syncer) Sync(ctx context.Context) iter.Seq2[SyncEvent, error] {
+	return func(yield func(SyncEvent, error) bool) {
+		if s.GmailService == nil {
+			yield(SyncEvent{}, ErrGmailServiceCannotBeNil)
+			return
+		} else if s.Store == nil {
+			yield(SyncEvent{}, ErrSyncStateStoreCannotBeNil)
+			return
+		}
+		userID := labelUserID(s.UserID)
+		startID, err := s.Store.Load(ctx, userID)
+		if err != nil {
+			yield(SyncEvent{}, errorsutil.Wrap(err, "func Syncer.Sync() call to Store.Load()"))
+			return
+		}
+
+		var nextID uint64
+		ok := true
+		if startID > 0 {
+			nextID, ok, err = s.syncHistory(ctx, userID, startID, yield)
+			if err != nil && isHistoryExpired(err) {
+				startID, err = 0, nil
+			} else if err != nil {
+				yield(SyncEvent{}, err)
+				return
+			}
+		}
+		if startID == 0 && ok {
+			nextID, ok, err = s.syncFull(ctx, userID, yield)
+			if err != nil {
+				yield(SyncEvent{}, err)
+				return
+			}
+		}
+		if !ok {
+			return
+		}
+		if err := s.Store.Save(ctx, userID, nextID); err != nil {
+			yield(SyncEvent{}, errorsutil.Wrap(err, "func Syncer.Sync() call to Store.Save()"))
+		}
+	}
+}
+
+// syncHistory yields history events since `startID`. It returns the new cursor, and false
+// if the consumer stopped iteration. An expired `startID` is only reported before any
+// event has been yielded.
+func (s *Syncer) syncHistory(ctx context.Context, userID string, startID uint64, yield func(SyncEvent, error) bool) (uint64, bool, error) {
+	pageToken := ""
+	labelID := strings.TrimSpace(s.LabelID)
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, true, err
+		}
+		call := s.GmailService.UsersService.History.List(userID).StartHistoryId(startID)
+		if labelID != "" {
+			call.LabelId(labelID)
+		}
+		if len(s.HistoryTypes) > 0 {
+			call.HistoryTypes(s.HistoryTypes...)
+		}
+		if pageToken != "" {
+			call.PageToken(pageToken)
+		}
+		resp, err := call.Context(ctx).Do(s.GmailService.APICallOptions...)
+		if err != nil {
+			if pageToken == "" && isHistoryExpired(err) {
+				return 0, true, err
+			}
+			return 0, true, errorsutil.Wrap(err, "func Syncer.Sync() call to History.List().Do()")
+		}
+		for _, h := range resp.History {
+			for _, ev := range historyEvents(h) {
+				if !yield(ev, nil) {
+					return 0, false, nil
+				}
+			}
+		}
+		if resp.NextPageToken == "" {
+			return max(resp.HistoryId, startID), true, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// syncFull yields a reset followed by all messages. The cursor is the profile history ID
+// read before listing, so changes made while listing are delivered by the next sync.
+func (s *Syncer) syncFull(ctx context.Context, userID string, yield func(SyncEvent, error) bool) (uint64, bool, error) {
+	profile, err := s.GmailService.UsersService.GetProfile(userID).Context(ctx).Do(s.GmailService.APICallOptions...)
+	if err != nil {
+		return 0, true, errorsutil.Wrap(err, "func Syncer.Sync() call to GetProfile().Do()")
+	}
+	if !yield(SyncEvent{Type: SyncEventReset, HistoryID: profile.HistoryId}, nil) {
+		return 0, false, nil
+	}
+	opts := s.ListOpts
+	opts.UserID = userID
+	for msg, err := range s.GmailService.MessagesAPI.ListAll(ctx, opts) {
+		if err != nil {
+			return 0, true, err
+		}
+		if !yield(SyncEvent{
+			Type:      SyncEventMessageAdded,
+			HistoryID: profile.HistoryId,
+			MessageID: msg.Id,
+			ThreadID:  msg.ThreadId,
+			LabelIDs:  msg.LabelIds}, nil) {
+			return 0, false, nil
+		}
+	}
+	return profile.HistoryId, true, nil
+}
+
+// historyEvents flattens a history record into events.
+func historyEvents(h *gmail.History) []SyncEvent {
+	if h == nil {
+		return nil
+	}
+	var evs []SyncEvent
+	add := func(typ SyncEventType, msg *gmail.Message, labelIDs []string) {
+		if msg == nil {
+			return
+		}
+		if labelIDs == nil {
+			labelIDs = msg.LabelIds
+		}
+		evs = append(evs, SyncEvent{
+			Type:      typ,
+			HistoryID: h.Id,
+			MessageID: msg.Id,
+			ThreadID:  msg.ThreadId,
+			LabelIDs:  labelIDs})
+	}
+	for _, m := range h.MessagesAdded {
+		if m != nil {
+			add(SyncEventMessageAdded, m.Message, nil)
+		}
+	}
+	for _, m := range h.MessagesDeleted {
+		if m != nil {
+			add(SyncEventMessageDeleted, m.Message, nil)
+		}
+	}
+	for _, l := range h.LabelsAdded {
+		if l != nil {
+			add(SyncEventLabelsAdded, l.Message, l.LabelIds)
+		}
+	}
+	for _, l := range h.LabelsRemoved {
+		if l != nil {
+			add(SyncEventLabelsRemoved, l.Message, l.LabelIds)
+		}
+	}
+	return evs
+}
+
+// isHistoryExpired reports if `err` is the 404 returned by `users.history.list` for a
+// start history ID which is too old or invalid.
+func isHistoryExpired(err error) bool {
+	return isNotFound(err)
+}
+
+// isNotFound reports if `err` is a Gmail API 404 response.
+func isNotFound(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusNotFound
+}
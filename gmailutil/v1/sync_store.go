@@ -0,0 +1,99 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SyncStateStore persists the history ID cursor used by `Syncer`. Implementations must
+// be safe for concurrent use.
+type SyncStateStore interface {
+	// Load returns the stored history ID for `userID`, or 0 if there is none.
+	Load(ctx context.Context, userID string) (uint64, error)
+	// Save stores the history ID for `userID`.
+	Save(ctx context.Context, userID string, historyID uint64) error
+}
+
+// SyncState is the cursor stored per user by `FileSyncStateStore`.
+type SyncState struct {
+	HistoryID uint64    `json:"historyId,string"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FileSyncStateStore stores sync cursors for one or more users in a JSON file. Writes
+// replace the file atomically.
+type FileSyncStateStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewFileSyncStateStore returns a store backed by the JSON file at `path`, which is
+// created on the first save.
+func NewFileSyncStateStore(path string) *FileSyncStateStore {
+	return &FileSyncStateStore{Path: path}
+}
+
+// Load implements `SyncStateStore`.
+func (fs *FileSyncStateStore) Load(ctx context.Context, userID string) (uint64, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	states, err := fs.read()
+	if err != nil {
+		return 0, err
+	}
+	return states[userID].HistoryID, nil
+}
+
+// Save implements `SyncStateStore`.
+func (fs *FileSyncStateStore) Save(ctx context.Context, userID string, historyID uint64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	states, err := fs.read()
+	if err != nil {
+		return err
+	}
+	states[userID] = SyncState{HistoryID: historyID, UpdatedAt: time.Now().UTC()}
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(fs.Path, data)
+}
+
+func (fs *FileSyncStateStore) read() (map[string]SyncState, error) {
+	states := map[string]SyncState{}
+	data, err := os.ReadFile(fs.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return states, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return states, json.Unmarshal(data, &states)
+}
+
+// writeFileAtomic writes `data` to a temporary file in the directory of `path` and
+// renames it to `path`, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+// newTestSyncService serves a mailbox with two messages, a profile at history ID 500,
+// and two pages of history since 100. Any other start history ID returns 404.
+func newTestSyncService(t *testing.T, calls *[]string) *GmailService {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/me/profile", func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "profile")
+		_ = json.NewEncoder(w).Encode(&gmail.Profile{EmailAddress: "me@example.com", HistoryId: 500})
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/messages", func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, "list")
+		_ = json.NewEncoder(w).Encode(&gmail.ListMessagesResponse{Messages: []*gmail.Message{
+			{Id: "m1", ThreadId: "t1"}, {Id: "m2", ThreadId: "t2"}}})
+	})
+	mux.HandleFunc("GET /gmail/v1/users/me/history", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		*calls = append(*calls, "history:"+q.Get("startHistoryId")+":"+q.Get("pageToken"))
+		if q.Get("startHistoryId") != "100" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"message":"Requested entity was not found."}}`))
+			return
+		}
+		resp := gmail.ListHistoryResponse{HistoryId: 120}
+		if q.Get("pageToken") == "" {
+			resp.NextPageToken = "p2"
+			resp.History = []*gmail.History{{
+				Id: 110,
+				MessagesAdded: []*gmail.HistoryMessageAdded{{
+					Message: &gmail.Message{Id: "m3", ThreadId: "t3", LabelIds: []string{"INBOX", "UNREAD"}}}}}}
+		} else {
+			resp.History = []*gmail.History{{
+				Id: 115,
+				LabelsRemoved: []*gmail.HistoryLabelRemoved{{
+					LabelIds: []string{"UNREAD"},
+					Message:  &gmail.Message{Id: "m3", ThreadId: "t3"}}},
+			}, {
+				Id: 118,
+				LabelsAdded: []*gmail.HistoryLabelAdded{{
+					LabelIds: []string{"STARRED"},
+					Message:  &gmail.Message{Id: "m1", ThreadId: "t1"}}},
+				MessagesDeleted: []*gmail.HistoryMessageDeleted{{
+					Message: &gmail.Message{Id: "m2", ThreadId: "t2"}}},
+			}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	return newTestGmailService(t, mux)
+}
+
+func formatSyncEvents(t *testing.T, s *Syncer, limit int) string {
+	t.Helper()
+	var evs []string
+	for ev, err := range s.Sync(context.Background()) {
+		if err != nil {
+			t.Fatalf("Syncer.Sync() error: [%v]", err)
+		}
+		evs = append(evs, fmt.Sprintf("%s:%s:%s", ev.Type, ev.MessageID, strings.Join(ev.LabelIDs, "+")))
+		if limit > 0 && len(evs) >= limit {
+			break
+		}
+	}
+	return strings.Join(evs, ",")
+}
+
+func TestSyncer(t *testing.T) {
+	tests := []struct {
+		name       string
+		cursor     uint64
+		limit      int
+		wantEvents string
+		wantCalls  string
+		wantCursor uint64
+	}{
+		{"Initial", 0, 0,
+			"reset::,messageAdded:m1:,messageAdded:m2:",
+			"profile,list", 500},
+		{"Incremental", 100, 0,
+			"messageAdded:m3:INBOX+UNREAD,labelsRemoved:m3:UNREAD,messageDeleted:m2:,labelsAdded:m1:STARRED",
+			"history:100:,history:100:p2", 120},
+		{"Expired", 42, 0,
+			"reset::,messageAdded:m1:,messageAdded:m2:",
+			"history:42:,profile,list", 500},
+		{"StoppedEarly", 100, 1,
+			"messageAdded:m3:INBOX+UNREAD",
+			"history:100:", 100},
+	}
+	for _, tt := range tests {
+		var calls []string
+		store := NewFileSyncStateStore(filepath.Join(t.TempDir(), "sync.json"))
+		if tt.cursor > 0 {
+			if err := store.Save(context.Background(), UserIDMe, tt.cursor); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s := NewSyncer(newTestSyncService(t, &calls), store)
+		if got := formatSyncEvents(t, s, tt.limit); got != tt.wantEvents {
+			t.Errorf("Syncer.Sync(%s) events mismatch:\nwant [%s]\ngot  [%s]", tt.name, tt.wantEvents, got)
+		}
+		if got := strings.Join(calls, ","); got != tt.wantCalls {
+			t.Errorf("Syncer.Sync(%s) calls mismatch: want [%s] got [%s]", tt.name, tt.wantCalls, got)
+		}
+		if got, err := store.Load(context.Background(), UserIDMe); err != nil {
+			t.Fatal(err)
+		} else if got != tt.wantCursor {
+			t.Errorf("Syncer.Sync(%s) cursor mismatch: want [%d] got [%d]", tt.name, tt.wantCursor, got)
+		}
+	}
+}
+
+func TestFileSyncStateStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := NewFileSyncStateStore(path)
+	if id, err := store.Load(ctx, "a@example.com"); err != nil || id != 0 {
+		t.Fatalf("FileSyncStateStore.Load() empty mismatch: got [%d] err [%v]", id, err)
+	}
+	if err := store.Save(ctx, "a@example.com", 12345678901234); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(ctx, "b@example.com", 7); err != nil {
+		t.Fatal(err)
+	}
+	reopened := NewFileSyncStateStore(path)
+	for user, want := range map[string]uint64{"a@example.com": 12345678901234, "b@example.com": 7} {
+		if got, err := reopened.Load(ctx, user); err != nil || got != want {
+			t.Errorf("FileSyncStateStore.Load(%s) mismatch: want [%d] got [%d] err [%v]", user, want, got, err)
+		}
+	}
+}
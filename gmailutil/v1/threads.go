@@ -0,0 +1,216 @@
+package gmailutil
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"maps"
+	"net/textproto"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	"github.com/grokify/mogo/net/mailutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+// ErrThreadHasNoMessages is returned when replying to a thread without messages.
+var ErrThreadHasNoMessages = errors.New("gmail thread has no messages")
+
+// replyMetadataHeaders are the headers needed to address and thread a reply.
+var replyMetadataHeaders = []string{
+	mailutil.HeaderMessageID, HeaderReferences, HeaderInReplyTo, HeaderReplyTo, HeaderDate,
+	mailutil.HeaderFrom, mailutil.HeaderTo, mailutil.HeaderCc, mailutil.HeaderSubject}
+
+// ThreadsAPI lists, retrieves and replies to conversations.
+type ThreadsAPI struct {
+	GmailService *GmailService
+}
+
+// ParsedThread is a thread with its messages parsed and sorted chronologically.
+type ParsedThread struct {
+	ID        string
+	HistoryID uint64
+	Snippet   string
+	Messages  []*ParsedMessage
+}
+
+// Last returns the most recent message in the thread, or nil if there are none.
+func (pt *ParsedThread) Last() *ParsedMessage {
+	if pt == nil || len(pt.Messages) == 0 {
+		return nil
+	}
+	return pt.Messages[len(pt.Messages)-1]
+}
+
+// List returns a single page of threads matching `opts`, using the same query options
+// as `MessagesAPI`.
+func (tapi *ThreadsAPI) List(ctx context.Context, opts MessagesListOpts) (*gmail.ListThreadsResponse, error) {
+	if tapi.GmailService == nil {
+		return nil, ErrGmailServiceCannotBeNil
+	}
+	opts.Inflate()
+
+	call := tapi.GmailService.UsersService.Threads.List(opts.UserID)
+	call.IncludeSpamTrash(opts.IncludeSpamTrash)
+	if len(opts.LabelIDs) > 0 {
+		call.LabelIds(opts.LabelIDs...)
+	}
+	if opts.MaxResults > 0 {
+		call.MaxResults(int64(opts.MaxResults))
+	}
+	if len(opts.PageToken) > 0 {
+		call.PageToken(opts.PageToken)
+	}
+	if q := opts.Query.Encode(); len(q) > 0 {
+		call.Q(q)
+	}
+	if len(opts.Fields) > 0 {
+		call.Fields(opts.Fields...)
+	}
+	resp, err := call.Context(ctx).Do(tapi.GmailService.APICallOptions...)
+	if err != nil {
+		return resp, errorsutil.Wrap(err, "func ThreadsAPI.List() call to Threads.List().Do()")
+	}
+	return resp, nil
+}
+
+// ListAll returns an iterator over all threads matching `opts`, following `NextPageToken`
+// until the result set is exhausted, `opts.MaxTotal` threads have been yielded, or `ctx`
+// is cancelled. Threads are yielded as returned by `users.threads.list` which includes
+// only `Id`, `Snippet` and `HistoryId`. Iteration stops after the first error is yielded.
+func (tapi *ThreadsAPI) ListAll(ctx context.Context, opts MessagesListOpts) iter.Seq2[*gmail.Thread, error] {
+	return listAll(ctx, opts, func(opts MessagesListOpts) ([]*gmail.Thread, string, error) {
+		resp, err := tapi.List(ctx, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return resp.Threads, resp.NextPageToken, nil
+	})
+}
+
+// Get retrieves a thread and its messages using the format specified in `opts`, which
+// can be nil.
+func (tapi *ThreadsAPI) Get(ctx context.Context, userID, threadID string, opts *GetMessageOpts) (*gmail.Thread, error) {
+	if tapi.GmailService == nil {
+		return nil, ErrGmailServiceCannotBeNil
+	}
+	call := tapi.GmailService.UsersService.Threads.Get(labelUserID(userID), strings.TrimSpace(threadID))
+	if opts != nil {
+		if format := strings.TrimSpace(opts.Format); format != "" {
+			call.Format(format)
+		}
+		if len(opts.MetadataHeaders) > 0 {
+			call.MetadataHeaders(opts.MetadataHeaders...)
+		}
+	}
+	return call.Context(ctx).Do(tapi.GmailService.APICallOptions...)
+}
+
+// GetParsed retrieves a full thread and returns its messages, with decoded headers and
+// bodies, in chronological order.
+func (tapi *ThreadsAPI) GetParsed(ctx context.Context, userID, threadID string) (*ParsedThread, error) {
+	thread, err := tapi.Get(ctx, userID, threadID, &GetMessageOpts{Format: MessageFormatFull})
+	if err != nil {
+		return nil, err
+	}
+	return ParseThread(thread)
+}
+
+// ParseThread parses the messages of a thread and sorts them by `InternalDate`, which
+// is when Gmail received the message, falling back to the `Date` header.
+func ParseThread(thread *gmail.Thread) (*ParsedThread, error) {
+	if thread == nil {
+		return nil, errors.New("gmail thread cannot be nil")
+	}
+	pt := &ParsedThread{
+		ID:        thread.Id,
+		HistoryID: thread.HistoryId,
+		Snippet:   thread.Snippet}
+	for _, msg := range thread.Messages {
+		pm, err := ParseMessage(msg)
+		if err != nil {
+			return nil, errorsutil.Wrapf(err, "message id (%s)", msg.Id)
+		}
+		pt.Messages = append(pt.Messages, pm)
+	}
+	slices.SortStableFunc(pt.Messages, func(a, b *ParsedMessage) int {
+		return messageTime(a).Compare(messageTime(b))
+	})
+	return pt, nil
+}
+
+// Reply sends `msg` as a reply to the most recent message in a thread. The thread ID
+// and `In-Reply-To` and `References` headers are set so the reply is threaded both in
+// Gmail and in other clients. If `msg.To` is empty, the reply is addressed to the
+// `Reply-To` or `From` of the message being replied to. If `msg.Subject` is empty, the
+// thread subject is used with a `Re: ` prefix.
+func (tapi *ThreadsAPI) Reply(ctx context.Context, userID, threadID string, msg mailutil.MessageWriter) (*gmail.Message, error) {
+	thread, err := tapi.Get(ctx, userID, threadID, &GetMessageOpts{
+		Format:          MessageFormatMetadata,
+		MetadataHeaders: replyMetadataHeaders})
+	if err != nil {
+		return nil, err
+	}
+	pt, err := ParseThread(thread)
+	if err != nil {
+		return nil, err
+	}
+	parent := pt.Last()
+	if parent == nil {
+		return nil, ErrThreadHasNoMessages
+	}
+	msg.Header = maps.Clone(msg.Header)
+	SetReplyHeaders(&msg, parent)
+	if len(msg.To) == 0 {
+		if len(parent.ReplyTo) > 0 {
+			msg.To = parent.ReplyTo
+		} else if parent.From != nil {
+			msg.To = mailutil.Addresses{*parent.From}
+		}
+	}
+	return tapi.GmailService.SendInThread(ctx, labelUserID(userID), pt.ID, msg)
+}
+
+// SetReplyHeaders sets the `In-Reply-To` and `References` headers on `msg` to reply to
+// `parent`, following RFC 5322 section 3.6.4. If `msg.Subject` is empty, the subject of
+// `parent` is used with a `Re: ` prefix, which Gmail also requires to thread the reply.
+func SetReplyHeaders(msg *mailutil.MessageWriter, parent *ParsedMessage) {
+	if msg == nil || parent == nil {
+		return
+	}
+	if msg.Header == nil {
+		msg.Header = textproto.MIMEHeader{}
+	}
+	refs := slices.Clone(parent.References)
+	if len(refs) == 0 && parent.InReplyTo != "" && len(strings.Fields(parent.InReplyTo)) == 1 {
+		refs = []string{parent.InReplyTo}
+	}
+	if parent.MessageID != "" {
+		msg.Header.Set(HeaderInReplyTo, parent.MessageID)
+		refs = append(refs, parent.MessageID)
+	}
+	if len(refs) > 0 {
+		msg.Header.Set(HeaderReferences, strings.Join(refs, " "))
+	}
+	if strings.TrimSpace(msg.Subject) == "" {
+		msg.Subject = ReplySubject(parent.Subject)
+	}
+}
+
+// ReplySubject returns `subject` with a `Re: ` prefix, unless it already has one.
+func ReplySubject(subject string) string {
+	subject = strings.TrimSpace(subject)
+	if len(subject) >= 3 && strings.EqualFold(subject[:3], "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+func messageTime(pm *ParsedMessage) time.Time {
+	if !pm.InternalDate.IsZero() {
+		return pm.InternalDate
+	}
+	return pm.Date
+}
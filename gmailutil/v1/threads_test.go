@@ -0,0 +1,200 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/grokify/mogo/mime/multipartutil"
+	"github.com/grokify/mogo/net/mailutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+func testThreadMessage(id string, internalDate int64, headers map[string]string, body string) *gmail.Message {
+	msg := &gmail.Message{
+		Id:           id,
+		ThreadId:     "thread-1",
+		InternalDate: internalDate,
+		Payload: &gmail.MessagePart{
+			MimeType: "text/plain",
+			Body:     &gmail.MessagePartBody{Data: base64.URLEncoding.EncodeToString([]byte(body))}}}
+	for k, v := range headers {
+		msg.Payload.Headers = append(msg.Payload.Headers, &gmail.MessagePartHeader{Name: k, Value: v})
+	}
+	return msg
+}
+
+func testThread() *gmail.Thread {
+	return &gmail.Thread{
+		Id:        "thread-1",
+		HistoryId: 42,
+		Messages: []*gmail.Message{
+			testThreadMessage("m2", 2000, map[string]string{
+				"From":        "Support <support@example.com>",
+				"To":          "alice@example.com",
+				"Subject":     "Re: Order 1001",
+				"Message-ID":  "<m2@example.com>",
+				"In-Reply-To": "<m1@example.com>"}, "We are on it."),
+			testThreadMessage("m1", 1000, map[string]string{
+				"From":       "Alice <alice@example.com>",
+				"Reply-To":   "orders@example.com",
+				"To":         "support@example.com",
+				"Subject":    "=?UTF-8?Q?Order_1001?=",
+				"Message-ID": "<m1@example.com>"}, "Where is my order?"),
+			testThreadMessage("m3", 3000, map[string]string{
+				"From":        "Alice <alice@example.com>",
+				"Reply-To":    "orders@example.com",
+				"To":          "support@example.com",
+				"Subject":     "Re: Order 1001",
+				"Message-ID":  "<m3@example.com>",
+				"In-Reply-To": "<m2@example.com>",
+				"References":  "<m1@example.com> <m2@example.com>"}, "Thanks!"),
+		}}
+}
+
+func TestThreadsListAll(t *testing.T) {
+	calls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/me/threads", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if q := r.URL.Query().Get("q"); q != "from:alice@example.com" {
+			t.Errorf("ThreadsAPI.ListAll() query mismatch: got [%s]", q)
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+		resp := gmail.ListThreadsResponse{}
+		for i := range 2 {
+			resp.Threads = append(resp.Threads, &gmail.Thread{Id: strconv.Itoa(page*2 + i)})
+		}
+		if page < 2 {
+			resp.NextPageToken = strconv.Itoa(page + 1)
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	gs := newTestGmailService(t, mux)
+
+	var ids []string
+	for thread, err := range gs.ThreadsAPI.ListAll(context.Background(), MessagesListOpts{
+		Query: MessagesListQueryOpts{From: "alice@example.com"}}) {
+		if err != nil {
+			t.Fatalf("ThreadsAPI.ListAll() error: [%v]", err)
+		}
+		ids = append(ids, thread.Id)
+	}
+	if got := strings.Join(ids, ","); got != "0,1,2,3,4,5" || calls != 3 {
+		t.Errorf("ThreadsAPI.ListAll() mismatch: want [0,1,2,3,4,5] in [3] calls, got [%s] in [%d] calls", got, calls)
+	}
+}
+
+func TestThreadsGetParsed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/me/threads/thread-1", func(w http.ResponseWriter, r *http.Request) {
+		if f := r.URL.Query().Get("format"); f != MessageFormatFull {
+			t.Errorf("ThreadsAPI.GetParsed() format mismatch: got [%s]", f)
+		}
+		_ = json.NewEncoder(w).Encode(testThread())
+	})
+	gs := newTestGmailService(t, mux)
+
+	pt, err := gs.ThreadsAPI.GetParsed(context.Background(), "", "thread-1")
+	if err != nil {
+		t.Fatalf("ThreadsAPI.GetParsed() error: [%v]", err)
+	}
+	var ids []string
+	for _, pm := range pt.Messages {
+		ids = append(ids, pm.ID)
+	}
+	if got := strings.Join(ids, ","); got != "m1,m2,m3" {
+		t.Errorf("ThreadsAPI.GetParsed() order mismatch: want [m1,m2,m3] got [%s]", got)
+	}
+	if first := pt.Messages[0]; first.Subject != "Order 1001" || first.BodyText != "Where is my order?" {
+		t.Errorf("ThreadsAPI.GetParsed() first message mismatch: got subject [%s] body [%s]", first.Subject, first.BodyText)
+	}
+	if pt.Last().MessageID != "<m3@example.com>" || pt.HistoryID != 42 {
+		t.Errorf("ThreadsAPI.GetParsed() last message mismatch: got [%s]", pt.Last().MessageID)
+	}
+}
+
+func TestThreadsReply(t *testing.T) {
+	var sent *gmail.Message
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gmail/v1/users/me/threads/thread-1", func(w http.ResponseWriter, r *http.Request) {
+		if f := r.URL.Query().Get("format"); f != MessageFormatMetadata {
+			t.Errorf("ThreadsAPI.Reply() format mismatch: got [%s]", f)
+		}
+		_ = json.NewEncoder(w).Encode(testThread())
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/messages/send", func(w http.ResponseWriter, r *http.Request) {
+		sent = &gmail.Message{}
+		if err := json.NewDecoder(r.Body).Decode(sent); err != nil {
+			t.Error(err)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&gmail.Message{Id: "m4", ThreadId: sent.ThreadId})
+	})
+	gs := newTestGmailService(t, mux)
+
+	parts, err := multipartutil.NewPartsSetMail([]byte("Your order shipped today."), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := mailutil.MessageWriter{BodyPartsSet: parts}
+	if _, err := gs.ThreadsAPI.Reply(context.Background(), "", "thread-1", msg); err != nil {
+		t.Fatalf("ThreadsAPI.Reply() error: [%v]", err)
+	}
+	if msg.Header != nil {
+		t.Errorf("ThreadsAPI.Reply() modified caller header: got [%v]", msg.Header)
+	}
+	if sent == nil || sent.ThreadId != "thread-1" {
+		t.Fatalf("ThreadsAPI.Reply() thread id mismatch: got [%v]", sent)
+	}
+	raw, err := base64.URLEncoding.DecodeString(sent.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, err := ParseMessageRaw(raw)
+	if err != nil {
+		t.Fatalf("ParseMessageRaw() error: [%v]", err)
+	}
+	if pm.InReplyTo != "<m3@example.com>" {
+		t.Errorf("ThreadsAPI.Reply() In-Reply-To mismatch: got [%s]", pm.InReplyTo)
+	}
+	if got := strings.Join(pm.References, " "); got != "<m1@example.com> <m2@example.com> <m3@example.com>" {
+		t.Errorf("ThreadsAPI.Reply() References mismatch: got [%s]", got)
+	}
+	if pm.Subject != "Re: Order 1001" {
+		t.Errorf("ThreadsAPI.Reply() Subject mismatch: got [%s]", pm.Subject)
+	}
+	if len(pm.To) != 1 || pm.To[0].Address != "orders@example.com" {
+		t.Errorf("ThreadsAPI.Reply() To mismatch: got [%v]", pm.To)
+	}
+}
+
+func TestSetReplyHeaders(t *testing.T) {
+	for _, tt := range []struct {
+		parent         ParsedMessage
+		wantInReplyTo  string
+		wantReferences string
+		wantSubject    string
+	}{
+		{ParsedMessage{MessageID: "<a@x>", Subject: "Hello"}, "<a@x>", "<a@x>", "Re: Hello"},
+		{ParsedMessage{MessageID: "<b@x>", InReplyTo: "<a@x>", Subject: "RE: Hello"}, "<b@x>", "<a@x> <b@x>", "RE: Hello"},
+		{ParsedMessage{MessageID: "<c@x>", InReplyTo: "<b@x>", References: []string{"<a@x>", "<b@x>"}}, "<c@x>", "<a@x> <b@x> <c@x>", "Re:"},
+		{ParsedMessage{Subject: "No ID"}, "", "", "Re: No ID"},
+	} {
+		msg := mailutil.MessageWriter{}
+		SetReplyHeaders(&msg, &tt.parent)
+		if got := msg.Header.Get(HeaderInReplyTo); got != tt.wantInReplyTo {
+			t.Errorf("SetReplyHeaders() In-Reply-To mismatch: want [%s] got [%s]", tt.wantInReplyTo, got)
+		}
+		if got := msg.Header.Get(HeaderReferences); got != tt.wantReferences {
+			t.Errorf("SetReplyHeaders() References mismatch: want [%s] got [%s]", tt.wantReferences, got)
+		}
+		if strings.TrimSpace(msg.Subject) != tt.wantSubject {
+			t.Errorf("SetReplyHeaders() Subject mismatch: want [%s] got [%s]", tt.wantSubject, msg.Subject)
+		}
+	}
+}
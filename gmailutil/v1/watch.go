@@ -0,0 +1,170 @@
+package gmailutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/grokify/mogo/errors/errorsutil"
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+var ErrWatchTopicNameCannotBeEmpty = errors.New("watch topic name cannot be empty")
+
+// Label filter behaviors for `WatchOpts.LabelFilterBehavior`.
+const (
+	LabelFilterBehaviorInclude = "include"
+	LabelFilterBehaviorExclude = "exclude"
+)
+
+const (
+	// DefaultWatchRenewInterval is how often `Watcher` renews a watch. Gmail watches expire
+	// after 7 days and Google recommends renewing once a day.
+	DefaultWatchRenewInterval = 24 * time.Hour
+	// DefaultWatchRetryDelay is how long `Watcher` waits before retrying a failed renewal.
+	DefaultWatchRetryDelay = time.Minute
+	// WatchRenewMargin is how long before expiration `Watcher` renews a watch, if that is
+	// earlier than the renew interval.
+	WatchRenewMargin = time.Hour
+)
+
+// WatchOpts configures `users.watch`.
+type WatchOpts struct {
+	UserID              string
+	TopicName           string   // Pub/Sub topic, e.g. `projects/my-project/topics/gmail`
+	LabelIDs            []string // labels to filter notifications by
+	LabelFilterBehavior string   // `LabelFilterBehaviorInclude` (default) or `LabelFilterBehaviorExclude`
+}
+
+// Watch starts or renews push notifications for mailbox changes to the Pub/Sub topic in
+// `opts`. The response includes the current history ID and the expiration in Unix
+// milliseconds. Calling `Watch` again before expiration renews the watch.
+func (gs GmailService) Watch(ctx context.Context, opts WatchOpts) (*gmail.WatchResponse, error) {
+	if gs.UsersService == nil {
+		return nil, ErrGmailUsersServiceCannotBeNil
+	}
+	req := &gmail.WatchRequest{
+		TopicName:           strings.TrimSpace(opts.TopicName),
+		LabelIds:            opts.LabelIDs,
+		LabelFilterBehavior: strings.TrimSpace(opts.LabelFilterBehavior)}
+	if req.TopicName == "" {
+		return nil, ErrWatchTopicNameCannotBeEmpty
+	}
+	resp, err := gs.UsersService.Watch(labelUserID(opts.UserID), req).Context(ctx).Do(gs.APICallOptions...)
+	if err != nil {
+		return nil, errorsutil.Wrap(err, "func GmailService.Watch() call to Users.Watch().Do()")
+	}
+	return resp, nil
+}
+
+// StopWatch stops push notifications for the mailbox.
+func (gs GmailService) StopWatch(ctx context.Context, userID string) error {
+	if gs.UsersService == nil {
+		return ErrGmailUsersServiceCannotBeNil
+	}
+	if err := gs.UsersService.Stop(labelUserID(userID)).Context(ctx).Do(gs.APICallOptions...); err != nil {
+		return errorsutil.Wrap(err, "func GmailService.StopWatch() call to Users.Stop().Do()")
+	}
+	return nil
+}
+
+// WatchExpiration returns the expiration of a watch as a `time.Time`, or the zero time if
+// `resp` is nil or has no expiration.
+func WatchExpiration(resp *gmail.WatchResponse) time.Time {
+	if resp == nil || resp.Expiration <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(resp.Expiration)
+}
+
+// Watcher keeps a watch active by renewing it on a schedule until its context is
+// cancelled, and then stops it.
+type Watcher struct {
+	GmailService  *GmailService
+	Opts          WatchOpts
+	Store         SyncStateStore // if set and empty, seeded with the history ID of the first watch
+	RenewInterval time.Duration  // defaults to `DefaultWatchRenewInterval`
+	RetryDelay    time.Duration  // defaults to `DefaultWatchRetryDelay`
+	// OnWatch, if set, is called after every watch attempt.
+	OnWatch func(resp *gmail.WatchResponse, err error)
+}
+
+// NewWatcher returns a `Watcher` for the authenticated user.
+func NewWatcher(gs *GmailService, topicName string) *Watcher {
+	return &Watcher{GmailService: gs, Opts: WatchOpts{UserID: UserIDMe, TopicName: topicName}}
+}
+
+// Run starts the watch and renews it every `RenewInterval`, or `WatchRenewMargin` before
+// it expires if that is sooner. Failed renewals are retried after `RetryDelay`. If the
+// first watch fails, its error is returned immediately. When `ctx` is cancelled, the watch
+// is stopped and the result of `StopWatch()` is returned.
+func (w *Watcher) Run(ctx context.Context) error {
+	if w.GmailService == nil {
+		return ErrGmailServiceCannotBeNil
+	}
+	interval := w.RenewInterval
+	if interval <= 0 {
+		interval = DefaultWatchRenewInterval
+	}
+	retry := w.RetryDelay
+	if retry <= 0 {
+		retry = DefaultWatchRetryDelay
+	}
+	userID := labelUserID(w.Opts.UserID)
+
+	started := false
+	for {
+		resp, err := w.GmailService.Watch(ctx, w.Opts)
+		if w.OnWatch != nil {
+			w.OnWatch(resp, err)
+		}
+		var delay time.Duration
+		if err != nil {
+			if !started {
+				return err
+			}
+			delay = retry
+		} else {
+			if !started && w.Store != nil {
+				if err := w.seed(ctx, userID, resp.HistoryId); err != nil {
+					return err
+				}
+			}
+			started = true
+			delay = watchRenewalDelay(time.Now(), WatchExpiration(resp), interval)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return w.GmailService.StopWatch(context.WithoutCancel(ctx), userID)
+		case <-timer.C:
+		}
+	}
+}
+
+// seed saves `historyID` as the sync cursor if none is stored, so a `PushHandler` using
+// the same store reports changes from the start of the watch.
+func (w *Watcher) seed(ctx context.Context, userID string, historyID uint64) error {
+	cur, err := w.Store.Load(ctx, userID)
+	if err != nil {
+		return errorsutil.Wrap(err, "func Watcher.Run() call to Store.Load()")
+	} else if cur > 0 || historyID == 0 {
+		return nil
+	} else if err := w.Store.Save(ctx, userID, historyID); err != nil {
+		return errorsutil.Wrap(err, "func Watcher.Run() call to Store.Save()")
+	}
+	return nil
+}
+
+// watchRenewalDelay returns how long to wait before renewing a watch: `interval`, or
+// until `WatchRenewMargin` before `expiration` if that is sooner.
+func watchRenewalDelay(now, expiration time.Time, interval time.Duration) time.Duration {
+	delay := interval
+	if !expiration.IsZero() {
+		delay = min(delay, expiration.Add(-WatchRenewMargin).Sub(now))
+	}
+	return max(delay, 0)
+}
@@ -0,0 +1,122 @@
+package gmailutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gmail "google.golang.org/api/gmail/v1"
+)
+
+func TestWatch(t *testing.T) {
+	var stopped atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/watch", func(w http.ResponseWriter, r *http.Request) {
+		var req gmail.WatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode watch request error: [%v]", err)
+			return
+		}
+		if req.TopicName != "projects/p/topics/gmail" || len(req.LabelIds) != 1 || req.LabelIds[0] != "INBOX" ||
+			req.LabelFilterBehavior != LabelFilterBehaviorInclude {
+			t.Errorf("Watch() request mismatch: got [%+v]", req)
+		}
+		_ = json.NewEncoder(w).Encode(&gmail.WatchResponse{HistoryId: 300, Expiration: 1700000000000})
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/stop", func(w http.ResponseWriter, r *http.Request) {
+		stopped.Store(true)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	gs := newTestGmailService(t, mux)
+
+	resp, err := gs.Watch(context.Background(), WatchOpts{
+		TopicName:           "projects/p/topics/gmail",
+		LabelIDs:            []string{"INBOX"},
+		LabelFilterBehavior: LabelFilterBehaviorInclude})
+	if err != nil {
+		t.Fatalf("Watch() error: [%v]", err)
+	}
+	if resp.HistoryId != 300 || !WatchExpiration(resp).Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("Watch() mismatch: got historyId (%d) expiration (%v)", resp.HistoryId, WatchExpiration(resp))
+	}
+	if _, err := gs.Watch(context.Background(), WatchOpts{}); err != ErrWatchTopicNameCannotBeEmpty {
+		t.Errorf("Watch() without topic: want [%v], got [%v]", ErrWatchTopicNameCannotBeEmpty, err)
+	}
+	if err := gs.StopWatch(context.Background(), ""); err != nil || !stopped.Load() {
+		t.Errorf("StopWatch() mismatch: error [%v], stopped (%v)", err, stopped.Load())
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	var watches, stops atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/watch", func(w http.ResponseWriter, r *http.Request) {
+		watches.Add(1)
+		_ = json.NewEncoder(w).Encode(&gmail.WatchResponse{
+			HistoryId:  300 + uint64(watches.Load()),
+			Expiration: time.Now().Add(7 * 24 * time.Hour).UnixMilli()})
+	})
+	mux.HandleFunc("POST /gmail/v1/users/me/stop", func(w http.ResponseWriter, r *http.Request) {
+		stops.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	gs := newTestGmailService(t, mux)
+	store := NewFileSyncStateStore(filepath.Join(t.TempDir(), "sync.json"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewWatcher(gs, "projects/p/topics/gmail")
+	w.Store = store
+	w.RenewInterval = 5 * time.Millisecond
+	w.OnWatch = func(resp *gmail.WatchResponse, err error) {
+		if err != nil {
+			t.Errorf("Watcher.OnWatch() error: [%v]", err)
+		} else if watches.Load() >= 3 {
+			cancel()
+		}
+	}
+	if err := w.Run(ctx); err != nil {
+		t.Fatalf("Watcher.Run() error: [%v]", err)
+	}
+	if watches.Load() != 3 || stops.Load() != 1 {
+		t.Errorf("Watcher.Run() calls mismatch: want watches (3) stops (1), got watches (%d) stops (%d)", watches.Load(), stops.Load())
+	}
+	// only the first watch seeds the cursor
+	if got, err := store.Load(context.Background(), UserIDMe); err != nil || got != 301 {
+		t.Errorf("Watcher.Run() cursor mismatch: want (301), got (%d) error [%v]", got, err)
+	}
+}
+
+func TestWatcherRunFirstWatchError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /gmail/v1/users/me/watch", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":{"code":403,"message":"topic permission denied"}}`))
+	})
+	w := NewWatcher(newTestGmailService(t, mux), "projects/p/topics/gmail")
+	if err := w.Run(context.Background()); err == nil {
+		t.Error("Watcher.Run() want error for failed first watch, got nil")
+	}
+}
+
+func TestWatchRenewalDelay(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expiration time.Time
+		interval   time.Duration
+		want       time.Duration
+	}{
+		{time.Time{}, DefaultWatchRenewInterval, DefaultWatchRenewInterval},
+		{now.Add(7 * 24 * time.Hour), DefaultWatchRenewInterval, DefaultWatchRenewInterval},
+		{now.Add(3 * time.Hour), DefaultWatchRenewInterval, 2 * time.Hour},
+		{now.Add(30 * time.Minute), DefaultWatchRenewInterval, 0},
+	}
+	for _, tt := range tests {
+		if got := watchRenewalDelay(now, tt.expiration, tt.interval); got != tt.want {
+			t.Errorf("watchRenewalDelay(%v, %v): want (%v), got (%v)", tt.expiration, tt.interval, tt.want, got)
+		}
+	}
+}